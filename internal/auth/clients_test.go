@@ -0,0 +1,43 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+)
+
+func TestServiceClients(t *testing.T) {
+	sum := sha256.Sum256([]byte("secret"))
+
+	t.Run("OK", func(t *testing.T) {
+		clients, err := NewServiceClients(map[string]string{"resource": hex.EncodeToString(sum[:])})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if err = clients.Authenticate("resource", "secret"); err != nil {
+			t.Errorf("expected no error, got %s", err.Error())
+		}
+		if err = clients.Authenticate("resource", "wrong"); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("expected ErrInvalidClient, got %v", err)
+		}
+		if err = clients.Authenticate("unknown", "secret"); !errors.Is(err, ErrInvalidClient) {
+			t.Errorf("expected ErrInvalidClient, got %v", err)
+		}
+	})
+
+	t.Run("invalid hash", func(t *testing.T) {
+		if _, err := NewServiceClients(map[string]string{"resource": "not hex"}); err == nil {
+			t.Error("expected error with invalid hash")
+		}
+		if _, err := NewServiceClients(map[string]string{"resource": "abcd"}); err == nil {
+			t.Error("expected error with short hash")
+		}
+	})
+
+	t.Run("empty ID", func(t *testing.T) {
+		if _, err := NewServiceClients(map[string]string{"": hex.EncodeToString(sum[:])}); err == nil {
+			t.Error("expected error with empty client ID")
+		}
+	})
+}
@@ -0,0 +1,315 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/credentials"
+	"github.com/Salam4nder/identity/internal/database/verificationtoken"
+	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/pkg/password"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// EmailChangeDuration is how long the token confirming a new email can be used.
+	EmailChangeDuration = time.Hour
+	// EmailRevertDuration is how long the token reverting an email change can be used.
+	EmailRevertDuration = 7 * 24 * time.Hour
+	// EmailChangeSends is how many email changes a user can request within [EmailChangeWindow].
+	EmailChangeSends = 3
+	// EmailChangeWindow is the window [EmailChangeSends] are counted in.
+	EmailChangeWindow = time.Hour
+)
+
+// EmailChanges changes the emails of users of the credentials strategy. A new email is pending
+// until it is confirmed with a single use token sent to it, then the previous email is notified
+// with a token that reverts the change. Only the SHA-256 hash of a token is stored.
+type EmailChanges struct {
+	db         *sql.DB
+	publisher  email.Publisher
+	tokens     *Tokens
+	passwords  *Passwords
+	confirmURL *url.URL
+	revertURL  *url.URL
+}
+
+// NewEmailChanges returns a new [EmailChanges] that ends the sessions of a user with tokens once
+// a change is reverted, and has the user reset its password with passwords. Tokens are emailed as a link
+// to confirmLinkURL or revertLinkURL with the token as query parameter, without a link URL the token itself
+// is emailed.
+func NewEmailChanges(
+	db *sql.DB,
+	publisher email.Publisher,
+	tokens *Tokens,
+	passwords *Passwords,
+	confirmLinkURL string,
+	revertLinkURL string,
+) (*EmailChanges, error) {
+	x := &EmailChanges{db: db, publisher: publisher, tokens: tokens, passwords: passwords}
+	var err error
+	if confirmLinkURL != "" {
+		if x.confirmURL, err = url.Parse(confirmLinkURL); err != nil {
+			return nil, fmt.Errorf("auth: email change, parsing confirm link url, %w", err)
+		}
+	}
+	if revertLinkURL != "" {
+		if x.revertURL, err = url.Parse(revertLinkURL); err != nil {
+			return nil, fmt.Errorf("auth: email change, parsing revert link url, %w", err)
+		}
+	}
+	return x, nil
+}
+
+// EmailChangeParams define an email change requested with [EmailChanges.Request].
+type EmailChangeParams struct {
+	UserID   uuid.UUID
+	Password password.SafeString
+	NewEmail string
+}
+
+// Request emails a token confirming NewEmail to it after checking the password of the user,
+// it has to be used with [EmailChanges.Confirm] within [EmailChangeDuration].
+// The email of the user stays the same until then.
+// Returns [ErrNoPassword] if the user did not register with credentials, [ErrInvalidCredentials]
+// if the password does not match and [ErrTooManyDeliveries] if the user requested [EmailChangeSends]
+// changes within [EmailChangeWindow].
+func (x *EmailChanges) Request(ctx context.Context, params EmailChangeParams) error {
+	ctx, span := tracer.Start(ctx, "EmailChanges.Request")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", params.UserID.String()))
+
+	entry, err := credentials.Read(ctx, x.db, params.UserID)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return ErrNoPassword
+		}
+		return err
+	}
+	if err = params.Password.Compare(entry.PasswordHash); err != nil {
+		if errors.As(err, &password.MismatchError{}) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	tok, err := newVerificationToken()
+	if err != nil {
+		return err
+	}
+	if err = throttle(
+		ctx,
+		x.db,
+		entry.ID,
+		verificationtoken.PurposeEmailChange,
+		EmailChangeSends,
+		EmailChangeWindow,
+		func(tx *sql.Tx, now time.Time) error {
+			return verificationtoken.Insert(ctx, tx, verificationtoken.InsertParams{
+				ID:        uuid.New(),
+				TokenHash: token.Hash(tok),
+				UserID:    entry.ID,
+				Purpose:   verificationtoken.PurposeEmailChange,
+				ExpiresAt: now.Add(EmailChangeDuration),
+				CreatedAt: now,
+				Email:     &params.NewEmail,
+			})
+		},
+	); err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Confirm your new email with the token %s.", string(tok))
+	if x.confirmURL != nil {
+		body = fmt.Sprintf("Confirm your new email by opening %s.", tokenLink(x.confirmURL, tok))
+	}
+	return email.Ingest(ctx, x.publisher, email.Email{
+		To:      params.NewEmail,
+		From:    email.TestFrom,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf(
+			"%s It expires in %d minutes. If you did not ask for it, ignore this email.",
+			body,
+			int(EmailChangeDuration/time.Minute),
+		),
+	})
+}
+
+// Confirm uses an email change token and changes the email of its user to the pending email,
+// then emails the previous email a token that reverts the change with [EmailChanges.Revert]
+// within [EmailRevertDuration]. Unused password reset tokens, sent to the previous email, are expired.
+// Returns the ID of the user, [ErrInvalidVerificationToken], or [database.DuplicateEntryError]
+// if the pending email was registered in the meantime, the token is then not used up.
+func (x *EmailChanges) Confirm(ctx context.Context, tok token.SafeString) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "EmailChanges.Confirm")
+	defer span.End()
+
+	now := time.Now()
+	var previous string
+	userID, err := x.use(ctx, tok, verificationtoken.PurposeEmailChange, func(tx *sql.Tx, userID uuid.UUID) error {
+		entry, err := credentials.Read(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+		previous = entry.Email
+		_, err = verificationtoken.ExpireUnused(ctx, tx, userID, verificationtoken.PurposePasswordReset, now)
+		return err
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	revert, err := newVerificationToken()
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = verificationtoken.Insert(ctx, x.db, verificationtoken.InsertParams{
+		ID:        uuid.New(),
+		TokenHash: token.Hash(revert),
+		UserID:    userID,
+		Purpose:   verificationtoken.PurposeEmailRevert,
+		ExpiresAt: now.Add(EmailRevertDuration),
+		CreatedAt: now,
+		Email:     &previous,
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	body := fmt.Sprintf("If it was not you, revert the change with the token %s", string(revert))
+	if x.revertURL != nil {
+		body = fmt.Sprintf("If it was not you, revert the change by opening %s", tokenLink(x.revertURL, revert))
+	}
+	if err = email.Ingest(ctx, x.publisher, email.Email{
+		To:      previous,
+		From:    email.TestFrom,
+		Subject: "Your email was changed",
+		Body: fmt.Sprintf(
+			"The email of your identity was changed. %s within %d days.",
+			body,
+			int(EmailRevertDuration/(24*time.Hour)),
+		),
+	}); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// Revert uses an email revert token and changes the email of its user back to the previous email.
+// Whoever changed the email knows the password, so it is replaced with an unknown one and a password reset
+// token is emailed to the previous email, then every session of the user is ended. Unused email change,
+// revert and password reset tokens of the user are expired.
+// Returns the ID of the user, [ErrInvalidVerificationToken], or [database.DuplicateEntryError]
+// if the previous email was registered in the meantime, the token is then not used up.
+func (x *EmailChanges) Revert(ctx context.Context, tok token.SafeString) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "EmailChanges.Revert")
+	defer span.End()
+
+	now := time.Now()
+	var reset token.SafeString
+	userID, err := x.use(ctx, tok, verificationtoken.PurposeEmailRevert, func(tx *sql.Tx, userID uuid.UUID) error {
+		for _, purpose := range []string{
+			verificationtoken.PurposeEmailChange,
+			verificationtoken.PurposeEmailRevert,
+			verificationtoken.PurposePasswordReset,
+		} {
+			if _, err := verificationtoken.ExpireUnused(ctx, tx, userID, purpose, now); err != nil {
+				return err
+			}
+		}
+
+		unknown, err := newVerificationToken()
+		if err != nil {
+			return err
+		}
+		if err = credentials.UpdatePassword(ctx, tx, userID, password.SafeString(unknown)); err != nil {
+			return err
+		}
+
+		reset, err = x.passwords.insertResetToken(ctx, tx, userID, now)
+		return err
+	})
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, err = x.tokens.RevokeSessions(ctx, userID, uuid.Nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	entry, err := credentials.Read(ctx, x.db, userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if err = x.passwords.emailResetToken(
+		ctx,
+		entry.Email,
+		reset,
+		"The change of your email was reverted, set a new password to sign in again.",
+	); err != nil {
+		return uuid.Nil, err
+	}
+
+	return userID, nil
+}
+
+// use uses a token of purpose and changes the email of its user to the email of the token,
+// in the same transaction. before is called in that transaction before the email is changed.
+// The changed email counts as verified, following the emailed token proves it is owned.
+func (x *EmailChanges) use(
+	ctx context.Context,
+	tok token.SafeString,
+	purpose string,
+	before func(tx *sql.Tx, userID uuid.UUID) error,
+) (uuid.UUID, error) {
+	if tok == "" {
+		return uuid.Nil, ErrInvalidVerificationToken
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	now := time.Now()
+	entry, err := verificationtoken.ReadByHashForUpdate(ctx, tx, token.Hash(tok))
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = ErrInvalidVerificationToken
+		}
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if entry.Purpose != purpose ||
+		entry.Email == nil ||
+		entry.UsedAt != nil ||
+		now.After(entry.ExpiresAt) {
+		return uuid.Nil, errors.Join(ErrInvalidVerificationToken, tx.Rollback())
+	}
+
+	if before != nil {
+		if err = before(tx, entry.UserID); err != nil {
+			return uuid.Nil, errors.Join(err, tx.Rollback())
+		}
+	}
+	if err = verificationtoken.MarkUsed(ctx, tx, entry.ID, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = credentials.Update(ctx, tx, credentials.UpdateParams{ID: entry.UserID, Email: *entry.Email}); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = credentials.MarkEmailVerified(ctx, tx, entry.UserID, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entry.UserID, nil
+}
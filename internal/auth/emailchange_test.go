@@ -0,0 +1,27 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNewEmailChanges(t *testing.T) {
+	if _, err := NewEmailChanges(nil, nil, nil, nil, "://invalid", ""); err == nil {
+		t.Error("expected error with an invalid confirm link url")
+	}
+	if _, err := NewEmailChanges(nil, nil, nil, nil, "", "://invalid"); err == nil {
+		t.Error("expected error with an invalid revert link url")
+	}
+
+	x, err := NewEmailChanges(nil, nil, nil, nil, "", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if _, err = x.Confirm(context.Background(), ""); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected ErrInvalidVerificationToken, got %v", err)
+	}
+	if _, err = x.Revert(context.Background(), ""); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected ErrInvalidVerificationToken, got %v", err)
+	}
+}
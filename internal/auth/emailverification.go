@@ -0,0 +1,307 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/credentials"
+	"github.com/Salam4nder/identity/internal/database/verificationtoken"
+	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrEmailNotVerified is returned when authenticating an entry that has not verified its email,
+	// while verification is required.
+	ErrEmailNotVerified = errors.New("auth: email not verified")
+	// ErrInvalidVerificationToken is returned when a verification token is unknown, expired or used.
+	ErrInvalidVerificationToken = errors.New("auth: invalid verification token")
+)
+
+const (
+	// EmailVerificationDuration is how long a verification token can be used.
+	EmailVerificationDuration = 24 * time.Hour
+	// EmailVerificationSends is how many verification emails a user is sent within [EmailVerificationWindow].
+	EmailVerificationSends = 3
+	// EmailVerificationWindow is the window [EmailVerificationSends] are counted in.
+	EmailVerificationWindow = time.Hour
+
+	verificationTokenSize = 32
+)
+
+// EmailVerification emails a single use token to users of the credentials strategy, proving they own their email.
+// Only the SHA-256 hash of a token is stored.
+type EmailVerification struct {
+	db        *sql.DB
+	publisher email.Publisher
+	linkURL   *url.URL
+	required  bool
+}
+
+// NewEmailVerification returns a new [EmailVerification]. Tokens are emailed as a link to linkURL
+// with the token as query parameter, without a linkURL the token itself is emailed.
+// required blocks the authentication of users that have not verified their email.
+func NewEmailVerification(
+	db *sql.DB,
+	publisher email.Publisher,
+	linkURL string,
+	required bool,
+) (*EmailVerification, error) {
+	x := &EmailVerification{db: db, publisher: publisher, required: required}
+	if linkURL != "" {
+		var err error
+		if x.linkURL, err = url.Parse(linkURL); err != nil {
+			return nil, fmt.Errorf("auth: email verification, parsing link url, %w", err)
+		}
+	}
+	return x, nil
+}
+
+// Required reports whether users have to verify their email before they can authenticate.
+func (x *EmailVerification) Required() bool {
+	return x.required
+}
+
+// Send emails a new verification token to the user, it has to be used with [EmailVerification.Verify]
+// within [EmailVerificationDuration].
+// Returns [ErrTooManyDeliveries] if the user was sent [EmailVerificationSends] tokens within [EmailVerificationWindow].
+func (x *EmailVerification) Send(ctx context.Context, userID uuid.UUID, address string) error {
+	ctx, span := tracer.Start(ctx, "EmailVerification.Send")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	var tok token.SafeString
+	if err := throttle(
+		ctx,
+		x.db,
+		userID,
+		verificationtoken.PurposeEmailVerification,
+		EmailVerificationSends,
+		EmailVerificationWindow,
+		func(tx *sql.Tx, now time.Time) error {
+			var err error
+			tok, err = x.insertToken(ctx, tx, userID, now)
+			return err
+		},
+	); err != nil {
+		return err
+	}
+	return x.emailToken(ctx, address, tok)
+}
+
+// Register calls insert to insert a new user and inserts a verification token for it in the same transaction,
+// then emails the token to address. The user stays registered if the email can not be sent,
+// it can ask for another token with [EmailVerification.Resend].
+// Returns the ID of the user or the error of insert.
+func (x *EmailVerification) Register(
+	ctx context.Context,
+	address string,
+	insert func(tx *sql.Tx) (uuid.UUID, error),
+) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "EmailVerification.Register")
+	defer span.End()
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	userID, err := insert(tx)
+	if err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+	tok, err := x.insertToken(ctx, tx, userID, time.Now())
+	if err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	if err = x.emailToken(ctx, address, tok); err != nil {
+		span.RecordError(err)
+		slog.ErrorContext(ctx, "auth: emailing verification token", "user_id", userID, "err", err)
+	}
+
+	return userID, nil
+}
+
+// insertToken inserts a new verification token for the user and returns it.
+func (x *EmailVerification) insertToken(
+	ctx context.Context,
+	db database.Querier,
+	userID uuid.UUID,
+	now time.Time,
+) (token.SafeString, error) {
+	tok, err := newVerificationToken()
+	if err != nil {
+		return "", err
+	}
+	if err = verificationtoken.Insert(ctx, db, verificationtoken.InsertParams{
+		ID:        uuid.New(),
+		TokenHash: token.Hash(tok),
+		UserID:    userID,
+		Purpose:   verificationtoken.PurposeEmailVerification,
+		ExpiresAt: now.Add(EmailVerificationDuration),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// emailToken emails a verification token to address.
+func (x *EmailVerification) emailToken(ctx context.Context, address string, tok token.SafeString) error {
+	body := fmt.Sprintf("Verify your email with the token %s.", string(tok))
+	if x.linkURL != nil {
+		body = fmt.Sprintf("Verify your email by opening %s.", tokenLink(x.linkURL, tok))
+	}
+	return email.Ingest(ctx, x.publisher, email.Email{
+		To:      address,
+		From:    email.TestFrom,
+		Subject: "Verify your email",
+		Body:    fmt.Sprintf("%s It expires in %d hours.", body, int(EmailVerificationDuration/time.Hour)),
+	})
+}
+
+// Resend emails a new verification token to the credentials entry registered with address.
+// Nothing is sent if there is no such entry, its email is verified, or it was sent [EmailVerificationSends]
+// tokens within [EmailVerificationWindow], without telling the caller.
+func (x *EmailVerification) Resend(ctx context.Context, address string) error {
+	ctx, span := tracer.Start(ctx, "EmailVerification.Resend")
+	defer span.End()
+
+	entry, err := credentials.ReadByEmail(ctx, x.db, address)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return nil
+		}
+		return err
+	}
+	if entry.EmailVerifiedAt != nil {
+		return nil
+	}
+
+	if err = x.Send(ctx, entry.ID, entry.Email); err != nil {
+		// Telling a throttled caller apart would tell that address is registered.
+		if errors.Is(err, ErrTooManyDeliveries) {
+			span.SetAttributes(attribute.Bool("throttled", true))
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+// Verify uses a verification token and marks the email of its user as verified.
+// Returns the ID of the user, or [ErrInvalidVerificationToken].
+func (x *EmailVerification) Verify(ctx context.Context, tok token.SafeString) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "EmailVerification.Verify")
+	defer span.End()
+
+	if tok == "" {
+		return uuid.Nil, ErrInvalidVerificationToken
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	now := time.Now()
+	entry, err := verificationtoken.ReadByHashForUpdate(ctx, tx, token.Hash(tok))
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = ErrInvalidVerificationToken
+		}
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if entry.Purpose != verificationtoken.PurposeEmailVerification ||
+		entry.UsedAt != nil ||
+		now.After(entry.ExpiresAt) {
+		return uuid.Nil, errors.Join(ErrInvalidVerificationToken, tx.Rollback())
+	}
+	span.SetAttributes(attribute.String("user_id", entry.UserID.String()))
+
+	if err = verificationtoken.MarkUsed(ctx, tx, entry.ID, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = credentials.MarkEmailVerified(ctx, tx, entry.UserID, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entry.UserID, nil
+}
+
+// throttle calls insert in a transaction, unless the user was handed out limit verification tokens of purpose
+// within window. Tokens are counted and inserted under a lock on the user and purpose,
+// so concurrent requests can not exceed limit. Returns [ErrTooManyDeliveries] once limit is reached.
+func throttle(
+	ctx context.Context,
+	db *sql.DB,
+	userID uuid.UUID,
+	purpose string,
+	limit int,
+	window time.Duration,
+	insert func(tx *sql.Tx, now time.Time) error,
+) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if err = database.Lock(ctx, tx, verificationtoken.Tablename+":"+purpose+":"+userID.String()); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	now := time.Now()
+	sent, err := verificationtoken.CountSince(ctx, tx, userID, purpose, now.Add(-window))
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if sent >= limit {
+		return errors.Join(
+			fmt.Errorf("%w, %d %s tokens within %s", ErrTooManyDeliveries, sent, purpose, window),
+			tx.Rollback(),
+		)
+	}
+	if err = insert(tx, now); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
+
+// newVerificationToken returns a random token for the verification tokens table, store it with [token.Hash].
+func newVerificationToken() (token.SafeString, error) {
+	b := make([]byte, verificationTokenSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("auth: reading random bytes, %w", err)
+	}
+	return token.SafeString(base64.RawURLEncoding.EncodeToString(b)), nil
+}
+
+// tokenLink returns linkURL with tok as its token query parameter.
+func tokenLink(linkURL *url.URL, tok token.SafeString) string {
+	link := *linkURL
+	query := link.Query()
+	query.Set("token", string(tok))
+	link.RawQuery = query.Encode()
+	return link.String()
+}
@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestNewEmailVerification(t *testing.T) {
+	if _, err := NewEmailVerification(nil, nil, "://invalid", false); err == nil {
+		t.Error("expected error with an invalid link url")
+	}
+
+	x, err := NewEmailVerification(nil, nil, "", true)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if !x.Required() {
+		t.Error("expected verification to be required")
+	}
+
+	if _, err = x.Verify(context.Background(), ""); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected ErrInvalidVerificationToken, got %v", err)
+	}
+}
+
+func TestTokenLink(t *testing.T) {
+	linkURL, err := url.Parse("https://example.com/verify?lang=en")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	tok, err := newVerificationToken()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	link, err := url.Parse(tokenLink(linkURL, tok))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if link.Query().Get("token") != string(tok) || link.Query().Get("lang") != "en" {
+		t.Errorf("unexpected link %s", link)
+	}
+	if linkURL.RawQuery != "lang=en" {
+		t.Error("expected the link url to be left as is")
+	}
+}
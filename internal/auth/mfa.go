@@ -0,0 +1,428 @@
+package auth
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/backupcode"
+	"github.com/Salam4nder/identity/internal/database/mfachallenge"
+	"github.com/Salam4nder/identity/internal/database/totp"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	otptotp "github.com/pquerna/otp/totp"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var (
+	// ErrMFANotEnrolled is returned when a user has not enrolled TOTP, or has not confirmed it yet.
+	ErrMFANotEnrolled = errors.New("auth: mfa not enrolled")
+	// ErrMFAAlreadyEnabled is returned when enrolling a user that already confirmed TOTP.
+	ErrMFAAlreadyEnabled = errors.New("auth: mfa already enabled")
+	// ErrInvalidMFACode is returned when a TOTP or backup code does not match, or was already used.
+	ErrInvalidMFACode = errors.New("auth: invalid mfa code")
+	// ErrInvalidMFAChallenge is returned when an MFA challenge is unknown, expired, answered or out of attempts.
+	ErrInvalidMFAChallenge = errors.New("auth: invalid mfa challenge")
+)
+
+const (
+	// MFAEncryptionKeySize is the size of the key TOTP secrets are encrypted with.
+	MFAEncryptionKeySize = 32
+	// MFAChallengeDuration is how long an MFA challenge can be answered.
+	MFAChallengeDuration = 5 * time.Minute
+	// MFAChallengeAttempts is how often an MFA challenge can be answered wrong before it is invalid.
+	MFAChallengeAttempts = 5
+	// BackupCodes is the amount of backup codes handed out when TOTP is confirmed.
+	BackupCodes = 10
+)
+
+const (
+	totpSecretSize = 20
+	totpPeriod     = 30
+	totpDigits     = otp.DigitsSix
+	// totpSkew is the amount of time steps before and after the current one that are accepted.
+	totpSkew       = 1
+	backupCodeSize = 10
+	challengeSize  = 32
+)
+
+var base32Encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// MFA enrolls users into TOTP (RFC 6238) and answers the challenges handed out
+// to users that authenticated their first factor.
+// TOTP secrets are encrypted with AES-256-GCM before they are stored, bound to the user they belong to.
+// Backup codes can be used once instead of a TOTP code, only their SHA-256 hashes are stored.
+type MFA struct {
+	db     *sql.DB
+	aead   cipher.AEAD
+	issuer string
+}
+
+// NewMFA returns a new [MFA] encrypting TOTP secrets with key, which must be [MFAEncryptionKeySize] long.
+// issuer is shown next to the account in authenticator apps.
+func NewMFA(db *sql.DB, issuer string, key []byte) (*MFA, error) {
+	if issuer == "" {
+		return nil, errors.New("auth: mfa issuer is empty")
+	}
+	if len(key) != MFAEncryptionKeySize {
+		return nil, fmt.Errorf("auth: mfa encryption key must be %d bytes, got %d", MFAEncryptionKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("auth: creating mfa cipher, %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("auth: creating mfa aead, %w", err)
+	}
+	return &MFA{db: db, aead: aead, issuer: issuer}, nil
+}
+
+// Enrollment is a TOTP secret waiting to be confirmed.
+type Enrollment struct {
+	// Secret is the base32 encoded secret, for users that type it into their authenticator app.
+	Secret token.SafeString
+	// URI is the otpauth:// URI of the secret, usually shown as a QR code.
+	URI token.SafeString
+}
+
+// Enabled reports whether the user has confirmed TOTP.
+func (x *MFA) Enabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	ctx, span := tracer.Start(ctx, "MFA.Enabled")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	entry, err := totp.Read(ctx, x.db, userID)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return false, nil
+		}
+		return false, err
+	}
+	return entry.ConfirmedAt != nil, nil
+}
+
+// Enroll generates a new TOTP secret for the user, replacing an unconfirmed one.
+// TOTP is not enabled until the secret is confirmed with [MFA.Confirm].
+// accountName is shown in authenticator apps, usually the email of the user.
+// Returns [ErrMFAAlreadyEnabled] if the user already confirmed TOTP.
+func (x *MFA) Enroll(ctx context.Context, userID uuid.UUID, accountName string) (Enrollment, error) {
+	ctx, span := tracer.Start(ctx, "MFA.Enroll")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return Enrollment{}, fmt.Errorf("auth: reading random bytes, %w", err)
+	}
+	key, err := otptotp.Generate(otptotp.GenerateOpts{
+		Issuer:      x.issuer,
+		AccountName: accountName,
+		Period:      totpPeriod,
+		Secret:      secret,
+		Digits:      totpDigits,
+		Algorithm:   otp.AlgorithmSHA1,
+	})
+	if err != nil {
+		return Enrollment{}, fmt.Errorf("auth: generating totp key, %w", err)
+	}
+
+	encrypted, err := x.encrypt(userID, secret)
+	if err != nil {
+		return Enrollment{}, err
+	}
+	if err = totp.Upsert(ctx, x.db, userID, encrypted, time.Now()); err != nil {
+		if errors.As(err, &database.DuplicateEntryError{}) {
+			return Enrollment{}, ErrMFAAlreadyEnabled
+		}
+		return Enrollment{}, err
+	}
+
+	return Enrollment{Secret: token.SafeString(key.Secret()), URI: token.SafeString(key.URL())}, nil
+}
+
+// Confirm enables TOTP for the user with a code of the enrolled secret
+// and returns the backup codes of the user, they are only ever returned here.
+// Returns [ErrMFANotEnrolled], [ErrMFAAlreadyEnabled] or [ErrInvalidMFACode].
+func (x *MFA) Confirm(ctx context.Context, userID uuid.UUID, code string) ([]token.SafeString, error) {
+	ctx, span := tracer.Start(ctx, "MFA.Confirm")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	entry, err := totp.Read(ctx, tx, userID)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = ErrMFANotEnrolled
+		}
+		return nil, errors.Join(err, tx.Rollback())
+	}
+	if entry.ConfirmedAt != nil {
+		return nil, errors.Join(ErrMFAAlreadyEnabled, tx.Rollback())
+	}
+	now := time.Now()
+	if err = x.useTOTP(ctx, tx, entry, normalizeCode(code), now); err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+	if err = backupcode.Replace(ctx, tx, userID, hashes, now); err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return codes, nil
+}
+
+// Disable turns TOTP off for the user and deletes the backup codes,
+// code is either a TOTP or a backup code.
+// Returns [ErrMFANotEnrolled] or [ErrInvalidMFACode].
+func (x *MFA) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	ctx, span := tracer.Start(ctx, "MFA.Disable")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	if err = x.useCode(ctx, tx, userID, code, time.Now()); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err = totp.Delete(ctx, tx, userID); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err = backupcode.DeleteByUser(ctx, tx, userID); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
+
+// ChallengeParams define the session started once an MFA challenge is answered.
+type ChallengeParams struct {
+	UserID     uuid.UUID
+	Strategy   string
+	RememberMe bool
+}
+
+// Challenge hands out a challenge to a user that authenticated its first factor,
+// it has to be answered with [MFA.Verify] within [MFAChallengeDuration].
+// Only the hash of the challenge is stored.
+func (x *MFA) Challenge(ctx context.Context, params ChallengeParams) (token.SafeString, error) {
+	ctx, span := tracer.Start(ctx, "MFA.Challenge")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("user_id", params.UserID.String()),
+		attribute.String("strategy", params.Strategy),
+	)
+
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("auth: reading random bytes, %w", err)
+	}
+	challenge := token.SafeString(base64.RawURLEncoding.EncodeToString(b))
+
+	now := time.Now()
+	if err := mfachallenge.Insert(ctx, x.db, mfachallenge.InsertParams{
+		ID:         uuid.New(),
+		TokenHash:  token.Hash(challenge),
+		UserID:     params.UserID,
+		Strategy:   params.Strategy,
+		RememberMe: params.RememberMe,
+		ExpiresAt:  now.Add(MFAChallengeDuration),
+		CreatedAt:  now,
+	}); err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// Verify answers an MFA challenge with a TOTP or backup code and returns the parameters it was handed out with,
+// so a session can be started for the user. A challenge can be answered once.
+// Returns [ErrInvalidMFAChallenge] or [ErrInvalidMFACode], which counts against the attempts of the challenge.
+func (x *MFA) Verify(ctx context.Context, challenge token.SafeString, code string) (ChallengeParams, error) {
+	ctx, span := tracer.Start(ctx, "MFA.Verify")
+	defer span.End()
+
+	if challenge == "" {
+		return ChallengeParams{}, ErrInvalidMFAChallenge
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return ChallengeParams{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	now := time.Now()
+	entry, err := mfachallenge.ReadByHashForUpdate(ctx, tx, token.Hash(challenge))
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = ErrInvalidMFAChallenge
+		}
+		return ChallengeParams{}, errors.Join(err, tx.Rollback())
+	}
+	if entry.UsedAt != nil || now.After(entry.ExpiresAt) || entry.Attempts >= MFAChallengeAttempts {
+		return ChallengeParams{}, errors.Join(ErrInvalidMFAChallenge, tx.Rollback())
+	}
+	span.SetAttributes(attribute.String("user_id", entry.UserID.String()))
+
+	if err = x.useCode(ctx, tx, entry.UserID, code, now); err != nil {
+		if !errors.Is(err, ErrInvalidMFACode) {
+			return ChallengeParams{}, errors.Join(err, tx.Rollback())
+		}
+		// A wrong code still has to commit the attempt.
+		if aErr := mfachallenge.RecordAttempt(ctx, tx, entry.ID); aErr != nil {
+			return ChallengeParams{}, errors.Join(err, aErr, tx.Rollback())
+		}
+		if cErr := tx.Commit(); cErr != nil {
+			return ChallengeParams{}, errors.Join(err, database.NewOperationFailedError(ctx, cErr))
+		}
+		return ChallengeParams{}, err
+	}
+	if err = mfachallenge.MarkUsed(ctx, tx, entry.ID, now); err != nil {
+		return ChallengeParams{}, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return ChallengeParams{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	return ChallengeParams{UserID: entry.UserID, Strategy: entry.Strategy, RememberMe: entry.RememberMe}, nil
+}
+
+// useCode uses a TOTP code of the confirmed secret of the user, or one of its backup codes.
+func (x *MFA) useCode(ctx context.Context, db database.Querier, userID uuid.UUID, code string, now time.Time) error {
+	entry, err := totp.Read(ctx, db, userID)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return ErrMFANotEnrolled
+		}
+		return err
+	}
+	if entry.ConfirmedAt == nil {
+		return ErrMFANotEnrolled
+	}
+
+	code = normalizeCode(code)
+	if len(code) == totpDigits.Length() {
+		return x.useTOTP(ctx, db, entry, code, now)
+	}
+	if err = backupcode.Use(ctx, db, userID, hashBackupCode(code), now); err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+// useTOTP checks code against the time steps around now and records the matching step,
+// so neither it nor an earlier one can be used again.
+func (x *MFA) useTOTP(ctx context.Context, db database.Querier, entry *totp.Entry, code string, now time.Time) error {
+	secret, err := x.decrypt(entry.UserID, entry.Secret)
+	if err != nil {
+		return err
+	}
+	encoded := base32Encoding.EncodeToString(secret)
+
+	current := now.Unix() / totpPeriod
+	matched := int64(-1)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		want, err := hotp.GenerateCodeCustom(encoded, uint64(step), hotp.ValidateOpts{
+			Digits:    totpDigits,
+			Algorithm: otp.AlgorithmSHA1,
+		})
+		if err != nil {
+			return fmt.Errorf("auth: generating totp code, %w", err)
+		}
+		if subtle.ConstantTimeCompare([]byte(want), []byte(code)) == 1 {
+			matched = step
+		}
+	}
+	if matched < 0 {
+		return ErrInvalidMFACode
+	}
+
+	if err = totp.UseStep(ctx, db, entry.UserID, matched, now); err != nil {
+		if errors.As(err, &database.RowsAffectedError{}) {
+			return ErrInvalidMFACode
+		}
+		return err
+	}
+	return nil
+}
+
+func (x *MFA) encrypt(userID uuid.UUID, secret []byte) ([]byte, error) {
+	nonce := make([]byte, x.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("auth: reading random bytes, %w", err)
+	}
+	return x.aead.Seal(nonce, nonce, secret, userID[:]), nil
+}
+
+func (x *MFA) decrypt(userID uuid.UUID, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < x.aead.NonceSize() {
+		return nil, errors.New("auth: encrypted totp secret is too short")
+	}
+	nonce, ciphertext := encrypted[:x.aead.NonceSize()], encrypted[x.aead.NonceSize():]
+	secret, err := x.aead.Open(nil, nonce, ciphertext, userID[:])
+	if err != nil {
+		return nil, fmt.Errorf("auth: decrypting totp secret, %w", err)
+	}
+	return secret, nil
+}
+
+// newBackupCodes returns [BackupCodes] random backup codes, formatted for users, and their hashes.
+func newBackupCodes() ([]token.SafeString, []string, error) {
+	codes := make([]token.SafeString, 0, BackupCodes)
+	hashes := make([]string, 0, BackupCodes)
+	for range BackupCodes {
+		// Every 5 bytes are 8 base32 characters, read enough for one code.
+		b := make([]byte, backupCodeSize*5/8)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("auth: reading random bytes, %w", err)
+		}
+		code := strings.ToLower(base32Encoding.EncodeToString(b))[:backupCodeSize]
+		codes = append(codes, token.SafeString(code[:backupCodeSize/2]+"-"+code[backupCodeSize/2:]))
+		hashes = append(hashes, hashBackupCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// normalizeCode strips what users add to codes when they type them.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+}
+
+func hashBackupCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
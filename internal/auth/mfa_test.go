@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+)
+
+func TestNewMFA(t *testing.T) {
+	if _, err := NewMFA(nil, "identity", []byte(random.String(MFAEncryptionKeySize))); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if _, err := NewMFA(nil, "identity", []byte("short")); err == nil {
+		t.Error("expected error with short key")
+	}
+	if _, err := NewMFA(nil, "", []byte(random.String(MFAEncryptionKeySize))); err == nil {
+		t.Error("expected error with empty issuer")
+	}
+}
+
+func TestMFAEncryption(t *testing.T) {
+	mfa, err := NewMFA(nil, "identity", []byte(random.String(MFAEncryptionKeySize)))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	userID := uuid.New()
+
+	encrypted, err := mfa.encrypt(userID, []byte("secret"))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if strings.Contains(string(encrypted), "secret") {
+		t.Error("expected the secret to be encrypted")
+	}
+	secret, err := mfa.decrypt(userID, encrypted)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if string(secret) != "secret" {
+		t.Errorf("expected secret, got %s", secret)
+	}
+
+	if _, err = mfa.decrypt(uuid.New(), encrypted); err == nil {
+		t.Error("expected error decrypting the secret of another user")
+	}
+	if _, err = mfa.decrypt(userID, encrypted[:4]); err == nil {
+		t.Error("expected error with short secret")
+	}
+}
+
+func TestNewBackupCodes(t *testing.T) {
+	codes, hashes, err := newBackupCodes()
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if len(codes) != BackupCodes || len(hashes) != BackupCodes {
+		t.Fatalf("expected %d codes, got %d codes and %d hashes", BackupCodes, len(codes), len(hashes))
+	}
+
+	seen := make(map[string]struct{}, len(hashes))
+	for i, code := range codes {
+		if len(code) != backupCodeSize+1 || code[backupCodeSize/2] != '-' {
+			t.Errorf("unexpected code format %s", code)
+		}
+		// Users may type codes in upper case and without the dash.
+		if got := hashBackupCode(normalizeCode(strings.ToUpper(string(code)))); got != hashes[i] {
+			t.Errorf("expected the normalized code to match hash %s, got %s", hashes[i], got)
+		}
+		seen[hashes[i]] = struct{}{}
+	}
+	if len(seen) != BackupCodes {
+		t.Error("expected unique codes")
+	}
+}
@@ -0,0 +1,246 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/credentials"
+	"github.com/Salam4nder/identity/internal/database/verificationtoken"
+	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/pkg/password"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrNoPassword is returned when changing the password of a user that did not register with credentials.
+var ErrNoPassword = errors.New("auth: user has no password")
+
+const (
+	// PasswordResetDuration is how long a password reset token can be used.
+	PasswordResetDuration = 30 * time.Minute
+	// PasswordResetSends is how many password reset emails a user is sent within [PasswordResetWindow].
+	PasswordResetSends = 3
+	// PasswordResetWindow is the window [PasswordResetSends] are counted in.
+	PasswordResetWindow = time.Hour
+)
+
+// Passwords changes the passwords of users of the credentials strategy. Users that forgot their password
+// are emailed a single use token that sets a new one, only the SHA-256 hash of a token is stored.
+type Passwords struct {
+	db        *sql.DB
+	publisher email.Publisher
+	tokens    *Tokens
+	linkURL   *url.URL
+}
+
+// NewPasswords returns a new [Passwords] that ends the sessions of a user with tokens once
+// its password is reset. Reset tokens are emailed as a link to resetLinkURL with the token as query parameter,
+// without a resetLinkURL the token itself is emailed.
+func NewPasswords(db *sql.DB, publisher email.Publisher, tokens *Tokens, resetLinkURL string) (*Passwords, error) {
+	x := &Passwords{db: db, publisher: publisher, tokens: tokens}
+	if resetLinkURL != "" {
+		var err error
+		if x.linkURL, err = url.Parse(resetLinkURL); err != nil {
+			return nil, fmt.Errorf("auth: password reset, parsing link url, %w", err)
+		}
+	}
+	return x, nil
+}
+
+// RequestReset emails a password reset token to the credentials entry registered with address,
+// it has to be used with [Passwords.Reset] within [PasswordResetDuration].
+// Nothing is sent if there is no such entry, or it was sent [PasswordResetSends] tokens within
+// [PasswordResetWindow], without telling the caller.
+func (x *Passwords) RequestReset(ctx context.Context, address string) error {
+	ctx, span := tracer.Start(ctx, "Passwords.RequestReset")
+	defer span.End()
+
+	entry, err := credentials.ReadByEmail(ctx, x.db, address)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return nil
+		}
+		return err
+	}
+	span.SetAttributes(attribute.String("user_id", entry.ID.String()))
+
+	var tok token.SafeString
+	if err = throttle(
+		ctx,
+		x.db,
+		entry.ID,
+		verificationtoken.PurposePasswordReset,
+		PasswordResetSends,
+		PasswordResetWindow,
+		func(tx *sql.Tx, now time.Time) error {
+			var err error
+			tok, err = x.insertResetToken(ctx, tx, entry.ID, now)
+			return err
+		},
+	); err != nil {
+		if errors.Is(err, ErrTooManyDeliveries) {
+			span.SetAttributes(attribute.Bool("throttled", true))
+			return nil
+		}
+		return err
+	}
+	return x.emailResetToken(ctx, entry.Email, tok, "If you did not ask for it, ignore this email.")
+}
+
+// insertResetToken inserts a new password reset token for the user and returns it.
+func (x *Passwords) insertResetToken(
+	ctx context.Context,
+	db database.Querier,
+	userID uuid.UUID,
+	now time.Time,
+) (token.SafeString, error) {
+	tok, err := newVerificationToken()
+	if err != nil {
+		return "", err
+	}
+	if err = verificationtoken.Insert(ctx, db, verificationtoken.InsertParams{
+		ID:        uuid.New(),
+		TokenHash: token.Hash(tok),
+		UserID:    userID,
+		Purpose:   verificationtoken.PurposePasswordReset,
+		ExpiresAt: now.Add(PasswordResetDuration),
+		CreatedAt: now,
+	}); err != nil {
+		return "", err
+	}
+	return tok, nil
+}
+
+// emailResetToken emails a password reset token to address, note ends the email.
+func (x *Passwords) emailResetToken(ctx context.Context, address string, tok token.SafeString, note string) error {
+	body := fmt.Sprintf("Reset your password with the token %s.", string(tok))
+	if x.linkURL != nil {
+		body = fmt.Sprintf("Reset your password by opening %s.", tokenLink(x.linkURL, tok))
+	}
+	return email.Ingest(ctx, x.publisher, email.Email{
+		To:      address,
+		From:    email.TestFrom,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"%s It expires in %d minutes. %s",
+			body,
+			int(PasswordResetDuration/time.Minute),
+			note,
+		),
+	})
+}
+
+// Reset uses a password reset token to replace the password of its user with pw,
+// then ends every session of the user. Following the emailed token also verifies the email.
+// The other unused reset tokens of the user are expired, an older leaked one can not reset it again.
+// Returns the ID of the user, or [ErrInvalidVerificationToken].
+func (x *Passwords) Reset(ctx context.Context, tok token.SafeString, pw password.SafeString) (uuid.UUID, error) {
+	ctx, span := tracer.Start(ctx, "Passwords.Reset")
+	defer span.End()
+
+	if tok == "" {
+		return uuid.Nil, ErrInvalidVerificationToken
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	now := time.Now()
+	entry, err := verificationtoken.ReadByHashForUpdate(ctx, tx, token.Hash(tok))
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = ErrInvalidVerificationToken
+		}
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if entry.Purpose != verificationtoken.PurposePasswordReset ||
+		entry.UsedAt != nil ||
+		now.After(entry.ExpiresAt) {
+		return uuid.Nil, errors.Join(ErrInvalidVerificationToken, tx.Rollback())
+	}
+	span.SetAttributes(attribute.String("user_id", entry.UserID.String()))
+
+	if err = verificationtoken.MarkUsed(ctx, tx, entry.ID, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if _, err = verificationtoken.ExpireUnused(ctx, tx, entry.UserID, verificationtoken.PurposePasswordReset, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = credentials.UpdatePassword(ctx, tx, entry.UserID, pw); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = credentials.MarkEmailVerified(ctx, tx, entry.UserID, now); err != nil {
+		return uuid.Nil, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return uuid.Nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	if _, err = x.tokens.RevokeSessions(ctx, entry.UserID, uuid.Nil); err != nil {
+		return uuid.Nil, err
+	}
+
+	return entry.UserID, nil
+}
+
+// ChangeParams define a password change by [Passwords.Change].
+type ChangeParams struct {
+	UserID uuid.UUID
+	// SessionID is the session the change is made from, it is kept when RevokeOtherSessions is set.
+	SessionID uuid.UUID
+	Current   password.SafeString
+	New       password.SafeString
+	// RevokeOtherSessions ends every other session of the user, logging it out everywhere else.
+	RevokeOtherSessions bool
+}
+
+// Change replaces the password of a user after checking its current password,
+// then emails the user that its password was changed.
+// Returns [ErrNoPassword] if the user did not register with credentials
+// and [ErrInvalidCredentials] if the current password does not match.
+func (x *Passwords) Change(ctx context.Context, params ChangeParams) error {
+	ctx, span := tracer.Start(ctx, "Passwords.Change")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("user_id", params.UserID.String()),
+		attribute.Bool("revoke_other_sessions", params.RevokeOtherSessions),
+	)
+
+	entry, err := credentials.Read(ctx, x.db, params.UserID)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return ErrNoPassword
+		}
+		return err
+	}
+	if err = params.Current.Compare(entry.PasswordHash); err != nil {
+		if errors.As(err, &password.MismatchError{}) {
+			return ErrInvalidCredentials
+		}
+		return err
+	}
+
+	if err = credentials.UpdatePassword(ctx, x.db, entry.ID, params.New); err != nil {
+		return err
+	}
+	if params.RevokeOtherSessions {
+		if _, err = x.tokens.RevokeSessions(ctx, entry.ID, params.SessionID); err != nil {
+			return err
+		}
+	}
+
+	return email.Ingest(ctx, x.publisher, email.Email{
+		To:      entry.Email,
+		From:    email.TestFrom,
+		Subject: "Your password was changed",
+		Body:    "The password of your identity was changed. If it was not you, reset your password right away.",
+	})
+}
@@ -0,0 +1,21 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestNewPasswords(t *testing.T) {
+	if _, err := NewPasswords(nil, nil, nil, "://invalid"); err == nil {
+		t.Error("expected error with an invalid link url")
+	}
+
+	x, err := NewPasswords(nil, nil, nil, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if _, err = x.Reset(context.Background(), "", "password"); !errors.Is(err, ErrInvalidVerificationToken) {
+		t.Errorf("expected ErrInvalidVerificationToken, got %v", err)
+	}
+}
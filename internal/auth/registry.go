@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/Salam4nder/identity/proto/gen"
+)
+
+// ErrUnsupportedStrategy is returned when a strategy is not registered.
+var ErrUnsupportedStrategy = errors.New("auth: unsupported strategy")
+
+// Registry holds every configured [Strategy] keyed by its [gen.Strategy].
+// It is populated once on startup and is safe for concurrent reads.
+type Registry struct {
+	strategies map[gen.Strategy]Strategy
+}
+
+// NewRegistry returns a [Registry] with the given strategies.
+// Returns an error if a strategy is registered twice or is [gen.Strategy_NoStrategy].
+func NewRegistry(strategies ...Strategy) (*Registry, error) {
+	r := &Registry{strategies: make(map[gen.Strategy]Strategy, len(strategies))}
+	for _, s := range strategies {
+		key := s.ConfiguredStrategy()
+		if key == gen.Strategy_NoStrategy {
+			return nil, fmt.Errorf("auth: registering %T, %w", s, ErrUnsupportedStrategy)
+		}
+		if _, ok := r.strategies[key]; ok {
+			return nil, fmt.Errorf("auth: strategy %s is registered twice", key)
+		}
+		r.strategies[key] = s
+	}
+	return r, nil
+}
+
+// Get returns the [Strategy] registered for s.
+// Returns [ErrUnsupportedStrategy] if there is none.
+func (x *Registry) Get(s gen.Strategy) (Strategy, error) {
+	strategy, ok := x.strategies[s]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedStrategy, s)
+	}
+	return strategy, nil
+}
+
+// Strategies returns every registered [gen.Strategy] in enum order.
+func (x *Registry) Strategies() []gen.Strategy {
+	keys := make([]gen.Strategy, 0, len(x.strategies))
+	for k := range x.strategies {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
@@ -0,0 +1,73 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Salam4nder/identity/proto/gen"
+)
+
+type fakeStrategy struct {
+	Strategy
+	s gen.Strategy
+}
+
+func (x fakeStrategy) ConfiguredStrategy() gen.Strategy {
+	return x.s
+}
+
+func (x fakeStrategy) ParseInput(context.Context, *gen.Input) (Input, error) {
+	return nil, nil
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		r, err := NewRegistry(
+			fakeStrategy{s: gen.Strategy_PersonalNumber},
+			fakeStrategy{s: gen.Strategy_Credentials},
+		)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+
+		s, err := r.Get(gen.Strategy_Credentials)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if s.ConfiguredStrategy() != gen.Strategy_Credentials {
+			t.Errorf("expected %s, got %s", gen.Strategy_Credentials, s.ConfiguredStrategy())
+		}
+
+		got := r.Strategies()
+		if len(got) != 2 || got[0] != gen.Strategy_Credentials || got[1] != gen.Strategy_PersonalNumber {
+			t.Errorf("unexpected strategies %v", got)
+		}
+	})
+
+	t.Run("unregistered strategy returns error", func(t *testing.T) {
+		r, err := NewRegistry(fakeStrategy{s: gen.Strategy_Credentials})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = r.Get(gen.Strategy_PersonalNumber); !errors.Is(err, ErrUnsupportedStrategy) {
+			t.Errorf("expected ErrUnsupportedStrategy, got %v", err)
+		}
+	})
+
+	t.Run("duplicate strategy returns error", func(t *testing.T) {
+		_, err := NewRegistry(
+			fakeStrategy{s: gen.Strategy_Credentials},
+			fakeStrategy{s: gen.Strategy_Credentials},
+		)
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("NoStrategy returns error", func(t *testing.T) {
+		if _, err := NewRegistry(fakeStrategy{s: gen.Strategy_NoStrategy}); !errors.Is(err, ErrUnsupportedStrategy) {
+			t.Errorf("expected ErrUnsupportedStrategy, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/refreshtoken"
+	"github.com/Salam4nder/identity/internal/database/session"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ErrSessionNotFound is returned when a user has no active session with the given ID.
+var ErrSessionNotFound = errors.New("auth: session not found")
+
+// Sessions returns the active sessions of the user, most recently seen first.
+// A session is seen whenever its refresh token is renewed.
+func (x *Tokens) Sessions(ctx context.Context, userID uuid.UUID) ([]session.Entry, error) {
+	ctx, span := tracer.Start(ctx, "Sessions")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	return session.ListActive(ctx, x.db, userID, time.Now())
+}
+
+// RevokeSession ends a session of the user. Its refresh tokens can no longer be renewed
+// and its access tokens are denied until the session would have expired.
+// Returns [ErrSessionNotFound] if the user has no such session or it already ended.
+func (x *Tokens) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "RevokeSession")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("session_id", sessionID.String()),
+	)
+
+	now := time.Now()
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	entry, err := session.Revoke(ctx, tx, sessionID, userID, now)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = ErrSessionNotFound
+		}
+		return errors.Join(err, tx.Rollback())
+	}
+	if _, err = refreshtoken.RevokeFamily(ctx, tx, entry.ID, now); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return x.denylist.Deny(ctx, entry.ID.String(), entry.ExpiresAt)
+}
+
+// RevokeSessions ends every session of the user like [Tokens.RevokeSession], except the given one,
+// which can be [uuid.Nil] to log out everywhere. Returns the amount of ended sessions.
+func (x *Tokens) RevokeSessions(ctx context.Context, userID, except uuid.UUID) (int, error) {
+	ctx, span := tracer.Start(ctx, "RevokeSessions")
+	defer span.End()
+	span.SetAttributes(attribute.String("user_id", userID.String()))
+
+	now := time.Now()
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	entries, err := session.RevokeByUser(ctx, tx, userID, except, now)
+	if err != nil {
+		return 0, errors.Join(err, tx.Rollback())
+	}
+	for _, entry := range entries {
+		if _, err = refreshtoken.RevokeFamily(ctx, tx, entry.ID, now); err != nil {
+			return 0, errors.Join(err, tx.Rollback())
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+	span.SetAttributes(attribute.Int("revoked_sessions", len(entries)))
+
+	for _, entry := range entries {
+		if err = x.denylist.Deny(ctx, entry.ID.String(), entry.ExpiresAt); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(entries), nil
+}
@@ -0,0 +1,179 @@
+//go:build testdb
+// +build testdb
+
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokensSessions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("issue starts a session", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		pair, err := tokens.Issue(ctx, IssueParams{
+			UserID:    userID,
+			Strategy:  "Credentials",
+			UserAgent: "test-agent",
+			ClientIP:  "127.0.0.1:1234",
+		})
+		require.NoError(t, err)
+
+		sessions, err := tokens.Sessions(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, pair.SessionID, sessions[0].ID)
+		require.Equal(t, "Credentials", sessions[0].Strategy)
+		require.Equal(t, "test-agent", sessions[0].UserAgent)
+		require.Equal(t, "127.0.0.1:1234", sessions[0].ClientIP)
+
+		claims, err := tokens.maker.Verify(ctx, pair.AccessToken, token.TypeAccess)
+		require.NoError(t, err)
+		require.Equal(t, pair.SessionID, claims.SessionID())
+
+		renewed, err := tokens.Renew(ctx, pair.RefreshToken)
+		require.NoError(t, err)
+		require.Equal(t, pair.SessionID, renewed.SessionID)
+
+		sessions, err = tokens.Sessions(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.True(t, sessions[0].ExpiresAt.Equal(renewed.RefreshExpiresAt))
+	})
+
+	t.Run("revoke session", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		a, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		b, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+
+		require.ErrorIs(t, tokens.RevokeSession(ctx, uuid.New(), a.SessionID), ErrSessionNotFound)
+		require.NoError(t, tokens.RevokeSession(ctx, userID, a.SessionID))
+		require.ErrorIs(t, tokens.RevokeSession(ctx, userID, a.SessionID), ErrSessionNotFound)
+
+		_, err = tokens.maker.Verify(ctx, a.AccessToken, token.TypeAccess)
+		require.ErrorIs(t, err, token.ErrRevoked)
+		_, err = tokens.Renew(ctx, a.RefreshToken)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		_, err = tokens.maker.Verify(ctx, b.AccessToken, token.TypeAccess)
+		require.NoError(t, err)
+		sessions, err := tokens.Sessions(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 1)
+		require.Equal(t, b.SessionID, sessions[0].ID)
+	})
+
+	t.Run("revoking the refresh token ends the session", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		require.NoError(t, tokens.Revoke(ctx, "", pair.RefreshToken))
+
+		sessions, err := tokens.Sessions(ctx, userID)
+		require.NoError(t, err)
+		require.Empty(t, sessions)
+	})
+
+	t.Run("revoke sessions", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		current, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		for range 2 {
+			_, err = tokens.Issue(ctx, IssueParams{UserID: userID})
+			require.NoError(t, err)
+		}
+
+		n, err := tokens.RevokeSessions(ctx, userID, current.SessionID)
+		require.NoError(t, err)
+		require.Equal(t, 2, n)
+		_, err = tokens.maker.Verify(ctx, current.AccessToken, token.TypeAccess)
+		require.NoError(t, err)
+
+		n, err = tokens.RevokeSessions(ctx, userID, uuid.Nil)
+		require.NoError(t, err)
+		require.Equal(t, 1, n)
+		_, err = tokens.maker.Verify(ctx, current.AccessToken, token.TypeAccess)
+		require.ErrorIs(t, err, token.ErrRevoked)
+	})
+}
+
+func TestTokensSessionPolicies(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("remember me", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		tokens.policies = SessionPolicies{
+			Default:    SessionPolicy{IdleTimeout: time.Hour},
+			RememberMe: SessionPolicy{IdleTimeout: 24 * time.Hour, AbsoluteTimeout: 48 * time.Hour},
+		}
+		userID := uuid.New()
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		require.Equal(t, time.Hour, pair.RefreshExpiresAt.Sub(pair.IssuedAt))
+
+		pair, err = tokens.Issue(ctx, IssueParams{UserID: userID, RememberMe: true})
+		require.NoError(t, err)
+		require.Equal(t, 24*time.Hour, pair.RefreshExpiresAt.Sub(pair.IssuedAt))
+
+		renewed, err := tokens.Renew(ctx, pair.RefreshToken)
+		require.NoError(t, err)
+		require.Equal(t, 24*time.Hour, renewed.RefreshExpiresAt.Sub(renewed.IssuedAt))
+
+		sessions, err := tokens.Sessions(ctx, userID)
+		require.NoError(t, err)
+		require.Len(t, sessions, 2)
+		for _, s := range sessions {
+			if s.ID == pair.SessionID {
+				require.True(t, s.RememberMe)
+				require.NotNil(t, s.AbsoluteExpiresAt)
+			} else {
+				require.False(t, s.RememberMe)
+				require.Nil(t, s.AbsoluteExpiresAt)
+			}
+		}
+	})
+
+	t.Run("absolute timeout", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		tokens.policies = SessionPolicies{Default: SessionPolicy{IdleTimeout: time.Hour, AbsoluteTimeout: 2 * time.Second}}
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: uuid.New()})
+		require.NoError(t, err)
+		require.LessOrEqual(t, pair.RefreshExpiresAt.Sub(pair.IssuedAt), 2*time.Second)
+		require.LessOrEqual(t, pair.AccessExpiresAt.Sub(pair.IssuedAt), 2*time.Second)
+
+		time.Sleep(2 * time.Second)
+		_, err = tokens.Renew(ctx, pair.RefreshToken)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		tokens.policies = SessionPolicies{Default: SessionPolicy{IdleTimeout: time.Second}}
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: uuid.New()})
+		require.NoError(t, err)
+
+		time.Sleep(2 * time.Second)
+		_, err = tokens.Renew(ctx, pair.RefreshToken)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
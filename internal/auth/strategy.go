@@ -2,20 +2,88 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
 )
 
+// ErrInvalidCredentials is returned when an entry could not be authenticated.
+// It does not reveal whether the entry exists or the secret did not match.
+var ErrInvalidCredentials = errors.New("auth: invalid credentials")
+
+// ErrInvalidName is returned by a [ChallengeStrategy] when a name can not be registered.
+var ErrInvalidName = errors.New("auth: invalid name")
+
+var (
+	// ErrInvalidAddress is returned by a [DeliveryStrategy] when it can not deliver to an address.
+	ErrInvalidAddress = errors.New("auth: invalid address")
+	// ErrUnsupportedDeliveryMethod is returned by a [DeliveryStrategy] for a method it does not deliver with.
+	ErrUnsupportedDeliveryMethod = errors.New("auth: unsupported delivery method")
+	// ErrTooManyDeliveries is returned when an address was delivered to too often, by a [DeliveryStrategy],
+	// an [EmailVerification] or [EmailChanges].
+	ErrTooManyDeliveries = errors.New("auth: too many deliveries")
+)
+
+// Input is a validated, strategy specific input.
+// Every call to a [Strategy] receives its own [Input],
+// so a [Strategy] never has to hold per request state.
+type Input interface {
+	// Strategy returns the strategy the input is meant for.
+	Strategy() gen.Strategy
+	// TraceAttributes returns span attributes that are safe to record.
+	TraceAttributes() []attribute.KeyValue
+}
+
+// Result is returned by a [Strategy] on a successful registration or authentication.
+type Result struct {
+	// UserID is the ID of the registered or authenticated entry.
+	UserID uuid.UUID
+}
+
+// Strategy is an authentication strategy.
+// Implementations must be safe for concurrent use.
+// Renewing and revoking tokens is the same for every strategy and is done by [Tokens].
 type Strategy interface {
 	// ConfiguredStrategy exposes the current configured strategy.
 	ConfiguredStrategy() gen.Strategy
+	// ParseInput validates a generated request and turns it into the [Input]
+	// expected by the rest of the methods.
+	ParseInput(context.Context, *gen.Input) (Input, error)
 
-	// Renew will trade a valid refresh token for a new access token.
-	Renew(context.Context) error
-	// Revoke will purge all active tokens in the configured hot-storage.
-	Revoke(context.Context) error
 	// Register an entry with the configured strategy.
-	Register(context.Context) error
+	Register(context.Context, Input) (Result, error)
 	// Authenticate the user with the configured strategy.
-	Authenticate(context.Context) error
+	Authenticate(context.Context, Input) (Result, error)
+}
+
+// Challenge begins a ceremony of a [ChallengeStrategy], it is answered by the [Input]
+// of the following Register or Authenticate.
+type Challenge struct {
+	ID uuid.UUID
+	// Options are what the client needs to answer the challenge, in a strategy specific encoding.
+	Options   []byte
+	ExpiresAt time.Time
+}
+
+// ChallengeStrategy is a [Strategy] that hands out a single use [Challenge] before every
+// Register and Authenticate, like WebAuthn does.
+type ChallengeStrategy interface {
+	Strategy
+	// BeginRegistration hands out a challenge to register a new entry under name.
+	BeginRegistration(ctx context.Context, name, displayName string) (Challenge, error)
+	// BeginAuthentication hands out a challenge to authenticate the entry registered under name,
+	// an empty name lets the client choose the entry.
+	BeginAuthentication(ctx context.Context, name string) (Challenge, error)
+}
+
+// DeliveryStrategy is a [Strategy] that signs users in without a password, by delivering a single use
+// code or link to their address. The [Challenge] of a delivery is answered by the [Input]
+// of the following Register or Authenticate.
+type DeliveryStrategy interface {
+	Strategy
+	// Deliver sends a code or link to address and returns its challenge, which has no options.
+	Deliver(ctx context.Context, address string, method gen.DeliveryMethod) (Challenge, error)
 }
@@ -3,18 +3,18 @@ package strategy
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"time"
 	"unicode/utf8"
 
 	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/database"
 	"github.com/Salam4nder/identity/internal/database/credentials"
-	"github.com/Salam4nder/identity/internal/email"
 	"github.com/Salam4nder/identity/pkg/password"
 	"github.com/Salam4nder/identity/pkg/validation"
 	"github.com/Salam4nder/identity/proto/gen"
 	"github.com/google/uuid"
-	"github.com/nats-io/nats.go"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -22,100 +22,147 @@ import (
 
 var tracer = otel.Tracer("strategy")
 
-var _ auth.Strategy = (*Credentials)(nil)
+var (
+	_ auth.Strategy = (*Credentials)(nil)
+	_ auth.Input    = CredentialsInput{}
+)
+
+// dummyHash is compared against when no entry is found for an email,
+// so that unknown and known emails take roughly the same time to authenticate.
+const dummyHash = "$2a$10$92n9UoQ3Td87kzzS/XB1IeTMEyRsElHHS4qUaRS.hW9PJ6ugMIEGq"
 
 type (
-	// Credentials implements the [Strategy] interface and has everything
-	// to be able to [Register()], [Authenticate()] and [Revoke()] with credentials.
+	// Credentials implements the [auth.Strategy] interface and has everything
+	// to be able to [Register()] and [Authenticate()] with credentials.
+	// It holds no per request state and is safe for concurrent use.
 	Credentials struct {
-		db       *sql.DB
-		natsConn *nats.Conn
-
-		email    string
-		password password.SafeString
+		db           *sql.DB
+		verification *auth.EmailVerification
 	}
 
-	// CredentialsInput is the input for the credentials strategy.
+	// CredentialsInput is the validated input for the credentials strategy.
+	// It is created with [NewCredentialsInput()].
 	CredentialsInput struct {
 		Email    string
-		Password string
+		Password password.SafeString
 	}
 )
 
+// NewCredentialsInput validates the given email and password
+// and returns a [CredentialsInput] ready to be passed to [Credentials].
+func NewCredentialsInput(ctx context.Context, mail, pw string) (CredentialsInput, error) {
+	_, span := tracer.Start(ctx, "NewCredentialsInput")
+	defer span.End()
+
+	p, err := password.FromString(pw)
+	if err != nil {
+		return CredentialsInput{}, fmt.Errorf("strategy: credentials, %w", err)
+	}
+	if err = validation.Email(mail); err != nil {
+		return CredentialsInput{}, fmt.Errorf("strategy: credentials, %w", err)
+	}
+
+	return CredentialsInput{Email: mail, Password: p}, nil
+}
+
+func (x CredentialsInput) Strategy() gen.Strategy {
+	return gen.Strategy_Credentials
+}
+
 func (x CredentialsInput) TraceAttributes() []attribute.KeyValue {
 	return []attribute.KeyValue{
 		attribute.String("email", x.Email),
-		attribute.Int("password length", utf8.RuneCountInString((x.Password))),
+		attribute.Int("password length", utf8.RuneCountInString(string(x.Password))),
 	}
 }
 
 // NewCredentials creates a new [Credentials] strategy for authentication.
-// It still needs [CredentialsInput] to be able to call it's methods.
-// An [CredentialsInput] is created with [IngestInput()].
-func NewCredentials(db *sql.DB, natsConn *nats.Conn) *Credentials {
-	return &Credentials{db: db, natsConn: natsConn}
+// Its methods expect a [CredentialsInput], created with [NewCredentialsInput()].
+// Registered emails are verified with verification.
+func NewCredentials(db *sql.DB, verification *auth.EmailVerification) *Credentials {
+	return &Credentials{db: db, verification: verification}
 }
 
 func (x *Credentials) ConfiguredStrategy() gen.Strategy {
 	return gen.Strategy_Credentials
 }
 
-// IngestInput sets the input field of the underlying [Credentials].
-// [Credentials] is ready to call the rest of it's methods if this method returns no error.
-func (x *Credentials) IngestInput(ctx context.Context, input CredentialsInput) error {
-	_, span := tracer.Start(ctx, "IngestInput", trace.WithAttributes(input.TraceAttributes()...))
-	defer span.End()
+// ParseInput returns a [CredentialsInput] from the credentials of the request.
+func (x *Credentials) ParseInput(ctx context.Context, req *gen.Input) (auth.Input, error) {
+	c := req.GetCredentials()
+	if c == nil {
+		return nil, errors.New("strategy: credentials input is missing")
+	}
+	return NewCredentialsInput(ctx, c.GetEmail(), c.GetPassword())
+}
 
-	p, err := password.FromString(input.Password)
+// Register will handles registration with the credentials strategy.
+// It will insert a new [credentials.Entry] into the credentials table together with its verification token
+// and send a verification email to the registered user, see [auth.EmailVerification.Register].
+func (x *Credentials) Register(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := credentialsInput(in)
 	if err != nil {
-		return fmt.Errorf("strategy: credentials, %w", err)
-	}
-	if err = validation.Email(input.Email); err != nil {
-		return fmt.Errorf("strategy: credentials, %w", err)
+		return auth.Result{}, err
 	}
 
-	x.email = input.Email
-	x.password = p
+	ctx, span := tracer.Start(ctx, "Register", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
 
-	return nil
+	id, err := x.verification.Register(ctx, input.Email, func(tx *sql.Tx) (uuid.UUID, error) {
+		id := uuid.New()
+		return id, credentials.Insert(ctx, tx, credentials.InsertParams{
+			ID:        id,
+			Email:     input.Email,
+			Password:  input.Password,
+			CreatedAt: time.Now(),
+		})
+	})
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	return auth.Result{UserID: id}, nil
 }
 
-// Register will handles registration with the credentials strategy.
-// It will insert a new [credentials.Entry] into the credentials table
-// and send an email to the registered user.
-func (x *Credentials) Register(ctx context.Context) error {
-	ctx, span := tracer.Start(ctx, "Register")
+// Authenticate will read the [credentials.Entry] by email and compare
+// its password hash with the given password.
+// Returns [auth.ErrInvalidCredentials] if the entry does not exist or the password does not match,
+// and [auth.ErrEmailNotVerified] if verification is required and the email is not verified.
+func (x *Credentials) Authenticate(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := credentialsInput(in)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Authenticate", trace.WithAttributes(input.TraceAttributes()...))
 	defer span.End()
 
-	if err := credentials.Insert(ctx, x.db, credentials.InsertParams{
-		ID:        uuid.New(),
-		Email:     x.email,
-		Password:  x.password,
-		CreatedAt: time.Now(),
-	}); err != nil {
-		return err
+	entry, err := credentials.ReadByEmail(ctx, x.db, input.Email)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			_ = input.Password.Compare(dummyHash)
+			return auth.Result{}, auth.ErrInvalidCredentials
+		}
+		return auth.Result{}, err
 	}
 
-	if err := email.Ingest(ctx, x.natsConn, email.Email{
-		To:      x.email,
-		From:    email.TestFrom,
-		Subject: email.TestSubject,
-		Body:    email.TestBody,
-	}); err != nil {
-		return err
+	if err = input.Password.Compare(entry.PasswordHash); err != nil {
+		if errors.As(err, &password.MismatchError{}) {
+			return auth.Result{}, auth.ErrInvalidCredentials
+		}
+		return auth.Result{}, err
+	}
+	if x.verification.Required() && entry.EmailVerifiedAt == nil {
+		return auth.Result{}, auth.ErrEmailNotVerified
 	}
 
-	return nil
-}
-
-func (x *Credentials) Authenticate(_ context.Context) error {
-	return nil
-}
-
-func (x *Credentials) Revoke(_ context.Context) error {
-	return nil
+	return auth.Result{UserID: entry.ID}, nil
 }
 
-func (x *Credentials) Renew(_ context.Context) error {
-	return nil
+func credentialsInput(in auth.Input) (CredentialsInput, error) {
+	input, ok := in.(CredentialsInput)
+	if !ok {
+		return CredentialsInput{}, fmt.Errorf("strategy: credentials, unsupported input %T", in)
+	}
+	return input, nil
 }
@@ -0,0 +1,159 @@
+package strategy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/emailuser"
+	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/pkg/validation"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ auth.DeliveryStrategy = (*Email)(nil)
+
+// Email implements the [auth.DeliveryStrategy] interface and signs users in without a password,
+// with a one time code or link sent to their email. Its methods expect a [OneTimeInput].
+type Email struct {
+	challenges oneTime
+	publisher  email.Publisher
+	linkURL    *url.URL
+}
+
+// NewEmail creates a new [Email] strategy for authentication. Codes are hashed and links are signed
+// with key, which must be [OneTimeKeySize] bytes. Links point to linkURL with the token as query parameter,
+// without a linkURL only codes are delivered.
+func NewEmail(db *sql.DB, publisher email.Publisher, key []byte, linkURL string) (*Email, error) {
+	challenges, err := newOneTime(db, gen.Strategy_Email, key)
+	if err != nil {
+		return nil, err
+	}
+	x := &Email{challenges: challenges, publisher: publisher}
+	if linkURL != "" {
+		if x.linkURL, err = url.Parse(linkURL); err != nil {
+			return nil, fmt.Errorf("strategy: email, parsing link url, %w", err)
+		}
+	}
+	return x, nil
+}
+
+func (x *Email) ConfiguredStrategy() gen.Strategy {
+	return gen.Strategy_Email
+}
+
+// ParseInput returns a [OneTimeInput] from the one time input of the request.
+func (x *Email) ParseInput(_ context.Context, req *gen.Input) (auth.Input, error) {
+	return x.challenges.parseInput(req)
+}
+
+// Deliver emails a one time code or link to address.
+// Returns [auth.ErrInvalidAddress] if address is not an email,
+// [auth.ErrUnsupportedDeliveryMethod] for links without a link URL and [auth.ErrTooManyDeliveries]
+// if address was sent [OneTimeDeliveries] codes or links within [OneTimeDeliveryWindow].
+func (x *Email) Deliver(ctx context.Context, address string, method gen.DeliveryMethod) (auth.Challenge, error) {
+	ctx, span := tracer.Start(ctx, "Deliver", trace.WithAttributes(
+		attribute.String("email", address),
+		attribute.String("method", method.String()),
+	))
+	defer span.End()
+
+	if err := validation.Email(address); err != nil {
+		return auth.Challenge{}, fmt.Errorf("%w, %w", auth.ErrInvalidAddress, err)
+	}
+	if method != gen.DeliveryMethod_Code && (method != gen.DeliveryMethod_Link || x.linkURL == nil) {
+		return auth.Challenge{}, fmt.Errorf("%w: %s", auth.ErrUnsupportedDeliveryMethod, method)
+	}
+
+	challenge, secret, err := x.challenges.challenge(ctx, address, method)
+	if err != nil {
+		return auth.Challenge{}, err
+	}
+
+	expires := fmt.Sprintf("It expires in %d minutes.", int(OneTimeChallengeDuration/time.Minute))
+	mail := email.Email{
+		To:      address,
+		From:    email.TestFrom,
+		Subject: "Your sign in code",
+		Body:    fmt.Sprintf("Your sign in code is %s. %s", string(secret), expires),
+	}
+	if method == gen.DeliveryMethod_Link {
+		link := *x.linkURL
+		query := link.Query()
+		query.Set("token", string(secret))
+		link.RawQuery = query.Encode()
+		mail.Subject = "Your sign in link"
+		mail.Body = fmt.Sprintf("Sign in by opening %s. %s", link.String(), expires)
+	}
+	if err = email.Ingest(ctx, x.publisher, mail); err != nil {
+		return auth.Challenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// Register answers a delivered challenge and inserts a new [emailuser.Entry] for its email.
+// Returns [database.DuplicateEntryError] if the email is registered, the challenge is then not used up,
+// and [auth.ErrInvalidCredentials] if the challenge could not be answered.
+func (x *Email) Register(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := oneTimeInput(in, gen.Strategy_Email)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Register", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	return x.challenges.verify(ctx, input, func(tx *sql.Tx, address string) (uuid.UUID, error) {
+		id := uuid.New()
+		if err := emailuser.Insert(ctx, tx, emailuser.InsertParams{
+			ID:        id,
+			Email:     address,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return uuid.Nil, err
+		}
+		return id, nil
+	})
+}
+
+// Authenticate answers a delivered challenge and returns the [emailuser.Entry] of its email.
+// An email signs up on its first authentication.
+// Returns [auth.ErrInvalidCredentials] if the challenge could not be answered.
+func (x *Email) Authenticate(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := oneTimeInput(in, gen.Strategy_Email)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Authenticate", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	return x.challenges.verify(ctx, input, func(tx *sql.Tx, address string) (uuid.UUID, error) {
+		entry, err := emailuser.ReadByEmail(ctx, tx, address)
+		if err == nil {
+			return entry.ID, nil
+		}
+		if !errors.As(err, &database.NotFoundError{}) {
+			return uuid.Nil, err
+		}
+
+		id := uuid.New()
+		if err = emailuser.Insert(ctx, tx, emailuser.InsertParams{
+			ID:        id,
+			Email:     address,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return uuid.Nil, err
+		}
+		return id, nil
+	})
+}
@@ -0,0 +1,296 @@
+package strategy
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/onetimechallenge"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var _ auth.Input = OneTimeInput{}
+
+const (
+	// OneTimeKeySize is the size of the key one time codes are hashed and links are signed with.
+	OneTimeKeySize = 32
+	// OneTimeChallengeDuration is how long a delivered code or link can be used.
+	OneTimeChallengeDuration = 15 * time.Minute
+	// OneTimeChallengeAttempts is how many wrong codes a challenge accepts.
+	OneTimeChallengeAttempts = 5
+	// OneTimeDeliveries is how many codes or links are delivered to an address within [OneTimeDeliveryWindow].
+	OneTimeDeliveries = 3
+	// OneTimeDeliveryWindow is the window [OneTimeDeliveries] are counted in.
+	OneTimeDeliveryWindow = 15 * time.Minute
+
+	oneTimeCodeDigits = 6
+	// oneTimeLinkSize is the size of a decoded link token, the challenge ID followed by its signature.
+	oneTimeLinkSize = 16 + sha256.Size
+)
+
+type (
+	// OneTimeInput is the validated input for strategies that deliver a one time code or link.
+	// It is created with [NewOneTimeInput()].
+	OneTimeInput struct {
+		strategy    gen.Strategy
+		ChallengeID uuid.UUID
+		// Code is empty when the challenge is answered with the Token of a link.
+		Code  token.SafeString
+		Token token.SafeString
+	}
+
+	// oneTime hands out and verifies the challenges of a strategy that delivers one time codes and links.
+	// Codes are stored as a keyed hash, links carry their challenge ID signed with the same key.
+	oneTime struct {
+		db       *sql.DB
+		key      []byte
+		strategy gen.Strategy
+	}
+)
+
+// NewOneTimeInput validates the answer to a challenge of strategy s and returns a [OneTimeInput].
+// The challenge is answered either by its ID and the delivered code, or by the token of the delivered link.
+func NewOneTimeInput(s gen.Strategy, challengeID, code, linkToken string) (OneTimeInput, error) {
+	if linkToken != "" {
+		b, err := base64.RawURLEncoding.DecodeString(linkToken)
+		if err != nil || len(b) != oneTimeLinkSize {
+			return OneTimeInput{}, errors.New("strategy: one time, malformed link token")
+		}
+		id, _ := uuid.FromBytes(b[:16])
+		if challengeID != "" && challengeID != id.String() {
+			return OneTimeInput{}, errors.New("strategy: one time, link token is for another challenge")
+		}
+		return OneTimeInput{strategy: s, ChallengeID: id, Token: token.SafeString(linkToken)}, nil
+	}
+
+	id, err := uuid.Parse(challengeID)
+	if err != nil {
+		return OneTimeInput{}, fmt.Errorf("strategy: one time, invalid challenge id, %w", err)
+	}
+	if id == uuid.Nil {
+		return OneTimeInput{}, errors.New("strategy: one time, challenge id is empty")
+	}
+	if len(code) != oneTimeCodeDigits {
+		return OneTimeInput{}, fmt.Errorf("strategy: one time, code must be %d digits", oneTimeCodeDigits)
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return OneTimeInput{}, fmt.Errorf("strategy: one time, code must be %d digits", oneTimeCodeDigits)
+		}
+	}
+	return OneTimeInput{strategy: s, ChallengeID: id, Code: token.SafeString(code)}, nil
+}
+
+func (x OneTimeInput) Strategy() gen.Strategy {
+	return x.strategy
+}
+
+func (x OneTimeInput) TraceAttributes() []attribute.KeyValue {
+	method := onetimechallenge.MethodCode
+	if x.Token != "" {
+		method = onetimechallenge.MethodLink
+	}
+	return []attribute.KeyValue{
+		attribute.String("challenge_id", x.ChallengeID.String()),
+		attribute.String("method", method),
+	}
+}
+
+func newOneTime(db *sql.DB, s gen.Strategy, key []byte) (oneTime, error) {
+	if len(key) != OneTimeKeySize {
+		return oneTime{}, fmt.Errorf("strategy: %s one time key must be %d bytes, got %d", s, OneTimeKeySize, len(key))
+	}
+	return oneTime{db: db, key: key, strategy: s}, nil
+}
+
+// parseInput returns a [OneTimeInput] from the one time input of the request.
+func (x oneTime) parseInput(req *gen.Input) (auth.Input, error) {
+	in := req.GetOneTime()
+	if in == nil {
+		return nil, fmt.Errorf("strategy: %s input is missing", x.strategy)
+	}
+	return NewOneTimeInput(x.strategy, in.GetChallengeId(), in.GetCode(), in.GetToken())
+}
+
+// challenge stores a new challenge for address and returns it with the code or link token to deliver.
+// Returns [auth.ErrTooManyDeliveries] if address was delivered [OneTimeDeliveries] challenges
+// within [OneTimeDeliveryWindow], the limit keeps the few digits of a code from being guessed
+// across many challenges. Challenges of an address are counted and stored under a lock on it,
+// so concurrent requests can not exceed the limit.
+func (x oneTime) challenge(
+	ctx context.Context,
+	address string,
+	method gen.DeliveryMethod,
+) (auth.Challenge, token.SafeString, error) {
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return auth.Challenge{}, "", database.NewOperationFailedError(ctx, err)
+	}
+	if err = database.Lock(ctx, tx, onetimechallenge.Tablename+":"+x.strategy.String()+":"+address); err != nil {
+		return auth.Challenge{}, "", errors.Join(err, tx.Rollback())
+	}
+
+	now := time.Now()
+	delivered, err := onetimechallenge.CountSince(
+		ctx,
+		tx,
+		x.strategy.String(),
+		address,
+		now.Add(-OneTimeDeliveryWindow),
+	)
+	if err != nil {
+		return auth.Challenge{}, "", errors.Join(err, tx.Rollback())
+	}
+	if delivered >= OneTimeDeliveries {
+		return auth.Challenge{}, "", errors.Join(
+			fmt.Errorf("%w, %d challenges within %s", auth.ErrTooManyDeliveries, delivered, OneTimeDeliveryWindow),
+			tx.Rollback(),
+		)
+	}
+
+	params := onetimechallenge.InsertParams{
+		ID:        uuid.New(),
+		Strategy:  x.strategy.String(),
+		Address:   address,
+		ExpiresAt: now.Add(OneTimeChallengeDuration),
+		CreatedAt: now,
+	}
+
+	var secret token.SafeString
+	if method == gen.DeliveryMethod_Link {
+		params.Method = onetimechallenge.MethodLink
+		secret = token.SafeString(base64.RawURLEncoding.EncodeToString(append(params.ID[:], x.sign(params.ID)...)))
+	} else {
+		n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+		if err != nil {
+			return auth.Challenge{}, "", errors.Join(fmt.Errorf("strategy: reading random code, %w", err), tx.Rollback())
+		}
+		secret = token.SafeString(fmt.Sprintf("%0*d", oneTimeCodeDigits, n.Int64()))
+		hash := x.hashCode(params.ID, secret)
+		params.Method = onetimechallenge.MethodCode
+		params.CodeHash = &hash
+	}
+
+	if err = onetimechallenge.Insert(ctx, tx, params); err != nil {
+		return auth.Challenge{}, "", errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return auth.Challenge{}, "", database.NewOperationFailedError(ctx, err)
+	}
+
+	return auth.Challenge{ID: params.ID, ExpiresAt: params.ExpiresAt}, secret, nil
+}
+
+// verify answers a challenge with input and passes its address to complete, in the transaction that uses
+// up the challenge. The challenge is only used up if complete succeeds.
+// Returns [auth.ErrInvalidCredentials] if the challenge is unknown, expired, used or out of attempts,
+// or the code or link does not match. A wrong code counts against the attempts of the challenge.
+func (x oneTime) verify(
+	ctx context.Context,
+	input OneTimeInput,
+	complete func(tx *sql.Tx, address string) (uuid.UUID, error),
+) (auth.Result, error) {
+	// A link is checked before touching the database, it can only be forged with the key.
+	if input.Token != "" {
+		b, err := base64.RawURLEncoding.DecodeString(string(input.Token))
+		if err != nil || len(b) != oneTimeLinkSize || !hmac.Equal(b[16:], x.sign(input.ChallengeID)) {
+			return auth.Result{}, fmt.Errorf("%w, invalid link signature", auth.ErrInvalidCredentials)
+		}
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return auth.Result{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	now := time.Now()
+	entry, err := onetimechallenge.ReadForUpdate(ctx, tx, input.ChallengeID)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			err = fmt.Errorf("%w, unknown challenge", auth.ErrInvalidCredentials)
+		}
+		return auth.Result{}, errors.Join(err, tx.Rollback())
+	}
+	if entry.Strategy != x.strategy.String() ||
+		entry.UsedAt != nil ||
+		now.After(entry.ExpiresAt) ||
+		entry.Attempts >= OneTimeChallengeAttempts {
+		return auth.Result{}, errors.Join(
+			fmt.Errorf("%w, expired or used challenge", auth.ErrInvalidCredentials),
+			tx.Rollback(),
+		)
+	}
+
+	if input.Token != "" {
+		if entry.Method != onetimechallenge.MethodLink {
+			return auth.Result{}, errors.Join(
+				fmt.Errorf("%w, challenge was not delivered as a link", auth.ErrInvalidCredentials),
+				tx.Rollback(),
+			)
+		}
+	} else if entry.Method != onetimechallenge.MethodCode ||
+		entry.CodeHash == nil ||
+		!hmac.Equal([]byte(*entry.CodeHash), []byte(x.hashCode(entry.ID, input.Code))) {
+		// A wrong code still has to commit the attempt.
+		err = fmt.Errorf("%w, wrong code", auth.ErrInvalidCredentials)
+		if aErr := onetimechallenge.RecordAttempt(ctx, tx, entry.ID); aErr != nil {
+			return auth.Result{}, errors.Join(err, aErr, tx.Rollback())
+		}
+		if cErr := tx.Commit(); cErr != nil {
+			return auth.Result{}, errors.Join(err, database.NewOperationFailedError(ctx, cErr))
+		}
+		return auth.Result{}, err
+	}
+
+	if err = onetimechallenge.MarkUsed(ctx, tx, entry.ID, now); err != nil {
+		return auth.Result{}, errors.Join(err, tx.Rollback())
+	}
+	userID, err := complete(tx, entry.Address)
+	if err != nil {
+		return auth.Result{}, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return auth.Result{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	return auth.Result{UserID: userID}, nil
+}
+
+// sign returns the signature a link carries for the challenge id.
+func (x oneTime) sign(id uuid.UUID) []byte {
+	mac := hmac.New(sha256.New, x.key)
+	mac.Write([]byte("link:"))
+	mac.Write(id[:])
+	return mac.Sum(nil)
+}
+
+// hashCode returns the hex encoded keyed hash of the code of the challenge id.
+// Codes are short, the key keeps a leaked hash from being brute forced offline.
+func (x oneTime) hashCode(id uuid.UUID, code token.SafeString) string {
+	mac := hmac.New(sha256.New, x.key)
+	mac.Write([]byte("code:"))
+	mac.Write(id[:])
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func oneTimeInput(in auth.Input, s gen.Strategy) (OneTimeInput, error) {
+	input, ok := in.(OneTimeInput)
+	if !ok || input.strategy != s {
+		return OneTimeInput{}, fmt.Errorf("strategy: %s, unsupported input %T", s, in)
+	}
+	return input, nil
+}
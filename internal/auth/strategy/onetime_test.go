@@ -0,0 +1,173 @@
+package strategy
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+)
+
+var testOneTimeKey = []byte(strings.Repeat("k", OneTimeKeySize))
+
+func TestNewOneTimeInput(t *testing.T) {
+	id := uuid.New()
+
+	t.Run("code", func(t *testing.T) {
+		in, err := NewOneTimeInput(gen.Strategy_Email, id.String(), "012345", "")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if in.ChallengeID != id || in.Code != "012345" || in.Strategy() != gen.Strategy_Email {
+			t.Errorf("unexpected input %+v", in)
+		}
+	})
+
+	t.Run("link carries its challenge", func(t *testing.T) {
+		x, err := newOneTime(nil, gen.Strategy_Email, testOneTimeKey)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		link := base64.RawURLEncoding.EncodeToString(append(id[:], x.sign(id)...))
+
+		in, err := NewOneTimeInput(gen.Strategy_Email, "", "", link)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if in.ChallengeID != id || in.Code != "" {
+			t.Errorf("unexpected input %+v", in)
+		}
+
+		if _, err = NewOneTimeInput(gen.Strategy_Email, uuid.NewString(), "", link); err == nil {
+			t.Error("expected error for a link of another challenge")
+		}
+	})
+
+	for name, tc := range map[string]struct {
+		challengeID string
+		code        string
+		link        string
+	}{
+		"invalid challenge ID": {challengeID: "challenge", code: "123456"},
+		"empty challenge ID":   {challengeID: uuid.Nil.String(), code: "123456"},
+		"short code":           {challengeID: id.String(), code: "12345"},
+		"code with letters":    {challengeID: id.String(), code: "12345a"},
+		"malformed link":       {link: "link"},
+	} {
+		t.Run(name+" returns error", func(t *testing.T) {
+			if _, err := NewOneTimeInput(gen.Strategy_Email, tc.challengeID, tc.code, tc.link); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestOneTimeHashCode(t *testing.T) {
+	x, err := newOneTime(nil, gen.Strategy_Email, testOneTimeKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	other, err := newOneTime(nil, gen.Strategy_Email, []byte(strings.Repeat("o", OneTimeKeySize)))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	id := uuid.New()
+
+	if x.hashCode(id, "123456") != x.hashCode(id, "123456") {
+		t.Error("expected the same hash for the same code")
+	}
+	if x.hashCode(id, "123456") == x.hashCode(uuid.New(), "123456") {
+		t.Error("expected the hash to depend on the challenge")
+	}
+	if x.hashCode(id, "123456") == other.hashCode(id, "123456") {
+		t.Error("expected the hash to depend on the key")
+	}
+}
+
+func TestOneTimeForgedLink(t *testing.T) {
+	x, err := newOneTime(nil, gen.Strategy_Email, testOneTimeKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+	id := uuid.New()
+	forged := base64.RawURLEncoding.EncodeToString(append(id[:], make([]byte, 32)...))
+	in, err := NewOneTimeInput(gen.Strategy_Email, "", "", forged)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	// The signature is checked before the database is used.
+	if _, err = x.verify(context.Background(), in, nil); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials, got %v", err)
+	}
+}
+
+func TestNewEmail(t *testing.T) {
+	if _, err := NewEmail(nil, nil, []byte("short"), ""); err == nil {
+		t.Error("expected error for a short key")
+	}
+
+	s, err := NewEmail(nil, nil, testOneTimeKey, "")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := s.Deliver(context.Background(), "email", gen.DeliveryMethod_Code)
+		if !errors.Is(err, auth.ErrInvalidAddress) {
+			t.Errorf("expected ErrInvalidAddress, got %v", err)
+		}
+	})
+
+	t.Run("links need a link url", func(t *testing.T) {
+		_, err := s.Deliver(context.Background(), "email@email.com", gen.DeliveryMethod_Link)
+		if !errors.Is(err, auth.ErrUnsupportedDeliveryMethod) {
+			t.Errorf("expected ErrUnsupportedDeliveryMethod, got %v", err)
+		}
+	})
+
+	t.Run("missing input returns error", func(t *testing.T) {
+		if _, err := s.ParseInput(context.Background(), &gen.Input{Strategy: gen.Strategy_Email}); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestNewSMS(t *testing.T) {
+	if _, err := NewSMS(nil, nil, []byte("short")); err == nil {
+		t.Error("expected error for a short key")
+	}
+
+	s, err := NewSMS(nil, nil, testOneTimeKey)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := s.Deliver(context.Background(), "0701234567", gen.DeliveryMethod_Code)
+		if !errors.Is(err, auth.ErrInvalidAddress) {
+			t.Errorf("expected ErrInvalidAddress, got %v", err)
+		}
+	})
+
+	t.Run("links are not supported", func(t *testing.T) {
+		_, err := s.Deliver(context.Background(), "+46701234567", gen.DeliveryMethod_Link)
+		if !errors.Is(err, auth.ErrUnsupportedDeliveryMethod) {
+			t.Errorf("expected ErrUnsupportedDeliveryMethod, got %v", err)
+		}
+	})
+
+	t.Run("input of another strategy returns error", func(t *testing.T) {
+		in, err := NewOneTimeInput(gen.Strategy_Email, uuid.NewString(), "123456", "")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if _, err := s.Authenticate(context.Background(), in); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
@@ -0,0 +1,151 @@
+package strategy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/personalnumber"
+	"github.com/Salam4nder/identity/internal/verification"
+	"github.com/Salam4nder/identity/pkg/validation"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ auth.Strategy = (*PersonalNumber)(nil)
+	_ auth.Input    = PersonalNumberInput{}
+)
+
+type (
+	// PersonalNumber implements the [auth.Strategy] interface and has everything
+	// to be able to [Register()] and [Authenticate()] with a personal number.
+	// The person behind the number is verified by a [verification.Provider].
+	PersonalNumber struct {
+		db       *sql.DB
+		provider verification.Provider
+	}
+
+	// PersonalNumberInput is the validated input for the personal number strategy.
+	// It is created with [NewPersonalNumberInput()].
+	PersonalNumberInput struct {
+		Number uint64
+	}
+)
+
+// NewPersonalNumberInput validates the checksum of the given number
+// and returns a [PersonalNumberInput] ready to be passed to [PersonalNumber].
+func NewPersonalNumberInput(number uint64) (PersonalNumberInput, error) {
+	if err := validation.PersonalNumber(number); err != nil {
+		return PersonalNumberInput{}, fmt.Errorf("strategy: personal number, %w", err)
+	}
+	return PersonalNumberInput{Number: number}, nil
+}
+
+func (x PersonalNumberInput) Strategy() gen.Strategy {
+	return gen.Strategy_PersonalNumber
+}
+
+func (x PersonalNumberInput) TraceAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("number", verification.Mask(x.Number)),
+	}
+}
+
+// NewPersonalNumber creates a new [PersonalNumber] strategy for authentication.
+// Its methods expect a [PersonalNumberInput], created with [NewPersonalNumberInput()].
+func NewPersonalNumber(db *sql.DB, provider verification.Provider) *PersonalNumber {
+	return &PersonalNumber{db: db, provider: provider}
+}
+
+func (x *PersonalNumber) ConfiguredStrategy() gen.Strategy {
+	return gen.Strategy_PersonalNumber
+}
+
+// ParseInput returns a [PersonalNumberInput] from the numbers of the request.
+func (x *PersonalNumber) ParseInput(_ context.Context, req *gen.Input) (auth.Input, error) {
+	n := req.GetNumbers()
+	if n == nil {
+		return nil, errors.New("strategy: personal number input is missing")
+	}
+	return NewPersonalNumberInput(n.GetNumbers())
+}
+
+// Register will verify the person behind the number with the [verification.Provider]
+// and insert a new [personalnumber.Entry] into the personal numbers table.
+// Returns [auth.ErrInvalidCredentials] if the person could not be verified.
+func (x *PersonalNumber) Register(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := personalNumberInput(in)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Register", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	if err = x.verify(ctx, input.Number); err != nil {
+		return auth.Result{}, err
+	}
+
+	id := uuid.New()
+	if err = personalnumber.Insert(ctx, x.db, personalnumber.InsertParams{
+		ID:        id,
+		Number:    input.Number,
+		CreatedAt: time.Now(),
+	}); err != nil {
+		return auth.Result{}, err
+	}
+
+	return auth.Result{UserID: id}, nil
+}
+
+// Authenticate will verify the person behind the number with the [verification.Provider]
+// and read the [personalnumber.Entry] of the number.
+// Returns [auth.ErrInvalidCredentials] if the person could not be verified or is not registered.
+func (x *PersonalNumber) Authenticate(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := personalNumberInput(in)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Authenticate", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	if err = x.verify(ctx, input.Number); err != nil {
+		return auth.Result{}, err
+	}
+
+	entry, err := personalnumber.ReadByNumber(ctx, x.db, input.Number)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return auth.Result{}, auth.ErrInvalidCredentials
+		}
+		return auth.Result{}, err
+	}
+
+	return auth.Result{UserID: entry.ID}, nil
+}
+
+func (x *PersonalNumber) verify(ctx context.Context, number uint64) error {
+	if err := x.provider.VerifyPersonalNumber(ctx, number); err != nil {
+		if errors.Is(err, verification.ErrNotVerified) {
+			return fmt.Errorf("%w, %w", auth.ErrInvalidCredentials, err)
+		}
+		return fmt.Errorf("strategy: personal number, verifying, %w", err)
+	}
+	return nil
+}
+
+func personalNumberInput(in auth.Input) (PersonalNumberInput, error) {
+	input, ok := in.(PersonalNumberInput)
+	if !ok {
+		return PersonalNumberInput{}, fmt.Errorf("strategy: personal number, unsupported input %T", in)
+	}
+	return input, nil
+}
@@ -0,0 +1,62 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/verification"
+	"github.com/Salam4nder/identity/proto/gen"
+)
+
+const testNumber uint64 = 198112189876
+
+func TestPersonalNumberParseInput(t *testing.T) {
+	s := NewPersonalNumber(nil, verification.NewFakeProvider())
+
+	t.Run("OK", func(t *testing.T) {
+		in, err := s.ParseInput(context.Background(), &gen.Input{
+			Strategy: gen.Strategy_PersonalNumber,
+			Data:     &gen.Input_Numbers{Numbers: &gen.PersonalNumberInput{Numbers: testNumber}},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if in.(PersonalNumberInput).Number != testNumber {
+			t.Errorf("expected %d, got %d", testNumber, in.(PersonalNumberInput).Number)
+		}
+	})
+
+	t.Run("invalid checksum returns error", func(t *testing.T) {
+		_, err := s.ParseInput(context.Background(), &gen.Input{
+			Strategy: gen.Strategy_PersonalNumber,
+			Data:     &gen.Input_Numbers{Numbers: &gen.PersonalNumberInput{Numbers: testNumber + 1}},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("missing input returns error", func(t *testing.T) {
+		_, err := s.ParseInput(context.Background(), &gen.Input{Strategy: gen.Strategy_PersonalNumber})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestPersonalNumberNotVerified(t *testing.T) {
+	s := NewPersonalNumber(nil, verification.NewFakeProvider(testNumber))
+	in, err := NewPersonalNumberInput(testNumber)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	if _, err = s.Register(context.Background(), in); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials on register, got %v", err)
+	}
+	if _, err = s.Authenticate(context.Background(), in); !errors.Is(err, auth.ErrInvalidCredentials) {
+		t.Errorf("expected ErrInvalidCredentials on authenticate, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package strategy
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/internal/verification"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// Dependencies are shared by every strategy in a registry.
+type Dependencies struct {
+	DB        *sql.DB
+	Publisher email.Publisher
+	// EmailVerification sends and checks the verification emails of [Credentials], which requires it.
+	EmailVerification *auth.EmailVerification
+	// Verifier verifies personal numbers, it is required by [PersonalNumber].
+	Verifier verification.Provider
+	// RelyingParty verifies WebAuthn ceremonies, it is required by [WebAuthn].
+	RelyingParty *webauthn.WebAuthn
+	// OneTimeKey hashes one time codes and signs links, it is required by [Email] and [SMS].
+	OneTimeKey []byte
+	// LinkURL is where [Email] links point to, without it only codes are emailed.
+	LinkURL string
+}
+
+// NewRegistry creates each of the given strategies and returns them in an [auth.Registry].
+// Returns [auth.ErrUnsupportedStrategy] if a strategy has no implementation.
+func NewRegistry(deps Dependencies, strategies ...gen.Strategy) (*auth.Registry, error) {
+	ss := make([]auth.Strategy, 0, len(strategies))
+	for _, s := range strategies {
+		switch s {
+		case gen.Strategy_Credentials:
+			if deps.EmailVerification == nil {
+				return nil, fmt.Errorf("strategy: %s requires email verification", s)
+			}
+			ss = append(ss, NewCredentials(deps.DB, deps.EmailVerification))
+		case gen.Strategy_PersonalNumber:
+			if deps.Verifier == nil {
+				return nil, fmt.Errorf("strategy: %s requires a verification provider", s)
+			}
+			ss = append(ss, NewPersonalNumber(deps.DB, deps.Verifier))
+		case gen.Strategy_WebAuthn:
+			if deps.RelyingParty == nil {
+				return nil, fmt.Errorf("strategy: %s requires a relying party", s)
+			}
+			ss = append(ss, NewWebAuthn(deps.DB, deps.RelyingParty))
+		case gen.Strategy_Email:
+			e, err := NewEmail(deps.DB, deps.Publisher, deps.OneTimeKey, deps.LinkURL)
+			if err != nil {
+				return nil, err
+			}
+			ss = append(ss, e)
+		case gen.Strategy_SMS:
+			x, err := NewSMS(deps.DB, deps.Publisher, deps.OneTimeKey)
+			if err != nil {
+				return nil, err
+			}
+			ss = append(ss, x)
+		default:
+			return nil, fmt.Errorf("strategy: %w: %s", auth.ErrUnsupportedStrategy, s)
+		}
+	}
+	return auth.NewRegistry(ss...)
+}
@@ -0,0 +1,142 @@
+package strategy
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/phoneuser"
+	"github.com/Salam4nder/identity/internal/sms"
+	"github.com/Salam4nder/identity/pkg/validation"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ auth.DeliveryStrategy = (*SMS)(nil)
+
+// SMS implements the [auth.DeliveryStrategy] interface and signs users in without a password,
+// with a one time code sent to their phone number. Its methods expect a [OneTimeInput].
+type SMS struct {
+	challenges oneTime
+	publisher  sms.Publisher
+}
+
+// NewSMS creates a new [SMS] strategy for authentication.
+// Codes are hashed with key, which must be [OneTimeKeySize] bytes.
+func NewSMS(db *sql.DB, publisher sms.Publisher, key []byte) (*SMS, error) {
+	challenges, err := newOneTime(db, gen.Strategy_SMS, key)
+	if err != nil {
+		return nil, err
+	}
+	return &SMS{challenges: challenges, publisher: publisher}, nil
+}
+
+func (x *SMS) ConfiguredStrategy() gen.Strategy {
+	return gen.Strategy_SMS
+}
+
+// ParseInput returns a [OneTimeInput] from the one time input of the request.
+func (x *SMS) ParseInput(_ context.Context, req *gen.Input) (auth.Input, error) {
+	return x.challenges.parseInput(req)
+}
+
+// Deliver texts a one time code to address, a phone number in E.164 format.
+// Returns [auth.ErrInvalidAddress] if address is not a phone number,
+// [auth.ErrUnsupportedDeliveryMethod] for anything but codes and [auth.ErrTooManyDeliveries]
+// if address was sent [OneTimeDeliveries] codes within [OneTimeDeliveryWindow].
+func (x *SMS) Deliver(ctx context.Context, address string, method gen.DeliveryMethod) (auth.Challenge, error) {
+	ctx, span := tracer.Start(ctx, "Deliver", trace.WithAttributes(
+		attribute.String("phone", address),
+		attribute.String("method", method.String()),
+	))
+	defer span.End()
+
+	if err := validation.Phone(address); err != nil {
+		return auth.Challenge{}, fmt.Errorf("%w, %w", auth.ErrInvalidAddress, err)
+	}
+	if method != gen.DeliveryMethod_Code {
+		return auth.Challenge{}, fmt.Errorf("%w: %s", auth.ErrUnsupportedDeliveryMethod, method)
+	}
+
+	challenge, secret, err := x.challenges.challenge(ctx, address, method)
+	if err != nil {
+		return auth.Challenge{}, err
+	}
+
+	if err = sms.Ingest(ctx, x.publisher, sms.SMS{
+		To: address,
+		Body: fmt.Sprintf(
+			"Your sign in code is %s. It expires in %d minutes.",
+			string(secret),
+			int(OneTimeChallengeDuration/time.Minute),
+		),
+	}); err != nil {
+		return auth.Challenge{}, err
+	}
+
+	return challenge, nil
+}
+
+// Register answers a delivered challenge and inserts a new [phoneuser.Entry] for its phone number.
+// Returns [database.DuplicateEntryError] if the phone number is registered, the challenge is then not used up,
+// and [auth.ErrInvalidCredentials] if the challenge could not be answered.
+func (x *SMS) Register(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := oneTimeInput(in, gen.Strategy_SMS)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Register", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	return x.challenges.verify(ctx, input, func(tx *sql.Tx, address string) (uuid.UUID, error) {
+		id := uuid.New()
+		if err := phoneuser.Insert(ctx, tx, phoneuser.InsertParams{
+			ID:        id,
+			Phone:     address,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return uuid.Nil, err
+		}
+		return id, nil
+	})
+}
+
+// Authenticate answers a delivered challenge and returns the [phoneuser.Entry] of its phone number.
+// A phone number signs up on its first authentication.
+// Returns [auth.ErrInvalidCredentials] if the challenge could not be answered.
+func (x *SMS) Authenticate(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := oneTimeInput(in, gen.Strategy_SMS)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Authenticate", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	return x.challenges.verify(ctx, input, func(tx *sql.Tx, address string) (uuid.UUID, error) {
+		entry, err := phoneuser.ReadByPhone(ctx, tx, address)
+		if err == nil {
+			return entry.ID, nil
+		}
+		if !errors.As(err, &database.NotFoundError{}) {
+			return uuid.Nil, err
+		}
+
+		id := uuid.New()
+		if err = phoneuser.Insert(ctx, tx, phoneuser.InsertParams{
+			ID:        id,
+			Phone:     address,
+			CreatedAt: time.Now(),
+		}); err != nil {
+			return uuid.Nil, err
+		}
+		return id, nil
+	})
+}
@@ -0,0 +1,439 @@
+package strategy
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/passkey"
+	"github.com/Salam4nder/identity/internal/database/passkeyuser"
+	"github.com/Salam4nder/identity/internal/database/webauthnchallenge"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ auth.ChallengeStrategy = (*WebAuthn)(nil)
+	_ auth.Input             = WebAuthnInput{}
+	_ webauthn.User          = (*passkeyUser)(nil)
+)
+
+// WebAuthnChallengeDuration is how long a WebAuthn challenge can be answered.
+const WebAuthnChallengeDuration = 5 * time.Minute
+
+// maxNameLength is the longest name a passkey user can register under.
+const maxNameLength = 255
+
+type (
+	// WebAuthn implements the [auth.ChallengeStrategy] interface and has everything
+	// to be able to [Register()] and [Authenticate()] with a passkey.
+	// Every ceremony begins with a single use challenge, which is answered by the [WebAuthnInput].
+	WebAuthn struct {
+		db *sql.DB
+		rp *webauthn.WebAuthn
+	}
+
+	// WebAuthnInput is the validated input for the WebAuthn strategy.
+	// It is created with [NewWebAuthnInput()].
+	WebAuthnInput struct {
+		ChallengeID uuid.UUID
+		// Credential is the JSON encoded PublicKeyCredential returned by the authenticator.
+		Credential []byte
+	}
+
+	// passkeyUser is a [passkeyuser.Entry] and its passkeys as seen by the relying party.
+	passkeyUser struct {
+		entry       passkeyuser.Entry
+		credentials []webauthn.Credential
+	}
+)
+
+// NewWebAuthnInput validates the given challenge ID and credential
+// and returns a [WebAuthnInput] ready to be passed to [WebAuthn].
+func NewWebAuthnInput(challengeID string, credential []byte) (WebAuthnInput, error) {
+	id, err := uuid.Parse(challengeID)
+	if err != nil {
+		return WebAuthnInput{}, fmt.Errorf("strategy: webauthn, invalid challenge id, %w", err)
+	}
+	if len(credential) == 0 {
+		return WebAuthnInput{}, errors.New("strategy: webauthn, credential is empty")
+	}
+	return WebAuthnInput{ChallengeID: id, Credential: credential}, nil
+}
+
+func (x WebAuthnInput) Strategy() gen.Strategy {
+	return gen.Strategy_WebAuthn
+}
+
+func (x WebAuthnInput) TraceAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("challenge_id", x.ChallengeID.String()),
+	}
+}
+
+// NewWebAuthn creates a new [WebAuthn] strategy for authentication, verifying ceremonies as the relying party rp.
+// Its methods expect a [WebAuthnInput], created with [NewWebAuthnInput()].
+func NewWebAuthn(db *sql.DB, rp *webauthn.WebAuthn) *WebAuthn {
+	return &WebAuthn{db: db, rp: rp}
+}
+
+func (x *WebAuthn) ConfiguredStrategy() gen.Strategy {
+	return gen.Strategy_WebAuthn
+}
+
+// ParseInput returns a [WebAuthnInput] from the WebAuthn input of the request.
+func (x *WebAuthn) ParseInput(_ context.Context, req *gen.Input) (auth.Input, error) {
+	in := req.GetWebAuthn()
+	if in == nil {
+		return nil, errors.New("strategy: webauthn input is missing")
+	}
+	return NewWebAuthnInput(in.GetChallengeId(), in.GetCredential())
+}
+
+// BeginRegistration hands out a challenge to register a passkey for a new user under name.
+// displayName defaults to name. The options of the challenge are the JSON encoded
+// options for navigator.credentials.create.
+// Returns [auth.ErrInvalidName] if name or displayName is too long
+// and [database.DuplicateEntryError] if name is taken.
+func (x *WebAuthn) BeginRegistration(ctx context.Context, name, displayName string) (auth.Challenge, error) {
+	ctx, span := tracer.Start(ctx, "BeginRegistration")
+	defer span.End()
+
+	if name == "" || utf8.RuneCountInString(name) > maxNameLength {
+		return auth.Challenge{}, fmt.Errorf("%w, must be 1 to %d characters", auth.ErrInvalidName, maxNameLength)
+	}
+	if utf8.RuneCountInString(displayName) > maxNameLength {
+		return auth.Challenge{}, fmt.Errorf("%w, display name must be at most %d characters", auth.ErrInvalidName, maxNameLength)
+	}
+	if displayName == "" {
+		displayName = name
+	}
+
+	_, err := passkeyuser.ReadByName(ctx, x.db, name)
+	if err == nil {
+		return auth.Challenge{}, database.NewDuplicateEntryError(ctx, errors.New("strategy: webauthn, name is taken"), "passkey user")
+	}
+	if !errors.As(err, &database.NotFoundError{}) {
+		return auth.Challenge{}, err
+	}
+
+	user := &passkeyUser{entry: passkeyuser.Entry{ID: uuid.New(), Name: name, DisplayName: displayName}}
+	creation, session, err := x.rp.BeginRegistration(
+		user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+	)
+	if err != nil {
+		return auth.Challenge{}, fmt.Errorf("strategy: webauthn, beginning registration, %w", err)
+	}
+	creation.Response.Timeout = int(WebAuthnChallengeDuration.Milliseconds())
+
+	return x.challenge(ctx, creation, session, webauthnchallenge.InsertParams{
+		Ceremony:    webauthnchallenge.CeremonyRegistration,
+		UserID:      &user.entry.ID,
+		Name:        name,
+		DisplayName: displayName,
+	})
+}
+
+// Register finishes a registration begun with [WebAuthn.BeginRegistration].
+// It verifies the attestation of the new passkey against the challenge and inserts
+// a new [passkeyuser.Entry] with the [passkey.Entry] of the passkey.
+// Returns [auth.ErrInvalidCredentials] if the challenge is unknown, expired or used,
+// or the attestation could not be verified.
+func (x *WebAuthn) Register(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := webAuthnInput(in)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Register", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	now := time.Now()
+	entry, session, err := x.consume(ctx, input.ChallengeID, webauthnchallenge.CeremonyRegistration, now)
+	if err != nil {
+		return auth.Result{}, err
+	}
+	if entry.UserID == nil {
+		return auth.Result{}, fmt.Errorf("strategy: webauthn, registration challenge %s has no user", entry.ID)
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBody(bytes.NewReader(input.Credential))
+	if err != nil {
+		return auth.Result{}, fmt.Errorf("%w, %w", auth.ErrInvalidCredentials, err)
+	}
+	user := &passkeyUser{entry: passkeyuser.Entry{
+		ID:          *entry.UserID,
+		Name:        entry.Name,
+		DisplayName: entry.DisplayName,
+		CreatedAt:   now,
+	}}
+	credential, err := x.rp.CreateCredential(user, session, parsed)
+	if err != nil {
+		return auth.Result{}, fmt.Errorf("%w, %w", auth.ErrInvalidCredentials, err)
+	}
+
+	transports := make([]string, 0, len(credential.Transport))
+	for _, t := range credential.Transport {
+		transports = append(transports, string(t))
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return auth.Result{}, database.NewOperationFailedError(ctx, err)
+	}
+	if err = passkeyuser.Insert(ctx, tx, passkeyuser.InsertParams{
+		ID:          user.entry.ID,
+		Name:        user.entry.Name,
+		DisplayName: user.entry.DisplayName,
+		CreatedAt:   now,
+	}); err != nil {
+		return auth.Result{}, errors.Join(err, tx.Rollback())
+	}
+	if err = passkey.Insert(ctx, tx, passkey.InsertParams{
+		ID:              credential.ID,
+		UserID:          user.entry.ID,
+		PublicKey:       credential.PublicKey,
+		SignCount:       credential.Authenticator.SignCount,
+		AAGUID:          credential.Authenticator.AAGUID,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		CreatedAt:       now,
+	}); err != nil {
+		return auth.Result{}, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return auth.Result{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	return auth.Result{UserID: user.entry.ID}, nil
+}
+
+// BeginAuthentication hands out a challenge to authenticate with a passkey of the user registered under name.
+// Without a name the authenticator offers the discoverable passkeys it has for the relying party.
+// The options of the challenge are the JSON encoded options for navigator.credentials.get.
+// Returns [auth.ErrInvalidCredentials] if no user is registered under name.
+func (x *WebAuthn) BeginAuthentication(ctx context.Context, name string) (auth.Challenge, error) {
+	ctx, span := tracer.Start(ctx, "BeginAuthentication")
+	defer span.End()
+
+	if name == "" {
+		assertion, session, err := x.rp.BeginDiscoverableLogin()
+		if err != nil {
+			return auth.Challenge{}, fmt.Errorf("strategy: webauthn, beginning authentication, %w", err)
+		}
+		assertion.Response.Timeout = int(WebAuthnChallengeDuration.Milliseconds())
+		return x.challenge(ctx, assertion, session, webauthnchallenge.InsertParams{
+			Ceremony: webauthnchallenge.CeremonyAuthentication,
+		})
+	}
+
+	entry, err := passkeyuser.ReadByName(ctx, x.db, name)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return auth.Challenge{}, auth.ErrInvalidCredentials
+		}
+		return auth.Challenge{}, err
+	}
+	user, err := x.user(ctx, entry.ID)
+	if err != nil {
+		return auth.Challenge{}, err
+	}
+	assertion, session, err := x.rp.BeginLogin(user)
+	if err != nil {
+		return auth.Challenge{}, fmt.Errorf("strategy: webauthn, beginning authentication, %w", err)
+	}
+	assertion.Response.Timeout = int(WebAuthnChallengeDuration.Milliseconds())
+
+	return x.challenge(ctx, assertion, session, webauthnchallenge.InsertParams{
+		Ceremony: webauthnchallenge.CeremonyAuthentication,
+		UserID:   &entry.ID,
+		Name:     entry.Name,
+	})
+}
+
+// Authenticate finishes an authentication begun with [WebAuthn.BeginAuthentication].
+// It verifies the assertion of the passkey against the challenge and stores the new
+// signature counter of its authenticator.
+// Returns [auth.ErrInvalidCredentials] if the challenge is unknown, expired or used,
+// the assertion could not be verified or the signature counter did not grow,
+// which is a sign of a cloned authenticator.
+func (x *WebAuthn) Authenticate(ctx context.Context, in auth.Input) (auth.Result, error) {
+	input, err := webAuthnInput(in)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	ctx, span := tracer.Start(ctx, "Authenticate", trace.WithAttributes(input.TraceAttributes()...))
+	defer span.End()
+
+	now := time.Now()
+	entry, session, err := x.consume(ctx, input.ChallengeID, webauthnchallenge.CeremonyAuthentication, now)
+	if err != nil {
+		return auth.Result{}, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBody(bytes.NewReader(input.Credential))
+	if err != nil {
+		return auth.Result{}, fmt.Errorf("%w, %w", auth.ErrInvalidCredentials, err)
+	}
+
+	var (
+		user       *passkeyUser
+		credential *webauthn.Credential
+	)
+	if entry.UserID != nil {
+		if user, err = x.user(ctx, *entry.UserID); err != nil {
+			return auth.Result{}, err
+		}
+		credential, err = x.rp.ValidateLogin(user, session, parsed)
+	} else {
+		credential, err = x.rp.ValidateDiscoverableLogin(func(_, userHandle []byte) (webauthn.User, error) {
+			id, err := uuid.FromBytes(userHandle)
+			if err != nil {
+				return nil, err
+			}
+			user, err = x.user(ctx, id)
+			return user, err
+		}, session, parsed)
+	}
+	if err != nil {
+		return auth.Result{}, fmt.Errorf("%w, %w", auth.ErrInvalidCredentials, err)
+	}
+	if credential.Authenticator.CloneWarning {
+		return auth.Result{}, fmt.Errorf("%w, signature counter did not grow", auth.ErrInvalidCredentials)
+	}
+
+	if err = passkey.Use(ctx, x.db, credential.ID, credential.Authenticator.SignCount, now); err != nil {
+		if errors.As(err, &database.RowsAffectedError{}) {
+			return auth.Result{}, fmt.Errorf("%w, signature counter did not grow", auth.ErrInvalidCredentials)
+		}
+		return auth.Result{}, err
+	}
+
+	return auth.Result{UserID: user.entry.ID}, nil
+}
+
+// challenge stores the session of a ceremony and returns it as an [auth.Challenge] with the given options.
+func (x *WebAuthn) challenge(
+	ctx context.Context,
+	options any,
+	session *webauthn.SessionData,
+	params webauthnchallenge.InsertParams,
+) (auth.Challenge, error) {
+	o, err := json.Marshal(options)
+	if err != nil {
+		return auth.Challenge{}, fmt.Errorf("strategy: webauthn, encoding options, %w", err)
+	}
+	s, err := json.Marshal(session)
+	if err != nil {
+		return auth.Challenge{}, fmt.Errorf("strategy: webauthn, encoding session, %w", err)
+	}
+
+	now := time.Now()
+	params.ID = uuid.New()
+	params.SessionData = s
+	params.CreatedAt = now
+	params.ExpiresAt = now.Add(WebAuthnChallengeDuration)
+	if err = webauthnchallenge.Insert(ctx, x.db, params); err != nil {
+		return auth.Challenge{}, err
+	}
+
+	return auth.Challenge{ID: params.ID, Options: o, ExpiresAt: params.ExpiresAt}, nil
+}
+
+// consume uses up the challenge of a ceremony and returns it with its session.
+func (x *WebAuthn) consume(
+	ctx context.Context,
+	id uuid.UUID,
+	ceremony string,
+	now time.Time,
+) (*webauthnchallenge.Entry, webauthn.SessionData, error) {
+	entry, err := webauthnchallenge.Consume(ctx, x.db, id, ceremony, now)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return nil, webauthn.SessionData{}, fmt.Errorf("%w, unknown, expired or used challenge", auth.ErrInvalidCredentials)
+		}
+		return nil, webauthn.SessionData{}, err
+	}
+
+	var session webauthn.SessionData
+	if err = json.Unmarshal(entry.SessionData, &session); err != nil {
+		return nil, webauthn.SessionData{}, fmt.Errorf("strategy: webauthn, decoding session, %w", err)
+	}
+
+	return entry, session, nil
+}
+
+// user reads a passkey user and its passkeys.
+func (x *WebAuthn) user(ctx context.Context, id uuid.UUID) (*passkeyUser, error) {
+	entry, err := passkeyuser.Read(ctx, x.db, id)
+	if err != nil {
+		return nil, err
+	}
+	passkeys, err := passkey.ListByUser(ctx, x.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user := &passkeyUser{entry: *entry, credentials: make([]webauthn.Credential, 0, len(passkeys))}
+	for _, p := range passkeys {
+		var transports []protocol.AuthenticatorTransport
+		if p.Transports != "" {
+			for _, t := range strings.Split(p.Transports, ",") {
+				transports = append(transports, protocol.AuthenticatorTransport(t))
+			}
+		}
+		user.credentials = append(user.credentials, webauthn.Credential{
+			ID:              p.ID,
+			PublicKey:       p.PublicKey,
+			AttestationType: p.AttestationType,
+			Transport:       transports,
+			Authenticator:   webauthn.Authenticator{AAGUID: p.AAGUID, SignCount: p.SignCount},
+		})
+	}
+
+	return user, nil
+}
+
+// WebAuthnID returns the user handle, which is the ID of the user.
+func (x *passkeyUser) WebAuthnID() []byte {
+	return x.entry.ID[:]
+}
+
+func (x *passkeyUser) WebAuthnName() string {
+	return x.entry.Name
+}
+
+func (x *passkeyUser) WebAuthnDisplayName() string {
+	return x.entry.DisplayName
+}
+
+func (x *passkeyUser) WebAuthnCredentials() []webauthn.Credential {
+	return x.credentials
+}
+
+// WebAuthnIcon is deprecated and unused, it is only here because [webauthn.User] still requires it.
+func (x *passkeyUser) WebAuthnIcon() string {
+	return ""
+}
+
+func webAuthnInput(in auth.Input) (WebAuthnInput, error) {
+	input, ok := in.(WebAuthnInput)
+	if !ok {
+		return WebAuthnInput{}, fmt.Errorf("strategy: webauthn, unsupported input %T", in)
+	}
+	return input, nil
+}
@@ -0,0 +1,78 @@
+package strategy
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+)
+
+func TestWebAuthnParseInput(t *testing.T) {
+	s := NewWebAuthn(nil, nil)
+	id := uuid.New()
+
+	t.Run("OK", func(t *testing.T) {
+		in, err := s.ParseInput(context.Background(), &gen.Input{
+			Strategy: gen.Strategy_WebAuthn,
+			Data: &gen.Input_WebAuthn{
+				WebAuthn: &gen.WebAuthnInput{ChallengeId: id.String(), Credential: []byte("{}")},
+			},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if in.(WebAuthnInput).ChallengeID != id {
+			t.Errorf("expected %s, got %s", id, in.(WebAuthnInput).ChallengeID)
+		}
+	})
+
+	t.Run("invalid challenge ID returns error", func(t *testing.T) {
+		_, err := s.ParseInput(context.Background(), &gen.Input{
+			Strategy: gen.Strategy_WebAuthn,
+			Data: &gen.Input_WebAuthn{
+				WebAuthn: &gen.WebAuthnInput{ChallengeId: "challenge", Credential: []byte("{}")},
+			},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("empty credential returns error", func(t *testing.T) {
+		_, err := s.ParseInput(context.Background(), &gen.Input{
+			Strategy: gen.Strategy_WebAuthn,
+			Data:     &gen.Input_WebAuthn{WebAuthn: &gen.WebAuthnInput{ChallengeId: id.String()}},
+		})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("missing input returns error", func(t *testing.T) {
+		_, err := s.ParseInput(context.Background(), &gen.Input{Strategy: gen.Strategy_WebAuthn})
+		if err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestWebAuthnBeginRegistrationInvalidName(t *testing.T) {
+	s := NewWebAuthn(nil, nil)
+
+	for _, tc := range []struct {
+		name        string
+		displayName string
+	}{
+		{name: ""},
+		{name: strings.Repeat("a", maxNameLength+1)},
+		{name: "name", displayName: strings.Repeat("a", maxNameLength+1)},
+	} {
+		if _, err := s.BeginRegistration(context.Background(), tc.name, tc.displayName); !errors.Is(err, auth.ErrInvalidName) {
+			t.Errorf("expected ErrInvalidName, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/refreshtoken"
+	"github.com/Salam4nder/identity/internal/database/session"
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("auth")
+
+var (
+	// ErrInvalidRefreshToken is returned when a refresh token is malformed, expired, revoked or unknown.
+	ErrInvalidRefreshToken = errors.New("auth: invalid refresh token")
+	// ErrRefreshTokenReused is returned when an already renewed refresh token is replayed.
+	// The whole family of the replayed token is revoked when this happens.
+	ErrRefreshTokenReused = errors.New("auth: refresh token reused")
+)
+
+// claimStrategy is the custom claim holding the strategy a user authenticated with.
+const claimStrategy = "strategy"
+
+// Pair is an access and refresh token pair issued for a user.
+type Pair struct {
+	UserID       uuid.UUID
+	SessionID    uuid.UUID
+	AccessToken  token.SafeString
+	RefreshToken token.SafeString
+	IssuedAt     time.Time
+	// AccessExpiresAt and RefreshExpiresAt tell clients when to renew.
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+}
+
+// Tokens issues token [Pair]s, rotates refresh tokens and keeps track of sessions.
+// Refresh tokens are stored hashed and grouped into families, a new family and session is started
+// by every [Tokens.Issue] and every [Tokens.Renew] adds to the family of the renewed token.
+// Each refresh token can be renewed exactly once, replaying it revokes its whole family.
+type Tokens struct {
+	db       *sql.DB
+	maker    token.Maker
+	denylist hotstorage.Denylist
+	policies SessionPolicies
+}
+
+// SessionPolicy defines how long a session lasts.
+type SessionPolicy struct {
+	// IdleTimeout ends a session that is not renewed within it, it is the lifetime of its refresh tokens.
+	// Defaults to the refresh duration of the maker.
+	IdleTimeout time.Duration
+	// AbsoluteTimeout ends a session this long after it started however often it is renewed,
+	// the user has to authenticate again after it. Zero never ends a session.
+	AbsoluteTimeout time.Duration
+}
+
+// SessionPolicies select the [SessionPolicy] of a session.
+type SessionPolicies struct {
+	Default SessionPolicy
+	// RememberMe is the policy of sessions started with [IssueParams.RememberMe], usually a longer one.
+	// Defaults to Default.
+	RememberMe SessionPolicy
+}
+
+func (x SessionPolicies) policy(rememberMe bool) SessionPolicy {
+	if rememberMe && x.RememberMe != (SessionPolicy{}) {
+		return x.RememberMe
+	}
+	return x.Default
+}
+
+// NewTokens returns a new [Tokens] that starts sessions with the given policies.
+// denylist should be the one maker verifies against.
+func NewTokens(db *sql.DB, maker token.Maker, denylist hotstorage.Denylist, policies SessionPolicies) *Tokens {
+	return &Tokens{db: db, maker: maker, denylist: denylist, policies: policies}
+}
+
+// IssueParams define the session started by [Tokens.Issue].
+type IssueParams struct {
+	UserID uuid.UUID
+	// Strategy the user authenticated with, it is recorded on the session and in the strategy claim.
+	Strategy string
+	// UserAgent and ClientIP describe the device of the session.
+	UserAgent string
+	ClientIP  string
+	// RememberMe selects the remember me [SessionPolicy].
+	RememberMe bool
+	// Custom claims are added to both tokens and carried over when they are renewed.
+	Custom map[string]string
+}
+
+// Issue starts a new session for the user and returns its first [Pair].
+// The refresh token family of the session has the ID of the session,
+// which is in the [token.ClaimSession] claim of every token of the session.
+func (x *Tokens) Issue(ctx context.Context, params IssueParams) (Pair, error) {
+	ctx, span := tracer.Start(ctx, "Issue")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("user_id", params.UserID.String()),
+		attribute.String("strategy", params.Strategy),
+		attribute.Bool("remember_me", params.RememberMe),
+	)
+
+	policy := x.policies.policy(params.RememberMe)
+	var absolute *time.Time
+	if policy.AbsoluteTimeout > 0 {
+		at := time.Now().Add(policy.AbsoluteTimeout)
+		absolute = &at
+	}
+
+	sessionID := uuid.New()
+	custom := make(map[string]string, len(params.Custom)+2)
+	for k, v := range params.Custom {
+		custom[k] = v
+	}
+	if params.Strategy != "" {
+		custom[claimStrategy] = params.Strategy
+	}
+	custom[token.ClaimSession] = sessionID.String()
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Pair{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	pair, err := x.issue(ctx, tx, sessionParams(params.UserID, custom, absolute), sessionID, policy)
+	if err != nil {
+		return Pair{}, errors.Join(err, tx.Rollback())
+	}
+	if err = session.Insert(ctx, tx, session.InsertParams{
+		ID:                sessionID,
+		UserID:            params.UserID,
+		Strategy:          params.Strategy,
+		UserAgent:         params.UserAgent,
+		ClientIP:          params.ClientIP,
+		RememberMe:        params.RememberMe,
+		CreatedAt:         pair.IssuedAt,
+		ExpiresAt:         pair.RefreshExpiresAt,
+		AbsoluteExpiresAt: absolute,
+	}); err != nil {
+		return Pair{}, errors.Join(err, tx.Rollback())
+	}
+	if err = tx.Commit(); err != nil {
+		return Pair{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	return pair, nil
+}
+
+// Renew trades a refresh token for a new [Pair] in the same family.
+// Returns [ErrInvalidRefreshToken] if the token can not be renewed
+// or [ErrRefreshTokenReused] if it was already renewed, its family and session are then revoked
+// and the access tokens of the session are denied like with [Tokens.RevokeSession].
+func (x *Tokens) Renew(ctx context.Context, refresh token.SafeString) (Pair, error) {
+	ctx, span := tracer.Start(ctx, "Renew")
+	defer span.End()
+
+	claims, err := x.maker.Verify(ctx, refresh, token.TypeRefresh)
+	if err != nil {
+		if errors.Is(err, token.ErrInvalid) || errors.Is(err, token.ErrRevoked) {
+			return Pair{}, fmt.Errorf("%w, %w", ErrInvalidRefreshToken, err)
+		}
+		return Pair{}, err
+	}
+
+	tx, err := x.db.BeginTx(ctx, nil)
+	if err != nil {
+		return Pair{}, database.NewOperationFailedError(ctx, err)
+	}
+
+	pair, ended, err := x.renew(ctx, tx, refresh, claims)
+	// A reused token still has to commit the revocation of its family and session.
+	if err != nil && !errors.Is(err, ErrRefreshTokenReused) {
+		return Pair{}, errors.Join(err, tx.Rollback())
+	}
+	if cErr := tx.Commit(); cErr != nil {
+		return Pair{}, errors.Join(err, database.NewOperationFailedError(ctx, cErr))
+	}
+	if ended != nil {
+		if dErr := x.denylist.Deny(ctx, ended.ID.String(), ended.ExpiresAt); dErr != nil {
+			return Pair{}, errors.Join(err, dErr)
+		}
+	}
+
+	return pair, err
+}
+
+// Revoke denies the given tokens on the denylist until they would have expired.
+// Either token can be empty. Revoking a refresh token also revokes its family and ends its session,
+// so none of the tokens renewed from it can be renewed again.
+// Tokens that are already invalid or revoked are ignored, there is nothing left to revoke.
+func (x *Tokens) Revoke(ctx context.Context, access, refresh token.SafeString) error {
+	ctx, span := tracer.Start(ctx, "Revoke")
+	defer span.End()
+
+	for t, typ := range map[token.SafeString]token.Type{access: token.TypeAccess, refresh: token.TypeRefresh} {
+		if t == "" {
+			continue
+		}
+		claims, err := x.maker.Verify(ctx, t, typ)
+		if err != nil {
+			if errors.Is(err, token.ErrInvalid) || errors.Is(err, token.ErrRevoked) {
+				continue
+			}
+			return err
+		}
+		if err = x.denylist.Deny(ctx, claims.ID, claims.ExpiresAt); err != nil {
+			return err
+		}
+		if revoker, ok := x.maker.(token.Revoker); ok {
+			if err = revoker.Revoke(ctx, t); err != nil {
+				return err
+			}
+		}
+		if typ == token.TypeRefresh && claims.SessionID() != uuid.Nil {
+			if err = x.RevokeSession(ctx, claims.Subject, claims.SessionID()); err != nil && !errors.Is(err, ErrSessionNotFound) {
+				return err
+			}
+		}
+	}
+
+	if refresh != "" {
+		n, err := refreshtoken.RevokeFamilyByHash(ctx, x.db, token.Hash(refresh), time.Now())
+		if err != nil {
+			return err
+		}
+		span.SetAttributes(attribute.Int64("revoked_refresh_tokens", n))
+	}
+
+	return nil
+}
+
+// Introspect reports whether t is active and returns its claims if it is, see RFC 7662.
+// hint is the expected [token.Type] of t and is tried first, it can be empty.
+// Refresh tokens are also checked against their stored state, they are inactive once renewed or revoked.
+func (x *Tokens) Introspect(ctx context.Context, t token.SafeString, hint token.Type) (token.Claims, bool, error) {
+	ctx, span := tracer.Start(ctx, "Introspect")
+	defer span.End()
+
+	types := []token.Type{token.TypeAccess, token.TypeRefresh}
+	if hint == token.TypeRefresh {
+		types = []token.Type{token.TypeRefresh, token.TypeAccess}
+	}
+	for _, typ := range types {
+		claims, err := x.maker.Verify(ctx, t, typ)
+		if err != nil {
+			if errors.Is(err, token.ErrInvalid) || errors.Is(err, token.ErrRevoked) {
+				continue
+			}
+			return token.Claims{}, false, err
+		}
+		if typ == token.TypeRefresh {
+			active, err := x.refreshActive(ctx, t)
+			if err != nil || !active {
+				return token.Claims{}, false, err
+			}
+		}
+		span.SetAttributes(attribute.Bool("active", true))
+		return claims, true, nil
+	}
+
+	return token.Claims{}, false, nil
+}
+
+// refreshActive reports whether the stored refresh token can still be renewed.
+func (x *Tokens) refreshActive(ctx context.Context, refresh token.SafeString) (bool, error) {
+	entry, err := refreshtoken.ReadByHash(ctx, x.db, token.Hash(refresh))
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return false, nil
+		}
+		return false, err
+	}
+	return entry.UsedAt == nil && entry.RevokedAt == nil && time.Now().Before(entry.ExpiresAt), nil
+}
+
+// PublicKeys returns the public keys tokens can be verified with offline.
+// Returns false if tokens are made with symmetric keys, which can not be published.
+func (x *Tokens) PublicKeys() ([]token.PublicKey, bool) {
+	source, ok := x.maker.(token.PublicKeySource)
+	if !ok {
+		return nil, false
+	}
+	return source.PublicKeys(), true
+}
+
+// renew renews refresh in tx. A reused refresh token revokes its family and returns the session
+// it ended, whose access tokens have to be denied once tx commits.
+func (x *Tokens) renew(
+	ctx context.Context,
+	tx *sql.Tx,
+	refresh token.SafeString,
+	claims token.Claims,
+) (Pair, *session.Entry, error) {
+	now := time.Now()
+
+	entry, err := refreshtoken.ReadByHashForUpdate(ctx, tx, token.Hash(refresh))
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return Pair{}, nil, ErrInvalidRefreshToken
+		}
+		return Pair{}, nil, err
+	}
+	if entry.RevokedAt != nil || now.After(entry.ExpiresAt) || entry.UserID != claims.Subject {
+		return Pair{}, nil, ErrInvalidRefreshToken
+	}
+
+	if entry.UsedAt != nil {
+		n, err := refreshtoken.RevokeFamily(ctx, tx, entry.FamilyID, now)
+		if err != nil {
+			return Pair{}, nil, err
+		}
+		// Families issued before sessions existed have none, and the session may have ended already.
+		ended, err := session.Revoke(ctx, tx, entry.FamilyID, entry.UserID, now)
+		if err != nil && !errors.As(err, &database.NotFoundError{}) {
+			return Pair{}, nil, err
+		}
+		slog.WarnContext(
+			ctx,
+			"auth: refresh token reused, revoked its family and session",
+			"family_id", entry.FamilyID,
+			"user_id", entry.UserID,
+			"revoked", n,
+		)
+		return Pair{}, ended, ErrRefreshTokenReused
+	}
+
+	// Families issued before sessions existed have none, they get the default policy.
+	policy, absolute := x.policies.Default, (*time.Time)(nil)
+	sess, err := session.Read(ctx, tx, entry.FamilyID)
+	if err != nil && !errors.As(err, &database.NotFoundError{}) {
+		return Pair{}, nil, err
+	}
+	if sess != nil {
+		policy, absolute = x.policies.policy(sess.RememberMe), sess.AbsoluteExpiresAt
+	}
+	// Tokens are encoded with second precision, less than that left can not be renewed.
+	if absolute != nil && absolute.Sub(now) < time.Second {
+		return Pair{}, nil, fmt.Errorf("%w, session expired", ErrInvalidRefreshToken)
+	}
+
+	if err = refreshtoken.MarkUsed(ctx, tx, entry.ID, now); err != nil {
+		return Pair{}, nil, err
+	}
+
+	pair, err := x.issue(ctx, tx, sessionParams(entry.UserID, claims.Custom, absolute), entry.FamilyID, policy)
+	if err != nil {
+		return Pair{}, nil, err
+	}
+	if err = session.Touch(ctx, tx, entry.FamilyID, now, pair.RefreshExpiresAt); err != nil {
+		return Pair{}, nil, err
+	}
+
+	return pair, nil, nil
+}
+
+// sessionParams returns the [token.Params] of the tokens of a session,
+// they never outlive its absolute timeout.
+func sessionParams(userID uuid.UUID, custom map[string]string, absolute *time.Time) token.Params {
+	params := token.Params{Subject: userID, Custom: custom}
+	if absolute != nil {
+		params.NotAfter = *absolute
+	}
+	return params
+}
+
+// issue makes a new [Pair] in the given family, the refresh token lasts for the idle timeout of policy.
+// Every token is stored with db, so none outlives a rolled back transaction.
+func (x *Tokens) issue(
+	ctx context.Context,
+	db database.Querier,
+	params token.Params,
+	familyID uuid.UUID,
+	policy SessionPolicy,
+) (Pair, error) {
+	params.DB = db
+	access, accessClaims, err := x.maker.MakeAccessToken(ctx, params)
+	if err != nil {
+		return Pair{}, err
+	}
+	params.Duration = policy.IdleTimeout
+	refresh, claims, err := x.maker.MakeRefreshToken(ctx, params)
+	if err != nil {
+		return Pair{}, err
+	}
+
+	if err = refreshtoken.Insert(ctx, db, refreshtoken.InsertParams{
+		ID:        uuid.New(),
+		FamilyID:  familyID,
+		UserID:    params.Subject,
+		TokenHash: token.Hash(refresh),
+		ExpiresAt: claims.ExpiresAt,
+		CreatedAt: claims.IssuedAt,
+	}); err != nil {
+		return Pair{}, err
+	}
+
+	return Pair{
+		UserID:           params.Subject,
+		SessionID:        familyID,
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		IssuedAt:         claims.IssuedAt,
+		AccessExpiresAt:  accessClaims.ExpiresAt,
+		RefreshExpiresAt: claims.ExpiresAt,
+	}, nil
+}
@@ -0,0 +1,162 @@
+//go:build testdb
+// +build testdb
+
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestTokens(t *testing.T) *Tokens {
+	t.Helper()
+
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	denylist := hotstorage.NewMemory()
+	ring, err := token.NewKeyRing(token.Key{ID: "test", Secret: []byte(random.String(token.KeySize))})
+	require.NoError(t, err)
+	maker, err := token.BootstrapPasetoMaker(
+		token.MakerOpts{AccessDuration: time.Minute, RefreshDuration: time.Hour, Denylist: denylist},
+		ring,
+	)
+	require.NoError(t, err)
+
+	return NewTokens(db, maker, denylist, SessionPolicies{})
+}
+
+func TestTokensRenew(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("OK", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		require.Equal(t, userID, pair.UserID)
+
+		renewed, err := tokens.Renew(ctx, pair.RefreshToken)
+		require.NoError(t, err)
+		require.Equal(t, userID, renewed.UserID)
+		require.NotEqual(t, pair.RefreshToken, renewed.RefreshToken)
+
+		_, err = tokens.Renew(ctx, renewed.RefreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("reuse revokes the family and its session", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		first, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		second, err := tokens.Renew(ctx, first.RefreshToken)
+		require.NoError(t, err)
+
+		_, err = tokens.Renew(ctx, first.RefreshToken)
+		require.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = tokens.Renew(ctx, second.RefreshToken)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+
+		sessions, err := tokens.Sessions(ctx, userID)
+		require.NoError(t, err)
+		require.Empty(t, sessions)
+		_, err = tokens.maker.Verify(ctx, second.AccessToken, token.TypeAccess)
+		require.ErrorIs(t, err, token.ErrRevoked)
+	})
+
+	t.Run("other families are untouched", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		a, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+		b, err := tokens.Issue(ctx, IssueParams{UserID: userID})
+		require.NoError(t, err)
+
+		_, err = tokens.Renew(ctx, a.RefreshToken)
+		require.NoError(t, err)
+		_, err = tokens.Renew(ctx, a.RefreshToken)
+		require.ErrorIs(t, err, ErrRefreshTokenReused)
+
+		_, err = tokens.Renew(ctx, b.RefreshToken)
+		require.NoError(t, err)
+	})
+
+	t.Run("unknown token", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		refresh, _, err := tokens.maker.MakeRefreshToken(ctx, token.Params{Subject: uuid.New()})
+		require.NoError(t, err)
+		_, err = tokens.Renew(ctx, refresh)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("access token is rejected", func(t *testing.T) {
+		tokens := newTestTokens(t)
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: uuid.New()})
+		require.NoError(t, err)
+		_, err = tokens.Renew(ctx, pair.AccessToken)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("custom claims are carried over", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		userID := uuid.New()
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: userID, Strategy: "Credentials"})
+		require.NoError(t, err)
+		renewed, err := tokens.Renew(ctx, pair.RefreshToken)
+		require.NoError(t, err)
+
+		claims, err := tokens.maker.Verify(ctx, renewed.AccessToken, token.TypeAccess)
+		require.NoError(t, err)
+		require.Equal(t, userID, claims.Subject)
+		require.Equal(t, "Credentials", claims.Custom["strategy"])
+	})
+
+	t.Run("malformed token", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		_, err := tokens.Renew(ctx, token.SafeString("ass"))
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+}
+
+func TestTokensRevoke(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("OK", func(t *testing.T) {
+		tokens := newTestTokens(t)
+
+		pair, err := tokens.Issue(ctx, IssueParams{UserID: uuid.New()})
+		require.NoError(t, err)
+		renewed, err := tokens.Renew(ctx, pair.RefreshToken)
+		require.NoError(t, err)
+
+		require.NoError(t, tokens.Revoke(ctx, renewed.AccessToken, pair.RefreshToken))
+
+		_, err = tokens.maker.Verify(ctx, renewed.AccessToken, token.TypeAccess)
+		require.ErrorIs(t, err, token.ErrRevoked)
+		_, err = tokens.maker.Verify(ctx, pair.RefreshToken, token.TypeRefresh)
+		require.ErrorIs(t, err, token.ErrRevoked)
+
+		// Revoking the first refresh token revoked the renewed one in its family.
+		_, err = tokens.Renew(ctx, renewed.RefreshToken)
+		require.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("invalid tokens are ignored", func(t *testing.T) {
+		tokens := newTestTokens(t)
+		require.NoError(t, tokens.Revoke(ctx, token.SafeString("ass"), token.SafeString("ass")))
+	})
+}
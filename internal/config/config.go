@@ -1,22 +1,40 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/Salam4nder/identity/proto/gen"
 	"gopkg.in/yaml.v3"
 )
 
 // Application is the application configuration.
 type Application struct {
-	Environment  string `yaml:"environment"`
-	SymmetricKey string `yaml:"symmetricKey"`
-	// AccessDuration  time.Duration `yaml:"accessDuration"`
-	// RefreshDuration time.Duration `yaml:"refreshDuration"`
-	PSQL   Postgres `yaml:"postgres"`
-	NATS   NATS     `yaml:"nats"`
-	Server Server   `yaml:"server"`
+	Environment string     `yaml:"environment"`
+	Strategies  []string   `yaml:"strategies"`
+	Sessions    Sessions   `yaml:"sessions"`
+	PSQL        Postgres   `yaml:"postgres"`
+	NATS        NATS       `yaml:"nats"`
+	Server      Server     `yaml:"server"`
+	HotStorage  HotStorage `yaml:"hotStorage"`
+	Token       Token      `yaml:"token"`
+	MFA         MFA        `yaml:"mfa"`
+	WebAuthn    WebAuthn   `yaml:"webAuthn"`
+	// PersonalNumber configures the provider the personalNumber strategy verifies people with.
+	PersonalNumber PersonalNumber `yaml:"personalNumber"`
+	// Passwordless configures the strategies that sign in with a one time code or link, like email.
+	Passwordless Passwordless `yaml:"passwordless"`
+	SMS          SMS          `yaml:"sms"`
+	// EmailVerification configures the verification emails sent on registration with credentials.
+	EmailVerification EmailVerification `yaml:"emailVerification"`
+	PasswordReset     PasswordReset     `yaml:"passwordReset"`
+	EmailChange       EmailChange       `yaml:"emailChange"`
+	// ServiceClients are the other services allowed to call service only RPCs, like Introspect.
+	ServiceClients []ServiceClient `yaml:"serviceClients"`
 }
 
 // New returns a new application configuration
@@ -39,6 +57,31 @@ func New() (*Application, error) {
 	return &cfg, nil
 }
 
+// ConfiguredStrategies parses [Application.Strategies] into [gen.Strategy] values.
+// Names are matched case-insensitively against the enum in proto/service.proto.
+func (x *Application) ConfiguredStrategies() ([]gen.Strategy, error) {
+	if len(x.Strategies) == 0 {
+		return nil, errors.New("config: no strategies configured")
+	}
+
+	strategies := make([]gen.Strategy, 0, len(x.Strategies))
+	for _, name := range x.Strategies {
+		var found bool
+		for k, v := range gen.Strategy_value {
+			if strings.EqualFold(k, name) && v != int32(gen.Strategy_NoStrategy) {
+				strategies = append(strategies, gen.Strategy(v))
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("config: unknown strategy %q", name)
+		}
+	}
+
+	return strategies, nil
+}
+
 // Postgres holds the Postgres configuration.
 type Postgres struct {
 	Host            string `yaml:"host"`
@@ -61,6 +104,151 @@ type Server struct {
 	GRPCPort string `yaml:"port"`
 }
 
+// HotStorage holds the hot storage configuration.
+type HotStorage struct {
+	// Driver is either "memory" or "redis". Defaults to "memory".
+	Driver string `yaml:"driver"`
+	Redis  Redis  `yaml:"redis"`
+}
+
+// Token holds the token maker configuration.
+type Token struct {
+	// Maker is either "paseto" for encrypted v4.local tokens, "pasetoPublic"
+	// for signed v4.public tokens, "jwt" for signed JWTs or "opaque" for
+	// random reference tokens stored in Postgres. Defaults to "paseto".
+	Maker string `yaml:"maker"`
+	// JWTAlgorithm is either "EdDSA" or "ES256". Defaults to "EdDSA".
+	JWTAlgorithm string `yaml:"jwtAlgorithm"`
+	// OpaqueCache caches the claims of opaque tokens in the hot storage.
+	OpaqueCache bool   `yaml:"opaqueCache"`
+	Issuer      string `yaml:"issuer"`
+	Audience    string `yaml:"audience"`
+	// ActiveKey is the ID of the key in Keys new tokens are made with.
+	// Every other key is only used to verify tokens.
+	ActiveKey string     `yaml:"activeKey"`
+	Keys      []TokenKey `yaml:"keys"`
+}
+
+// Defaults of [Sessions].
+const (
+	DefaultAccessTokenDuration = 15 * time.Minute
+	DefaultIdleTimeout         = 7 * 24 * time.Hour
+)
+
+// Sessions holds the token lifetimes and session timeouts. Durations are written like "15m" or "168h".
+type Sessions struct {
+	// AccessTokenDuration is the lifetime of access tokens. Defaults to [DefaultAccessTokenDuration].
+	AccessTokenDuration time.Duration `yaml:"accessTokenDuration"`
+	// Default is the policy of every session, unless it was started with remember me.
+	Default SessionPolicy `yaml:"default"`
+	// RememberMe is the policy of sessions started with remember me. Defaults to Default.
+	RememberMe SessionPolicy `yaml:"rememberMe"`
+}
+
+// SessionPolicy holds the timeouts of a session.
+type SessionPolicy struct {
+	// IdleTimeout ends a session that is not renewed within it, it is the lifetime of refresh tokens.
+	// Defaults to [DefaultIdleTimeout].
+	IdleTimeout time.Duration `yaml:"idleTimeout"`
+	// AbsoluteTimeout ends a session this long after it started, however often it is renewed.
+	// Zero never ends a session.
+	AbsoluteTimeout time.Duration `yaml:"absoluteTimeout"`
+}
+
+// MFA holds the multi-factor authentication configuration.
+type MFA struct {
+	// Issuer is shown next to the account in authenticator apps.
+	Issuer string `yaml:"issuer"`
+	// EncryptionKey is the 32 byte secret TOTP secrets are encrypted with at rest.
+	// Changing it makes every enrolled TOTP secret unusable.
+	EncryptionKey string `yaml:"encryptionKey"`
+}
+
+// WebAuthn holds the relying party configuration of the WebAuthn strategy.
+type WebAuthn struct {
+	// RPID is the domain passkeys are bound to, like example.com.
+	RPID          string `yaml:"rpID"`
+	RPDisplayName string `yaml:"rpDisplayName"`
+	// RPOrigins are the origins ceremonies are accepted from, like https://login.example.com.
+	RPOrigins []string `yaml:"rpOrigins"`
+}
+
+// PersonalNumber holds the configuration of the provider personal numbers are verified with.
+type PersonalNumber struct {
+	// Provider is either "http" or "fake", which verifies every personal number and only runs
+	// in the dev environment. Defaults to "fake" in the dev environment and to none otherwise.
+	Provider string `yaml:"provider"`
+	// URL and Token configure the "http" provider, which posts every personal number as JSON to URL
+	// with Token as bearer token.
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+}
+
+// Passwordless holds the configuration of the strategies that deliver one time codes and links.
+type Passwordless struct {
+	// OneTimeKey is the 32 byte secret codes are hashed and links are signed with.
+	OneTimeKey string `yaml:"oneTimeKey"`
+	// LinkURL is the page sign in links point to, it gets the token as query parameter.
+	// Without it only codes are delivered.
+	LinkURL string `yaml:"linkURL"`
+}
+
+// SMS holds the configuration of the SMS sender.
+type SMS struct {
+	// Sender is either "noop", which logs every SMS, or "http". Defaults to "noop".
+	Sender string `yaml:"sender"`
+	// URL, Token and From configure the "http" sender, which posts every SMS as JSON to URL
+	// with Token as bearer token. From is the sender ID or number shown to the recipient.
+	URL   string `yaml:"url"`
+	Token string `yaml:"token"`
+	From  string `yaml:"from"`
+}
+
+// EmailVerification holds the configuration of the emails credentials users verify their email with.
+type EmailVerification struct {
+	// Required blocks the authentication of users that have not verified their email.
+	Required bool `yaml:"required"`
+	// LinkURL is the page verification links point to, it gets the token as query parameter.
+	// Without it the token itself is emailed.
+	LinkURL string `yaml:"linkURL"`
+}
+
+// PasswordReset holds the configuration of the emails credentials users reset a forgotten password with.
+type PasswordReset struct {
+	// LinkURL is the page password reset links point to, it gets the token as query parameter.
+	// Without it the token itself is emailed.
+	LinkURL string `yaml:"linkURL"`
+}
+
+// EmailChange holds the configuration of the emails credentials users change their email with.
+type EmailChange struct {
+	// ConfirmLinkURL is the page the links sent to a new email point to, RevertLinkURL the page
+	// the links sent to the previous email point to. They get the token as query parameter.
+	// Without them the token itself is emailed.
+	ConfirmLinkURL string `yaml:"confirmLinkURL"`
+	RevertLinkURL  string `yaml:"revertLinkURL"`
+}
+
+// TokenKey is a 32 byte secret identified by its ID.
+type TokenKey struct {
+	ID     string `yaml:"id"`
+	Secret string `yaml:"secret"`
+}
+
+// ServiceClient identifies another service by its ID and the hex encoded SHA-256 hash of its secret.
+type ServiceClient struct {
+	ID         string `yaml:"id"`
+	SecretHash string `yaml:"secretHash"`
+}
+
+// Redis holds the Redis configuration.
+type Redis struct {
+	Host     string `yaml:"host"`
+	Port     string `yaml:"port"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
 // Addr returns the PSQL connection string.
 func (x *Postgres) Addr() string {
 	return fmt.Sprintf(
@@ -84,6 +272,11 @@ func (x NATS) Addr() string {
 	return fmt.Sprintf("nats://%s:%s", x.Host, x.Port)
 }
 
+// Addr returns the Redis address.
+func (x Redis) Addr() string {
+	return fmt.Sprintf("%s:%s", x.Host, x.Port)
+}
+
 // GRPCAddr returns the gRPC server address.
 func (x *Server) GRPCAddr() string {
 	return fmt.Sprintf("%s:%s", x.GRPCHost, x.GRPCPort)
@@ -0,0 +1,111 @@
+package backupcode
+
+import (
+	"context"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("backupcode")
+
+const Tablename = "backup_codes"
+
+// Entry defines an entry in the backup codes table.
+// Codes are stored hashed and can each be used once instead of a TOTP code.
+type Entry struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	CodeHash  string     `db:"code_hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// Replace deletes every backup code of a user and inserts the given hashes as new ones.
+// Should be called within a transaction. Returns [database.DuplicateEntryError] on duplicate hashes,
+// otherwise [database.OperationFailedError].
+func Replace(ctx context.Context, db database.Querier, userID uuid.UUID, hashes []string, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "Replace")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.Int("codes", len(hashes)),
+	)
+
+	if err := DeleteByUser(ctx, db, userID); err != nil {
+		return err
+	}
+
+	query := `
+    INSERT INTO backup_codes (id, user_id, code_hash, created_at)
+    VALUES ($1, $2, $3, $4)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	for _, hash := range hashes {
+		if _, err := db.ExecContext(ctx, query, uuid.New(), userID, hash, at); err != nil {
+			if database.IsPSQLDuplicateEntryError(err) {
+				return database.NewDuplicateEntryError(ctx, err, "backup code")
+			}
+			return database.NewOperationFailedError(ctx, err)
+		}
+	}
+
+	return nil
+}
+
+// Use marks the unused backup code of a user with the given hash as used.
+// Returns [database.NotFoundError] if the user has no such unused code,
+// otherwise [database.OperationFailedError].
+func Use(ctx context.Context, db database.Querier, userID uuid.UUID, hash string, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "Use")
+	defer span.End()
+
+	query := `
+        UPDATE backup_codes
+        SET used_at = $1
+        WHERE user_id = $2 AND code_hash = $3 AND used_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, userID, hash)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewNotFoundError(ctx, nil, "backup code", "code_hash")
+	}
+
+	return nil
+}
+
+// DeleteByUser deletes every backup code of a user. Returns [database.OperationFailedError] on failure.
+func DeleteByUser(ctx context.Context, db database.Querier, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "DeleteByUser")
+	defer span.End()
+
+	query := `
+        DELETE FROM backup_codes
+        WHERE user_id = $1
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	if _, err := db.ExecContext(ctx, query, userID); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
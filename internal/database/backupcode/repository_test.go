@@ -0,0 +1,78 @@
+//go:build testdb
+// +build testdb
+
+package backupcode_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/backupcode"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReplace(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, backupcode.Replace(ctx, db, userID, []string{"a", "b"}, time.Now()))
+		require.NoError(t, backupcode.Use(ctx, db, userID, "a", time.Now()))
+	})
+
+	t.Run("old codes are removed", func(t *testing.T) {
+		require.NoError(t, backupcode.Replace(ctx, db, userID, []string{"c"}, time.Now()))
+
+		err := backupcode.Use(ctx, db, userID, "b", time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("duplicate hash returns error", func(t *testing.T) {
+		err := backupcode.Replace(ctx, db, userID, []string{"d", "d"}, time.Now())
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+}
+
+func TestUse(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	require.NoError(t, backupcode.Replace(ctx, db, userID, []string{"a"}, time.Now()))
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, backupcode.Use(ctx, db, userID, "a", time.Now()))
+	})
+
+	t.Run("used code returns error", func(t *testing.T) {
+		err := backupcode.Use(ctx, db, userID, "a", time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("other user returns error", func(t *testing.T) {
+		require.NoError(t, backupcode.Replace(ctx, db, userID, []string{"b"}, time.Now()))
+
+		err := backupcode.Use(ctx, db, uuid.New(), "b", time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestDeleteByUser(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	require.NoError(t, backupcode.Replace(ctx, db, userID, []string{"a"}, time.Now()))
+	require.NoError(t, backupcode.DeleteByUser(ctx, db, userID))
+
+	err := backupcode.Use(ctx, db, userID, "a", time.Now())
+	require.ErrorAs(t, err, &database.NotFoundError{})
+}
@@ -26,6 +26,8 @@ type Entry struct {
 	PasswordHash string     `db:"password_hash"`
 	CreatedAt    time.Time  `db:"created_at"`
 	UpdatedAt    *time.Time `db:"updated_at"`
+	// EmailVerifiedAt is nil until the user verifies the email.
+	EmailVerifiedAt *time.Time `db:"email_verified_at"`
 }
 
 // InsertParams defines the parameters for inserts.
@@ -47,7 +49,7 @@ func (x InsertParams) SpanAttributes() []attribute.KeyValue {
 // Insert a new credentials entry.
 // Returns [database.DuplicateEntryError] on duplicate entry,
 // [database.RowsAffectedError] or [database.OperationFailedError].
-func Insert(ctx context.Context, db *sql.DB, params InsertParams) error {
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
 	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
 	defer span.End()
 
@@ -84,7 +86,7 @@ func Insert(ctx context.Context, db *sql.DB, params InsertParams) error {
 
 // Read a credentials [Entry] by ID.
 // Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
-func Read(ctx context.Context, db *sql.DB, id uuid.UUID) (*Entry, error) {
+func Read(ctx context.Context, db database.Querier, id uuid.UUID) (*Entry, error) {
 	ctx, span := tracer.Start(ctx, "Read")
 	defer span.End()
 	span.SetAttributes(attribute.String("id", id.String()))
@@ -94,7 +96,7 @@ func Read(ctx context.Context, db *sql.DB, id uuid.UUID) (*Entry, error) {
 	}
 
 	query := `
-        SELECT id, email, password_hash, created_at, updated_at
+        SELECT id, email, password_hash, created_at, updated_at, email_verified_at
         FROM credentials
         WHERE id = $1
         `
@@ -107,6 +109,7 @@ func Read(ctx context.Context, db *sql.DB, id uuid.UUID) (*Entry, error) {
 		&user.PasswordHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, database.NewNotFoundError(ctx, err, "credentials", id.String())
@@ -129,7 +132,7 @@ func ReadByEmail(ctx context.Context, db *sql.DB, email string) (*Entry, error)
 	}
 
 	query := `
-        SELECT id, email, password_hash, created_at, updated_at
+        SELECT id, email, password_hash, created_at, updated_at, email_verified_at
         FROM credentials
         WHERE email = $1
         `
@@ -145,6 +148,7 @@ func ReadByEmail(ctx context.Context, db *sql.DB, email string) (*Entry, error)
 		&user.PasswordHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.EmailVerifiedAt,
 	); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, database.NewNotFoundError(ctx, err, "credentials", email)
@@ -170,7 +174,7 @@ func (x UpdateParams) SpanAttributes() []attribute.KeyValue {
 
 // Update credentials. Returns [database.DuplicateEntryError] on duplicate entry,
 // [database.RowsAffectedError] or [database.OperationFailedError].
-func Update(ctx context.Context, db *sql.DB, params UpdateParams) error {
+func Update(ctx context.Context, db database.Querier, params UpdateParams) error {
 	ctx, span := tracer.Start(ctx, "Update", trace.WithAttributes(params.SpanAttributes()...))
 	defer span.End()
 
@@ -205,6 +209,61 @@ func Update(ctx context.Context, db *sql.DB, params UpdateParams) error {
 	return nil
 }
 
+// UpdatePassword replaces the password hash of a credentials entry with the hash of pw.
+// Returns [database.RowsAffectedError] if the entry does not exist, otherwise [database.OperationFailedError].
+func UpdatePassword(ctx context.Context, db database.Querier, id uuid.UUID, pw password.SafeString) error {
+	ctx, span := tracer.Start(ctx, "UpdatePassword")
+	defer span.End()
+
+	query := `
+        UPDATE credentials
+        SET password_hash = $1, updated_at = $2
+        WHERE id = $3
+        `
+	span.SetAttributes(
+		attribute.String("user_id", id.String()),
+		attribute.Int("password_length", len(pw)),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, pw, time.Now(), id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified marks the email of a credentials entry as verified, unless it already is.
+// Returns [database.OperationFailedError] on failure.
+func MarkEmailVerified(ctx context.Context, db database.Querier, id uuid.UUID, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "MarkEmailVerified")
+	defer span.End()
+
+	query := `
+        UPDATE credentials
+        SET email_verified_at = $1
+        WHERE id = $2 AND email_verified_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("user_id", id.String()),
+		attribute.String("query", query),
+	)
+
+	if _, err := db.ExecContext(ctx, query, at, id); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
+
 // Delete a credentils [Entry] from the database.
 // Returns [database.RowsAffectedError] or [database.OperationFailedError] on error.
 func Delete(ctx context.Context, db *sql.DB, id uuid.UUID) error {
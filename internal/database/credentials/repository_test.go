@@ -203,6 +203,25 @@ func TestUpdate(t *testing.T) {
 		require.Error(t, err)
 	})
 
+	t.Run("duplicate email returns err", func(t *testing.T) {
+		t.Cleanup(cleanup)
+
+		taken := credentials.InsertParams{
+			ID:        uuid.New(),
+			Email:     random.Email(),
+			Password:  password.SafeString(random.String(10)),
+			CreatedAt: time.Now().UTC(),
+		}
+		require.NoError(t, credentials.Insert(ctx, db, taken))
+		require.NoError(t, credentials.Insert(ctx, db, randomParams))
+
+		err := credentials.Update(ctx, db, credentials.UpdateParams{
+			ID:    randomParams.ID,
+			Email: taken.Email,
+		})
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
 	t.Run("not found", func(t *testing.T) {
 		err := credentials.Update(ctx, db, credentials.UpdateParams{
 			ID:    uuid.New(),
@@ -213,6 +232,63 @@ func TestUpdate(t *testing.T) {
 	})
 }
 
+func TestUpdatePassword(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	ID := uuid.New()
+	require.NoError(t, credentials.Insert(ctx, db, credentials.InsertParams{
+		ID:        ID,
+		Email:     random.Email(),
+		Password:  password.SafeString(random.String(15)),
+		CreatedAt: time.Now(),
+	}))
+
+	newPassword := password.SafeString(random.String(15))
+	require.NoError(t, credentials.UpdatePassword(ctx, db, ID, newPassword))
+
+	got, err := credentials.Read(ctx, db, ID)
+	require.NoError(t, err)
+	require.NoError(t, newPassword.Compare(got.PasswordHash))
+	require.NotNil(t, got.UpdatedAt)
+
+	t.Run("not found", func(t *testing.T) {
+		err := credentials.UpdatePassword(ctx, db, uuid.New(), newPassword)
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+}
+
+func TestMarkEmailVerified(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	ID := uuid.New()
+	require.NoError(t, credentials.Insert(ctx, db, credentials.InsertParams{
+		ID:        ID,
+		Email:     random.Email(),
+		Password:  password.SafeString(random.String(15)),
+		CreatedAt: time.Now(),
+	}))
+
+	got, err := credentials.Read(ctx, db, ID)
+	require.NoError(t, err)
+	require.Nil(t, got.EmailVerifiedAt)
+
+	verifiedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, credentials.MarkEmailVerified(ctx, db, ID, verifiedAt))
+
+	t.Run("verifying again keeps the first time", func(t *testing.T) {
+		require.NoError(t, credentials.MarkEmailVerified(ctx, db, ID, verifiedAt.Add(time.Hour)))
+
+		got, err := credentials.Read(ctx, db, ID)
+		require.NoError(t, err)
+		require.NotNil(t, got.EmailVerifiedAt)
+		require.True(t, verifiedAt.Equal(*got.EmailVerifiedAt))
+	})
+}
+
 func TestDelete(t *testing.T) {
 	ctx := context.Background()
 	db, cleanup := Conn()
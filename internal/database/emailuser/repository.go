@@ -0,0 +1,110 @@
+package emailuser
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("emailuser")
+
+const Tablename = "email_users"
+
+// Entry defines an entry in the email users table.
+// Email users sign in with a code or link sent to their email, they have no password.
+type Entry struct {
+	ID        uuid.UUID `db:"id"`
+	Email     string    `db:"email"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID        uuid.UUID
+	Email     string
+	CreatedAt time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("user_id", x.ID.String()),
+		attribute.String("email", x.Email),
+	}
+}
+
+// Insert a new email user entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO email_users (id, email, created_at)
+    VALUES ($1, $2, $3)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.Email,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "email user")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadByEmail reads an email user [Entry] by its email.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByEmail(ctx context.Context, db database.Querier, email string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByEmail")
+	defer span.End()
+	span.SetAttributes(attribute.String("email", email))
+
+	if email == "" {
+		return nil, database.NewInputError(ctx, nil, "email", email)
+	}
+
+	query := `
+        SELECT id, email, created_at
+        FROM email_users
+        WHERE email = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, email).Scan(
+		&entry.ID,
+		&entry.Email,
+		&entry.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "email user", "email")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,49 @@
+//go:build testdb
+// +build testdb
+
+package emailuser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/emailuser"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := emailuser.InsertParams{
+		ID:        uuid.New(),
+		Email:     random.Email(),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, emailuser.Insert(ctx, db, params))
+
+		got, err := emailuser.ReadByEmail(ctx, db, params.Email)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.True(t, params.CreatedAt.Equal(got.CreatedAt))
+	})
+
+	t.Run("duplicate email returns error", func(t *testing.T) {
+		p := params
+		p.ID = uuid.New()
+		err := emailuser.Insert(ctx, db, p)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := emailuser.ReadByEmail(ctx, db, random.Email())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
@@ -0,0 +1,17 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Lock takes a transaction level advisory lock on key, released when tx commits or rolls back.
+// Transactions locking the same key run one after the other, so what one reads after locking,
+// like a count that limits an insert, holds until it ends.
+// Returns [OperationFailedError] on failure.
+func Lock(ctx context.Context, tx *sql.Tx, key string) error {
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtextextended($1, 0))`, key); err != nil {
+		return NewOperationFailedError(ctx, err)
+	}
+	return nil
+}
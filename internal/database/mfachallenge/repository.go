@@ -0,0 +1,188 @@
+package mfachallenge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("mfachallenge")
+
+const Tablename = "mfa_challenges"
+
+// Entry defines an entry in the MFA challenges table.
+// A challenge is handed out by a first factor authentication that still needs a second factor,
+// only the hash of its token is stored.
+type Entry struct {
+	ID         uuid.UUID  `db:"id"`
+	TokenHash  string     `db:"token_hash"`
+	UserID     uuid.UUID  `db:"user_id"`
+	Strategy   string     `db:"strategy"`
+	RememberMe bool       `db:"remember_me"`
+	Attempts   int        `db:"attempts"`
+	ExpiresAt  time.Time  `db:"expires_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+	UsedAt     *time.Time `db:"used_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID         uuid.UUID
+	TokenHash  string
+	UserID     uuid.UUID
+	Strategy   string
+	RememberMe bool
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("user_id", x.UserID.String()),
+		attribute.String("strategy", x.Strategy),
+	}
+}
+
+// Insert a new MFA challenge entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO mfa_challenges (id, token_hash, user_id, strategy, remember_me, expires_at, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.TokenHash,
+		params.UserID,
+		params.Strategy,
+		params.RememberMe,
+		params.ExpiresAt,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "mfa challenge")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadByHashForUpdate reads an MFA challenge [Entry] by its hash and locks the row
+// until the surrounding transaction ends, so concurrent attempts are counted one by one.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByHashForUpdate(ctx context.Context, tx *sql.Tx, hash string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByHashForUpdate")
+	defer span.End()
+
+	if hash == "" {
+		return nil, database.NewInputError(ctx, nil, "token_hash", hash)
+	}
+
+	query := `
+        SELECT id, token_hash, user_id, strategy, remember_me, attempts, expires_at, created_at, used_at
+        FROM mfa_challenges
+        WHERE token_hash = $1
+        FOR UPDATE
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := tx.QueryRowContext(ctx, query, hash).Scan(
+		&entry.ID,
+		&entry.TokenHash,
+		&entry.UserID,
+		&entry.Strategy,
+		&entry.RememberMe,
+		&entry.Attempts,
+		&entry.ExpiresAt,
+		&entry.CreatedAt,
+		&entry.UsedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "mfa challenge", "hash")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// RecordAttempt counts a failed attempt to answer an MFA challenge.
+// Returns [database.OperationFailedError] on failure.
+func RecordAttempt(ctx context.Context, db database.Querier, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "RecordAttempt")
+	defer span.End()
+
+	query := `
+        UPDATE mfa_challenges
+        SET attempts = attempts + 1
+        WHERE id = $1
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	if _, err := db.ExecContext(ctx, query, id); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
+
+// MarkUsed marks an MFA challenge as answered.
+// Returns [database.RowsAffectedError] if the entry does not exist or is already used,
+// otherwise [database.OperationFailedError].
+func MarkUsed(ctx context.Context, db database.Querier, id uuid.UUID, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "MarkUsed")
+	defer span.End()
+
+	query := `
+        UPDATE mfa_challenges
+        SET used_at = $1
+        WHERE id = $2 AND used_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+//go:build testdb
+// +build testdb
+
+package mfachallenge_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/mfachallenge"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams() mfachallenge.InsertParams {
+	return mfachallenge.InsertParams{
+		ID:         uuid.New(),
+		TokenHash:  uuid.NewString(),
+		UserID:     uuid.New(),
+		Strategy:   "Credentials",
+		RememberMe: true,
+		ExpiresAt:  time.Now().Add(5 * time.Minute).UTC().Truncate(time.Second),
+		CreatedAt:  time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, mfachallenge.Insert(ctx, db, params))
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		got, err := mfachallenge.ReadByHashForUpdate(ctx, tx, params.TokenHash)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.Equal(t, params.UserID, got.UserID)
+		require.Equal(t, params.Strategy, got.Strategy)
+		require.True(t, got.RememberMe)
+		require.Zero(t, got.Attempts)
+		require.True(t, params.ExpiresAt.Equal(got.ExpiresAt))
+		require.Nil(t, got.UsedAt)
+	})
+
+	t.Run("duplicate hash returns error", func(t *testing.T) {
+		p := randomParams()
+		p.TokenHash = params.TokenHash
+		err := mfachallenge.Insert(ctx, db, p)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		_, err = mfachallenge.ReadByHashForUpdate(ctx, tx, "unknown")
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestRecordAttemptAndMarkUsed(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	require.NoError(t, mfachallenge.Insert(ctx, db, params))
+
+	require.NoError(t, mfachallenge.RecordAttempt(ctx, db, params.ID))
+	require.NoError(t, mfachallenge.MarkUsed(ctx, db, params.ID, time.Now()))
+
+	t.Run("already used returns error", func(t *testing.T) {
+		err := mfachallenge.MarkUsed(ctx, db, params.ID, time.Now())
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback() //nolint:errcheck
+
+	got, err := mfachallenge.ReadByHashForUpdate(ctx, tx, params.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, 1, got.Attempts)
+	require.NotNil(t, got.UsedAt)
+}
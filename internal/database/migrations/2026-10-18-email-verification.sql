@@ -0,0 +1,16 @@
+-- email_verified_at is NULL until the user follows the link of the verification email.
+ALTER TABLE credentials ADD COLUMN IF NOT EXISTS email_verified_at timestamptz DEFAULT NULL;
+
+-- verification_tokens are emailed to prove a user owns an email, only their hash is stored.
+-- purpose tells the flows apart, like email verification.
+CREATE TABLE IF NOT EXISTS verification_tokens (
+    id uuid PRIMARY KEY,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    user_id uuid NOT NULL,
+    purpose varchar(32) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL,
+    used_at timestamptz DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS verification_tokens_user_idx ON verification_tokens (user_id, purpose, created_at);
@@ -0,0 +1,29 @@
+-- secret is the TOTP secret encrypted with AES-GCM, confirmed_at is NULL until the user enters a first code.
+CREATE TABLE IF NOT EXISTS totp_secrets (
+    user_id uuid PRIMARY KEY,
+    secret bytea NOT NULL,
+    last_used_step bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL,
+    confirmed_at timestamptz DEFAULT NULL
+);
+
+CREATE TABLE IF NOT EXISTS backup_codes (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    code_hash varchar(64) NOT NULL,
+    created_at timestamptz NOT NULL,
+    used_at timestamptz DEFAULT NULL,
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    id uuid PRIMARY KEY,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    user_id uuid NOT NULL,
+    strategy varchar(32) NOT NULL,
+    remember_me boolean NOT NULL DEFAULT false,
+    attempts int NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL,
+    used_at timestamptz DEFAULT NULL
+);
@@ -0,0 +1,20 @@
+-- email users sign in without a password, with a code or link sent to their email.
+CREATE TABLE IF NOT EXISTS email_users (
+    id uuid PRIMARY KEY,
+    email varchar(255) NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL
+);
+
+-- address is where the code or link was delivered, only codes are stored and only as a keyed hash.
+-- Links are signed and carry the challenge ID instead.
+CREATE TABLE IF NOT EXISTS one_time_challenges (
+    id uuid PRIMARY KEY,
+    strategy varchar(32) NOT NULL,
+    address varchar(255) NOT NULL,
+    method varchar(8) NOT NULL,
+    code_hash varchar(64) DEFAULT NULL,
+    attempts int NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL,
+    used_at timestamptz DEFAULT NULL
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS personal_numbers (
+    id uuid PRIMARY KEY,
+    number bigint NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL,
+    updated_at timestamptz DEFAULT NULL
+);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS reference_tokens (
+    id uuid PRIMARY KEY,
+    token_hash varchar(64) NOT NULL UNIQUE,
+    subject uuid NOT NULL,
+    type varchar(16) NOT NULL,
+    issuer varchar(255) NOT NULL,
+    audience varchar(255) NOT NULL,
+    custom jsonb NOT NULL DEFAULT '{}',
+    issued_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS reference_tokens_subject_idx ON reference_tokens (subject);
+CREATE INDEX IF NOT EXISTS reference_tokens_expires_at_idx ON reference_tokens (expires_at);
@@ -0,0 +1,14 @@
+-- The refresh token family of a session has the ID of the session.
+CREATE TABLE IF NOT EXISTS sessions (
+    id uuid PRIMARY KEY,
+    user_id uuid NOT NULL,
+    strategy varchar(32) NOT NULL,
+    user_agent text NOT NULL DEFAULT '',
+    client_ip varchar(64) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL,
+    last_seen_at timestamptz NOT NULL,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON sessions (user_id);
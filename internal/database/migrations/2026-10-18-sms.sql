@@ -0,0 +1,9 @@
+-- phone users sign in without a password, with a code sent to their phone number in E.164 format.
+CREATE TABLE IF NOT EXISTS phone_users (
+    id uuid PRIMARY KEY,
+    phone varchar(16) NOT NULL UNIQUE,
+    created_at timestamptz NOT NULL
+);
+
+-- Deliveries are rate limited per address by counting its recent challenges.
+CREATE INDEX IF NOT EXISTS one_time_challenges_address_idx ON one_time_challenges (strategy, address, created_at);
@@ -0,0 +1,35 @@
+-- name is what users register their passkey under, the ID is also the WebAuthn user handle.
+CREATE TABLE IF NOT EXISTS passkey_users (
+    id uuid PRIMARY KEY,
+    name varchar(255) NOT NULL UNIQUE,
+    display_name varchar(255) NOT NULL,
+    created_at timestamptz NOT NULL
+);
+
+-- id is the credential ID chosen by the authenticator, aaguid identifies the model of the authenticator.
+CREATE TABLE IF NOT EXISTS passkeys (
+    id bytea PRIMARY KEY,
+    user_id uuid NOT NULL REFERENCES passkey_users (id) ON DELETE CASCADE,
+    public_key bytea NOT NULL,
+    sign_count bigint NOT NULL DEFAULT 0,
+    aaguid bytea NOT NULL,
+    attestation_type varchar(32) NOT NULL DEFAULT '',
+    transports varchar(255) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL,
+    last_used_at timestamptz DEFAULT NULL
+);
+
+CREATE INDEX IF NOT EXISTS passkeys_user_id_idx ON passkeys (user_id);
+
+-- session_data is the JSON encoded ceremony state, a challenge can be consumed once before it expires.
+CREATE TABLE IF NOT EXISTS webauthn_challenges (
+    id uuid PRIMARY KEY,
+    ceremony varchar(16) NOT NULL,
+    user_id uuid DEFAULT NULL,
+    name varchar(255) NOT NULL DEFAULT '',
+    display_name varchar(255) NOT NULL DEFAULT '',
+    session_data jsonb NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL,
+    used_at timestamptz DEFAULT NULL
+);
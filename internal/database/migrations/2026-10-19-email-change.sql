@@ -0,0 +1,3 @@
+-- email is the address an email change token is for. A new email is pending until its token is used,
+-- and the token that reverts a change holds the previous email.
+ALTER TABLE verification_tokens ADD COLUMN IF NOT EXISTS email varchar(255) DEFAULT NULL;
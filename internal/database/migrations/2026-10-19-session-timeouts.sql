@@ -0,0 +1,3 @@
+-- absolute_expires_at is NULL for sessions without an absolute timeout.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS remember_me boolean NOT NULL DEFAULT false;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS absolute_expires_at timestamptz DEFAULT NULL;
@@ -0,0 +1,221 @@
+package onetimechallenge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("onetimechallenge")
+
+const Tablename = "one_time_challenges"
+
+const (
+	// MethodCode challenges are answered with a short code, only its hash is stored.
+	MethodCode = "code"
+	// MethodLink challenges are answered with a signed link, nothing secret is stored.
+	MethodLink = "link"
+)
+
+// Entry defines an entry in the one time challenges table.
+// A challenge is a single use code or link delivered to the address of a user,
+// by a strategy that signs users in without a password.
+type Entry struct {
+	ID        uuid.UUID  `db:"id"`
+	Strategy  string     `db:"strategy"`
+	Address   string     `db:"address"`
+	Method    string     `db:"method"`
+	CodeHash  *string    `db:"code_hash"`
+	Attempts  int        `db:"attempts"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+// CodeHash is only set for [MethodCode] challenges.
+type InsertParams struct {
+	ID        uuid.UUID
+	Strategy  string
+	Address   string
+	Method    string
+	CodeHash  *string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("strategy", x.Strategy),
+		attribute.String("method", x.Method),
+	}
+}
+
+// Insert a new one time challenge entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO one_time_challenges (id, strategy, address, method, code_hash, expires_at, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.Strategy,
+		params.Address,
+		params.Method,
+		params.CodeHash,
+		params.ExpiresAt,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "one time challenge")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadForUpdate reads a one time challenge [Entry] by ID and locks the row
+// until the surrounding transaction ends, so concurrent attempts are counted one by one.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadForUpdate(ctx context.Context, tx *sql.Tx, id uuid.UUID) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadForUpdate")
+	defer span.End()
+	span.SetAttributes(attribute.String("id", id.String()))
+
+	if id == uuid.Nil {
+		return nil, database.NewInputError(ctx, nil, "id", id.String())
+	}
+
+	query := `
+        SELECT id, strategy, address, method, code_hash, attempts, expires_at, created_at, used_at
+        FROM one_time_challenges
+        WHERE id = $1
+        FOR UPDATE
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := tx.QueryRowContext(ctx, query, id).Scan(
+		&entry.ID,
+		&entry.Strategy,
+		&entry.Address,
+		&entry.Method,
+		&entry.CodeHash,
+		&entry.Attempts,
+		&entry.ExpiresAt,
+		&entry.CreatedAt,
+		&entry.UsedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "one time challenge", id.String())
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// RecordAttempt counts a failed attempt to answer a one time challenge.
+// Returns [database.OperationFailedError] on failure.
+func RecordAttempt(ctx context.Context, db database.Querier, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "RecordAttempt")
+	defer span.End()
+
+	query := `
+        UPDATE one_time_challenges
+        SET attempts = attempts + 1
+        WHERE id = $1
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	if _, err := db.ExecContext(ctx, query, id); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
+
+// MarkUsed marks a one time challenge as answered.
+// Returns [database.RowsAffectedError] if the entry does not exist or is already used,
+// otherwise [database.OperationFailedError].
+func MarkUsed(ctx context.Context, db database.Querier, id uuid.UUID, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "MarkUsed")
+	defer span.End()
+
+	query := `
+        UPDATE one_time_challenges
+        SET used_at = $1
+        WHERE id = $2 AND used_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// CountSince counts the one time challenges of strategy delivered to address since a point in time,
+// used or not. Returns [database.OperationFailedError] on failure.
+func CountSince(ctx context.Context, db database.Querier, strategy, address string, since time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "CountSince")
+	defer span.End()
+
+	query := `
+        SELECT count(*)
+        FROM one_time_challenges
+        WHERE strategy = $1 AND address = $2 AND created_at > $3
+        `
+	span.SetAttributes(
+		attribute.String("strategy", strategy),
+		attribute.String("query", query),
+	)
+
+	var count int
+	if err := db.QueryRowContext(ctx, query, strategy, address, since).Scan(&count); err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	return count, nil
+}
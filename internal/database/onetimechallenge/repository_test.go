@@ -0,0 +1,143 @@
+//go:build testdb
+// +build testdb
+
+package onetimechallenge_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/onetimechallenge"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams() onetimechallenge.InsertParams {
+	hash := uuid.NewString()
+	return onetimechallenge.InsertParams{
+		ID:        uuid.New(),
+		Strategy:  "Email",
+		Address:   random.Email(),
+		Method:    onetimechallenge.MethodCode,
+		CodeHash:  &hash,
+		ExpiresAt: time.Now().Add(5 * time.Minute).UTC().Truncate(time.Second),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, onetimechallenge.Insert(ctx, db, params))
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		got, err := onetimechallenge.ReadForUpdate(ctx, tx, params.ID)
+		require.NoError(t, err)
+		require.Equal(t, params.Strategy, got.Strategy)
+		require.Equal(t, params.Address, got.Address)
+		require.Equal(t, params.Method, got.Method)
+		require.Equal(t, *params.CodeHash, *got.CodeHash)
+		require.Zero(t, got.Attempts)
+		require.True(t, params.ExpiresAt.Equal(got.ExpiresAt))
+		require.Nil(t, got.UsedAt)
+	})
+
+	t.Run("link has no code hash", func(t *testing.T) {
+		p := randomParams()
+		p.Method = onetimechallenge.MethodLink
+		p.CodeHash = nil
+		require.NoError(t, onetimechallenge.Insert(ctx, db, p))
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		got, err := onetimechallenge.ReadForUpdate(ctx, tx, p.ID)
+		require.NoError(t, err)
+		require.Nil(t, got.CodeHash)
+	})
+
+	t.Run("duplicate ID returns error", func(t *testing.T) {
+		err := onetimechallenge.Insert(ctx, db, params)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		_, err = onetimechallenge.ReadForUpdate(ctx, tx, uuid.New())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestRecordAttemptAndMarkUsed(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	require.NoError(t, onetimechallenge.Insert(ctx, db, params))
+
+	require.NoError(t, onetimechallenge.RecordAttempt(ctx, db, params.ID))
+	require.NoError(t, onetimechallenge.MarkUsed(ctx, db, params.ID, time.Now()))
+
+	t.Run("already used returns error", func(t *testing.T) {
+		err := onetimechallenge.MarkUsed(ctx, db, params.ID, time.Now())
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback() //nolint:errcheck
+
+	got, err := onetimechallenge.ReadForUpdate(ctx, tx, params.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, got.Attempts)
+	require.NotNil(t, got.UsedAt)
+}
+
+func TestCountSince(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	params.CreatedAt = time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, onetimechallenge.Insert(ctx, db, params))
+	for i := 0; i < 2; i++ {
+		p := randomParams()
+		p.Address = params.Address
+		require.NoError(t, onetimechallenge.Insert(ctx, db, p))
+	}
+
+	t.Run("counts recent challenges of address", func(t *testing.T) {
+		count, err := onetimechallenge.CountSince(ctx, db, params.Strategy, params.Address, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		require.Equal(t, 2, count)
+	})
+
+	t.Run("counts every challenge since", func(t *testing.T) {
+		count, err := onetimechallenge.CountSince(ctx, db, params.Strategy, params.Address, time.Now().Add(-2*time.Hour))
+		require.NoError(t, err)
+		require.Equal(t, 3, count)
+	})
+
+	t.Run("other strategy", func(t *testing.T) {
+		count, err := onetimechallenge.CountSince(ctx, db, "SMS", params.Address, time.Now().Add(-2*time.Hour))
+		require.NoError(t, err)
+		require.Zero(t, count)
+	})
+}
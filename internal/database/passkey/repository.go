@@ -0,0 +1,182 @@
+package passkey
+
+import (
+	"context"
+	"encoding/base64"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("passkey")
+
+const Tablename = "passkeys"
+
+// Entry defines an entry in the passkeys table, a WebAuthn credential of a passkey user.
+type Entry struct {
+	// ID is the credential ID chosen by the authenticator.
+	ID        []byte    `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	PublicKey []byte    `db:"public_key"`
+	// SignCount is the last signature counter of the authenticator, it has to grow with every authentication.
+	SignCount uint32 `db:"sign_count"`
+	// AAGUID identifies the model of the authenticator.
+	AAGUID          []byte `db:"aaguid"`
+	AttestationType string `db:"attestation_type"`
+	// Transports are comma separated hints on how to reach the authenticator, like "usb" or "internal".
+	Transports string     `db:"transports"`
+	CreatedAt  time.Time  `db:"created_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID              []byte
+	UserID          uuid.UUID
+	PublicKey       []byte
+	SignCount       uint32
+	AAGUID          []byte
+	AttestationType string
+	Transports      string
+	CreatedAt       time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", base64.RawURLEncoding.EncodeToString(x.ID)),
+		attribute.String("user_id", x.UserID.String()),
+		attribute.String("attestation_type", x.AttestationType),
+	}
+}
+
+// Insert a new passkey entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO passkeys (id, user_id, public_key, sign_count, aaguid, attestation_type, transports, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.UserID,
+		params.PublicKey,
+		int64(params.SignCount),
+		params.AAGUID,
+		params.AttestationType,
+		params.Transports,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "passkey")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ListByUser returns every passkey [Entry] of the user, oldest first.
+// Returns [database.OperationFailedError] on failure.
+func ListByUser(ctx context.Context, db database.Querier, userID uuid.UUID) ([]Entry, error) {
+	ctx, span := tracer.Start(ctx, "ListByUser")
+	defer span.End()
+
+	query := `
+        SELECT id, user_id, public_key, sign_count, aaguid, attestation_type, transports, created_at, last_used_at
+        FROM passkeys
+        WHERE user_id = $1
+        ORDER BY created_at
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var (
+			entry     Entry
+			signCount int64
+		)
+		if err = rows.Scan(
+			&entry.ID,
+			&entry.UserID,
+			&entry.PublicKey,
+			&signCount,
+			&entry.AAGUID,
+			&entry.AttestationType,
+			&entry.Transports,
+			&entry.CreatedAt,
+			&entry.LastUsedAt,
+		); err != nil {
+			return nil, database.NewOperationFailedError(ctx, err)
+		}
+		entry.SignCount = uint32(signCount)
+		entries = append(entries, entry)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entries, nil
+}
+
+// Use records an authentication with a passkey and stores the new signature counter of its authenticator.
+// The counter only moves forward, so two authentications racing with the same counter can not both succeed.
+// Returns [database.RowsAffectedError] if the passkey does not exist or the counter did not grow,
+// otherwise [database.OperationFailedError].
+func Use(ctx context.Context, db database.Querier, id []byte, signCount uint32, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "Use")
+	defer span.End()
+
+	// Authenticators without a counter always report zero.
+	query := `
+        UPDATE passkeys
+        SET sign_count = $1, last_used_at = $2
+        WHERE id = $3 AND (sign_count < $1 OR (sign_count = 0 AND $1 = 0))
+        `
+	span.SetAttributes(
+		attribute.String("id", base64.RawURLEncoding.EncodeToString(id)),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, int64(signCount), at, id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+//go:build testdb
+// +build testdb
+
+package passkey_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/passkey"
+	"github.com/Salam4nder/identity/internal/database/passkeyuser"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// insertUser inserts the passkey user the passkeys of a test belong to.
+func insertUser(t *testing.T) uuid.UUID {
+	t.Helper()
+
+	db, _ := Conn()
+	id := uuid.New()
+	require.NoError(t, passkeyuser.Insert(context.Background(), db, passkeyuser.InsertParams{
+		ID:          id,
+		Name:        random.Email(),
+		DisplayName: random.FullName(),
+		CreatedAt:   time.Now(),
+	}))
+	return id
+}
+
+func randomParams(userID uuid.UUID) passkey.InsertParams {
+	return passkey.InsertParams{
+		ID:              []byte(random.String(16)),
+		UserID:          userID,
+		PublicKey:       []byte(random.String(77)),
+		SignCount:       1,
+		AAGUID:          []byte(random.String(16)),
+		AttestationType: "none",
+		Transports:      "internal,hybrid",
+		CreatedAt:       time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := insertUser(t)
+	params := randomParams(userID)
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, passkey.Insert(ctx, db, params))
+
+		got, err := passkey.ListByUser(ctx, db, userID)
+		require.NoError(t, err)
+		require.Len(t, got, 1)
+		require.Equal(t, params.ID, got[0].ID)
+		require.Equal(t, params.PublicKey, got[0].PublicKey)
+		require.Equal(t, params.SignCount, got[0].SignCount)
+		require.Equal(t, params.AAGUID, got[0].AAGUID)
+		require.Equal(t, params.AttestationType, got[0].AttestationType)
+		require.Equal(t, params.Transports, got[0].Transports)
+		require.Nil(t, got[0].LastUsedAt)
+	})
+
+	t.Run("duplicate ID returns error", func(t *testing.T) {
+		err := passkey.Insert(ctx, db, params)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("unknown user returns error", func(t *testing.T) {
+		err := passkey.Insert(ctx, db, randomParams(uuid.New()))
+		require.Error(t, err)
+	})
+
+	t.Run("no passkeys", func(t *testing.T) {
+		got, err := passkey.ListByUser(ctx, db, uuid.New())
+		require.NoError(t, err)
+		require.Empty(t, got)
+	})
+}
+
+func TestUse(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := insertUser(t)
+	params := randomParams(userID)
+	require.NoError(t, passkey.Insert(ctx, db, params))
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, passkey.Use(ctx, db, params.ID, 2, time.Now()))
+
+		got, err := passkey.ListByUser(ctx, db, userID)
+		require.NoError(t, err)
+		require.Equal(t, uint32(2), got[0].SignCount)
+		require.NotNil(t, got[0].LastUsedAt)
+	})
+
+	t.Run("counter that did not grow returns error", func(t *testing.T) {
+		err := passkey.Use(ctx, db, params.ID, 2, time.Now())
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+
+	t.Run("authenticator without a counter", func(t *testing.T) {
+		p := randomParams(userID)
+		p.SignCount = 0
+		require.NoError(t, passkey.Insert(ctx, db, p))
+
+		require.NoError(t, passkey.Use(ctx, db, p.ID, 0, time.Now()))
+		require.NoError(t, passkey.Use(ctx, db, p.ID, 0, time.Now()))
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		err := passkey.Use(ctx, db, []byte("unknown"), 3, time.Now())
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+}
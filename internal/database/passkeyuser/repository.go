@@ -0,0 +1,146 @@
+package passkeyuser
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("passkeyuser")
+
+const Tablename = "passkey_users"
+
+// Entry defines an entry in the passkey users table.
+// The ID of an entry is also its WebAuthn user handle.
+type Entry struct {
+	ID          uuid.UUID `db:"id"`
+	Name        string    `db:"name"`
+	DisplayName string    `db:"display_name"`
+	CreatedAt   time.Time `db:"created_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID          uuid.UUID
+	Name        string
+	DisplayName string
+	CreatedAt   time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("user_id", x.ID.String()),
+	}
+}
+
+// Insert a new passkey user entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO passkey_users (id, name, display_name, created_at)
+    VALUES ($1, $2, $3, $4)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.Name,
+		params.DisplayName,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "passkey user")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// Read a passkey user [Entry] by ID.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func Read(ctx context.Context, db database.Querier, id uuid.UUID) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "Read")
+	defer span.End()
+	span.SetAttributes(attribute.String("id", id.String()))
+
+	if id == uuid.Nil {
+		return nil, database.NewInputError(ctx, nil, "id", id.String())
+	}
+
+	query := `
+        SELECT id, name, display_name, created_at
+        FROM passkey_users
+        WHERE id = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, id).Scan(
+		&entry.ID,
+		&entry.Name,
+		&entry.DisplayName,
+		&entry.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "passkey user", id.String())
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// ReadByName reads a passkey user [Entry] by its name.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByName(ctx context.Context, db database.Querier, name string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByName")
+	defer span.End()
+
+	if name == "" {
+		return nil, database.NewInputError(ctx, nil, "name", name)
+	}
+
+	query := `
+        SELECT id, name, display_name, created_at
+        FROM passkey_users
+        WHERE name = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, name).Scan(
+		&entry.ID,
+		&entry.Name,
+		&entry.DisplayName,
+		&entry.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "passkey user", "name")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
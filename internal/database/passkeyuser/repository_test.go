@@ -0,0 +1,58 @@
+//go:build testdb
+// +build testdb
+
+package passkeyuser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/passkeyuser"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := passkeyuser.InsertParams{
+		ID:          uuid.New(),
+		Name:        random.Email(),
+		DisplayName: random.FullName(),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, passkeyuser.Insert(ctx, db, params))
+
+		got, err := passkeyuser.Read(ctx, db, params.ID)
+		require.NoError(t, err)
+		require.Equal(t, params.Name, got.Name)
+		require.Equal(t, params.DisplayName, got.DisplayName)
+		require.True(t, params.CreatedAt.Equal(got.CreatedAt))
+
+		got, err = passkeyuser.ReadByName(ctx, db, params.Name)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+	})
+
+	t.Run("duplicate name returns error", func(t *testing.T) {
+		p := params
+		p.ID = uuid.New()
+		err := passkeyuser.Insert(ctx, db, p)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := passkeyuser.Read(ctx, db, uuid.New())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+
+		_, err = passkeyuser.ReadByName(ctx, db, random.Email())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
@@ -0,0 +1,187 @@
+package personalnumber
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("personalnumber")
+
+const Tablename = "personal_numbers"
+
+// Entry defines an entry in the personal numbers table.
+type Entry struct {
+	ID        uuid.UUID  `db:"id"`
+	Number    uint64     `db:"number"`
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID        uuid.UUID
+	Number    uint64
+	CreatedAt time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("user_id", x.ID.String()),
+		attribute.Int64("number", int64(x.Number)),
+	}
+}
+
+// Insert a new personal number entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db *sql.DB, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO personal_numbers (id, number, created_at)
+    VALUES ($1, $2, $3)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		int64(params.Number),
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "personal number")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// Read a personal number [Entry] by ID.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func Read(ctx context.Context, db *sql.DB, id uuid.UUID) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "Read")
+	defer span.End()
+	span.SetAttributes(attribute.String("id", id.String()))
+
+	if id == uuid.Nil {
+		return nil, database.NewInputError(ctx, nil, "id", id.String())
+	}
+
+	query := `
+        SELECT id, number, created_at, updated_at
+        FROM personal_numbers
+        WHERE id = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var (
+		entry  Entry
+		number int64
+	)
+	if err := db.QueryRowContext(ctx, query, id).Scan(
+		&entry.ID,
+		&number,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "personal number", id.String())
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	entry.Number = uint64(number)
+
+	return &entry, nil
+}
+
+// ReadByNumber a personal number [Entry] by its number.
+// On error, it returns [database.NotFoundError] if entry is not found,
+// otherwise [database.OperationFailedError].
+func ReadByNumber(ctx context.Context, db *sql.DB, number uint64) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByNumber")
+	defer span.End()
+
+	if number == 0 {
+		return nil, database.NewInputError(ctx, nil, "number", number)
+	}
+
+	query := `
+        SELECT id, number, created_at, updated_at
+        FROM personal_numbers
+        WHERE number = $1
+        `
+	span.SetAttributes(
+		attribute.String("query", query),
+		attribute.Int64("number", int64(number)),
+	)
+
+	var (
+		entry  Entry
+		stored int64
+	)
+	if err := db.QueryRowContext(ctx, query, int64(number)).Scan(
+		&entry.ID,
+		&stored,
+		&entry.CreatedAt,
+		&entry.UpdatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "personal number", number)
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	entry.Number = uint64(stored)
+
+	return &entry, nil
+}
+
+// Delete a personal number [Entry] from the database.
+// Returns [database.RowsAffectedError] or [database.OperationFailedError] on error.
+func Delete(ctx context.Context, db *sql.DB, id uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "Delete")
+	defer span.End()
+
+	query := `
+        DELETE FROM personal_numbers
+        WHERE id = $1
+        `
+	span.SetAttributes(
+		attribute.String("user_id", id.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
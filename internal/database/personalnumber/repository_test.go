@@ -0,0 +1,104 @@
+//go:build testdb
+// +build testdb
+
+package personalnumber_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/personalnumber"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+const testNumber uint64 = 198112189876
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := personalnumber.InsertParams{
+		ID:        uuid.New(),
+		Number:    testNumber,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		t.Cleanup(cleanup)
+
+		err := personalnumber.Insert(ctx, db, params)
+		require.NoError(t, err)
+
+		got, err := personalnumber.Read(ctx, db, params.ID)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.Equal(t, params.Number, got.Number)
+		require.True(t, time.Now().After(got.CreatedAt))
+	})
+
+	t.Run("duplicate number returns error", func(t *testing.T) {
+		t.Cleanup(cleanup)
+
+		err := personalnumber.Insert(ctx, db, params)
+		require.NoError(t, err)
+
+		params.ID = uuid.New()
+		err = personalnumber.Insert(ctx, db, params)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+}
+
+func TestReadByNumber(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := personalnumber.InsertParams{
+		ID:        uuid.New(),
+		Number:    testNumber,
+		CreatedAt: time.Now().UTC(),
+	}
+	require.NoError(t, personalnumber.Insert(ctx, db, params))
+
+	got, err := personalnumber.ReadByNumber(ctx, db, testNumber)
+	require.NoError(t, err)
+	require.Equal(t, params.ID, got.ID)
+	require.Equal(t, testNumber, got.Number)
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := personalnumber.ReadByNumber(ctx, db, 199001011239)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("Number is zero", func(t *testing.T) {
+		_, err := personalnumber.ReadByNumber(ctx, db, 0)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.InputError{})
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	ID := uuid.New()
+	require.NoError(t, personalnumber.Insert(ctx, db, personalnumber.InsertParams{
+		ID:        ID,
+		Number:    testNumber,
+		CreatedAt: time.Now(),
+	}))
+	require.NoError(t, personalnumber.Delete(ctx, db, ID))
+
+	t.Run("Not found", func(t *testing.T) {
+		err := personalnumber.Delete(ctx, db, ID)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+}
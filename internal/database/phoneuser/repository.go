@@ -0,0 +1,110 @@
+package phoneuser
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("phoneuser")
+
+const Tablename = "phone_users"
+
+// Entry defines an entry in the phone users table.
+// Phone users sign in with a code sent to their phone number, they have no password.
+type Entry struct {
+	ID        uuid.UUID `db:"id"`
+	Phone     string    `db:"phone"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID        uuid.UUID
+	Phone     string
+	CreatedAt time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("user_id", x.ID.String()),
+		attribute.String("phone", x.Phone),
+	}
+}
+
+// Insert a new phone user entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO phone_users (id, phone, created_at)
+    VALUES ($1, $2, $3)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.Phone,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "phone user")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadByPhone reads a phone user [Entry] by its phone number.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByPhone(ctx context.Context, db database.Querier, phone string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByPhone")
+	defer span.End()
+	span.SetAttributes(attribute.String("phone", phone))
+
+	if phone == "" {
+		return nil, database.NewInputError(ctx, nil, "phone", phone)
+	}
+
+	query := `
+        SELECT id, phone, created_at
+        FROM phone_users
+        WHERE phone = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, phone).Scan(
+		&entry.ID,
+		&entry.Phone,
+		&entry.CreatedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "phone user", "phone")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,49 @@
+//go:build testdb
+// +build testdb
+
+package phoneuser_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/phoneuser"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := phoneuser.InsertParams{
+		ID:        uuid.New(),
+		Phone:     random.Phone(),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, phoneuser.Insert(ctx, db, params))
+
+		got, err := phoneuser.ReadByPhone(ctx, db, params.Phone)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.True(t, params.CreatedAt.Equal(got.CreatedAt))
+	})
+
+	t.Run("duplicate phone returns error", func(t *testing.T) {
+		p := params
+		p.ID = uuid.New()
+		err := phoneuser.Insert(ctx, db, p)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := phoneuser.ReadByPhone(ctx, db, random.Phone())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
@@ -0,0 +1,19 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+var (
+	_ Querier = (*sql.DB)(nil)
+	_ Querier = (*sql.Tx)(nil)
+)
+
+// Querier is implemented by both [*sql.DB] and [*sql.Tx],
+// so repository functions can run inside and outside of a transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
@@ -0,0 +1,179 @@
+package referencetoken
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("referencetoken")
+
+const Tablename = "reference_tokens"
+
+// Entry defines an entry in the reference tokens table.
+// Only the hash of an opaque token is stored, its claims are only known to this service.
+type Entry struct {
+	ID        uuid.UUID         `db:"id"`
+	TokenHash string            `db:"token_hash"`
+	Subject   uuid.UUID         `db:"subject"`
+	Type      string            `db:"type"`
+	Issuer    string            `db:"issuer"`
+	Audience  string            `db:"audience"`
+	Custom    map[string]string `db:"custom"`
+	IssuedAt  time.Time         `db:"issued_at"`
+	ExpiresAt time.Time         `db:"expires_at"`
+	RevokedAt *time.Time        `db:"revoked_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID        uuid.UUID
+	TokenHash string
+	Subject   uuid.UUID
+	Type      string
+	Issuer    string
+	Audience  string
+	Custom    map[string]string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("subject", x.Subject.String()),
+		attribute.String("type", x.Type),
+	}
+}
+
+// Insert a new reference token entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	custom, err := json.Marshal(params.Custom)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if params.Custom == nil {
+		custom = []byte("{}")
+	}
+
+	query := `
+    INSERT INTO reference_tokens (id, token_hash, subject, type, issuer, audience, custom, issued_at, expires_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.TokenHash,
+		params.Subject,
+		params.Type,
+		params.Issuer,
+		params.Audience,
+		custom,
+		params.IssuedAt,
+		params.ExpiresAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "reference token")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadByHash reads a reference token [Entry] by its hash.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByHash(ctx context.Context, db database.Querier, hash string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByHash")
+	defer span.End()
+
+	if hash == "" {
+		return nil, database.NewInputError(ctx, nil, "token_hash", hash)
+	}
+
+	query := `
+        SELECT id, token_hash, subject, type, issuer, audience, custom, issued_at, expires_at, revoked_at
+        FROM reference_tokens
+        WHERE token_hash = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var (
+		entry  Entry
+		custom []byte
+	)
+	if err := db.QueryRowContext(ctx, query, hash).Scan(
+		&entry.ID,
+		&entry.TokenHash,
+		&entry.Subject,
+		&entry.Type,
+		&entry.Issuer,
+		&entry.Audience,
+		&custom,
+		&entry.IssuedAt,
+		&entry.ExpiresAt,
+		&entry.RevokedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "reference token", "hash")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	if err := json.Unmarshal(custom, &entry.Custom); err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	if len(entry.Custom) == 0 {
+		entry.Custom = nil
+	}
+
+	return &entry, nil
+}
+
+// RevokeByHash revokes the reference token with the given hash if it is not already revoked.
+// Returns the amount of revoked tokens or [database.OperationFailedError].
+func RevokeByHash(ctx context.Context, db database.Querier, hash string, at time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RevokeByHash")
+	defer span.End()
+
+	query := `
+        UPDATE reference_tokens
+        SET revoked_at = $1
+        WHERE token_hash = $2 AND revoked_at IS NULL
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(ctx, query, at, hash)
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	return rowsAffected, nil
+}
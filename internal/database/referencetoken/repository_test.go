@@ -0,0 +1,108 @@
+//go:build testdb
+// +build testdb
+
+package referencetoken_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/referencetoken"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams() referencetoken.InsertParams {
+	return referencetoken.InsertParams{
+		ID:        uuid.New(),
+		TokenHash: random.String(64),
+		Subject:   uuid.New(),
+		Type:      "access",
+		Issuer:    "identity-test",
+		Audience:  "test",
+		Custom:    map[string]string{"strategy": "Credentials"},
+		IssuedAt:  time.Now().UTC().Truncate(time.Second),
+		ExpiresAt: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, referencetoken.Insert(ctx, db, params))
+
+		got, err := referencetoken.ReadByHash(ctx, db, params.TokenHash)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.Equal(t, params.Subject, got.Subject)
+		require.Equal(t, params.Type, got.Type)
+		require.Equal(t, params.Custom, got.Custom)
+		require.True(t, params.ExpiresAt.Equal(got.ExpiresAt))
+		require.Nil(t, got.RevokedAt)
+	})
+
+	t.Run("without custom claims", func(t *testing.T) {
+		p := randomParams()
+		p.Custom = nil
+		require.NoError(t, referencetoken.Insert(ctx, db, p))
+
+		got, err := referencetoken.ReadByHash(ctx, db, p.TokenHash)
+		require.NoError(t, err)
+		require.Nil(t, got.Custom)
+	})
+
+	t.Run("duplicate hash returns error", func(t *testing.T) {
+		dup := randomParams()
+		dup.TokenHash = params.TokenHash
+		err := referencetoken.Insert(ctx, db, dup)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+}
+
+func TestReadByHash(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := referencetoken.ReadByHash(ctx, db, random.String(64))
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("empty hash", func(t *testing.T) {
+		_, err := referencetoken.ReadByHash(ctx, db, "")
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.InputError{})
+	})
+}
+
+func TestRevokeByHash(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	require.NoError(t, referencetoken.Insert(ctx, db, params))
+
+	n, err := referencetoken.RevokeByHash(ctx, db, params.TokenHash, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	got, err := referencetoken.ReadByHash(ctx, db, params.TokenHash)
+	require.NoError(t, err)
+	require.NotNil(t, got.RevokedAt)
+
+	n, err = referencetoken.RevokeByHash(ctx, db, params.TokenHash, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+}
@@ -0,0 +1,252 @@
+package refreshtoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("refreshtoken")
+
+const Tablename = "refresh_tokens"
+
+// Entry defines an entry in the refresh tokens table.
+// Every refresh token issued by renewing belongs to the family of the token it replaced.
+type Entry struct {
+	ID        uuid.UUID  `db:"id"`
+	FamilyID  uuid.UUID  `db:"family_id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("family_id", x.FamilyID.String()),
+		attribute.String("user_id", x.UserID.String()),
+	}
+}
+
+// Insert a new refresh token entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO refresh_tokens (id, family_id, user_id, token_hash, expires_at, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.FamilyID,
+		params.UserID,
+		params.TokenHash,
+		params.ExpiresAt,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "refresh token")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadByHash reads a refresh token [Entry] by its hash.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByHash(ctx context.Context, db database.Querier, hash string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByHash")
+	defer span.End()
+
+	if hash == "" {
+		return nil, database.NewInputError(ctx, nil, "token_hash", hash)
+	}
+
+	query := `
+        SELECT id, family_id, user_id, token_hash, expires_at, created_at, used_at, revoked_at
+        FROM refresh_tokens
+        WHERE token_hash = $1
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, hash).Scan(
+		&entry.ID,
+		&entry.FamilyID,
+		&entry.UserID,
+		&entry.TokenHash,
+		&entry.ExpiresAt,
+		&entry.CreatedAt,
+		&entry.UsedAt,
+		&entry.RevokedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "refresh token", "hash")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// ReadByHashForUpdate reads a refresh token [Entry] by its hash and locks the row
+// until the surrounding transaction ends, so a token can not be renewed twice concurrently.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByHashForUpdate(ctx context.Context, tx *sql.Tx, hash string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByHashForUpdate")
+	defer span.End()
+
+	if hash == "" {
+		return nil, database.NewInputError(ctx, nil, "token_hash", hash)
+	}
+
+	query := `
+        SELECT id, family_id, user_id, token_hash, expires_at, created_at, used_at, revoked_at
+        FROM refresh_tokens
+        WHERE token_hash = $1
+        FOR UPDATE
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := tx.QueryRowContext(ctx, query, hash).Scan(
+		&entry.ID,
+		&entry.FamilyID,
+		&entry.UserID,
+		&entry.TokenHash,
+		&entry.ExpiresAt,
+		&entry.CreatedAt,
+		&entry.UsedAt,
+		&entry.RevokedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "refresh token", "hash")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// MarkUsed marks a refresh token [Entry] as used by a renewal.
+// Returns [database.RowsAffectedError] if the entry does not exist or is already used,
+// otherwise [database.OperationFailedError].
+func MarkUsed(ctx context.Context, db database.Querier, id uuid.UUID, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "MarkUsed")
+	defer span.End()
+
+	query := `
+        UPDATE refresh_tokens
+        SET used_at = $1
+        WHERE id = $2 AND used_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes every refresh token of a family that is not already revoked.
+// Returns the amount of revoked tokens or [database.OperationFailedError].
+func RevokeFamily(ctx context.Context, db database.Querier, familyID uuid.UUID, at time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RevokeFamily")
+	defer span.End()
+
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = $1
+        WHERE family_id = $2 AND revoked_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("family_id", familyID.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, familyID)
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	return rowsAffected, nil
+}
+
+// RevokeFamilyByHash revokes every refresh token in the family of the token with the given hash.
+// Returns the amount of revoked tokens or [database.OperationFailedError].
+func RevokeFamilyByHash(ctx context.Context, db database.Querier, hash string, at time.Time) (int64, error) {
+	ctx, span := tracer.Start(ctx, "RevokeFamilyByHash")
+	defer span.End()
+
+	query := `
+        UPDATE refresh_tokens
+        SET revoked_at = $1
+        WHERE revoked_at IS NULL AND family_id = (
+            SELECT family_id FROM refresh_tokens WHERE token_hash = $2
+        )
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(ctx, query, at, hash)
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	return rowsAffected, nil
+}
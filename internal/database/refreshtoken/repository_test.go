@@ -0,0 +1,164 @@
+//go:build testdb
+// +build testdb
+
+package refreshtoken_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/refreshtoken"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams(familyID uuid.UUID) refreshtoken.InsertParams {
+	return refreshtoken.InsertParams{
+		ID:        uuid.New(),
+		FamilyID:  familyID,
+		UserID:    uuid.New(),
+		TokenHash: random.String(64),
+		ExpiresAt: time.Now().Add(time.Hour).UTC(),
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams(uuid.New())
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, refreshtoken.Insert(ctx, db, params))
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		got, err := refreshtoken.ReadByHashForUpdate(ctx, tx, params.TokenHash)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.Equal(t, params.FamilyID, got.FamilyID)
+		require.Equal(t, params.UserID, got.UserID)
+		require.Nil(t, got.UsedAt)
+		require.Nil(t, got.RevokedAt)
+	})
+
+	t.Run("duplicate hash returns error", func(t *testing.T) {
+		dup := randomParams(uuid.New())
+		dup.TokenHash = params.TokenHash
+		err := refreshtoken.Insert(ctx, db, dup)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+}
+
+func TestReadByHash(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams(uuid.New())
+	require.NoError(t, refreshtoken.Insert(ctx, db, params))
+
+	t.Run("ok", func(t *testing.T) {
+		got, err := refreshtoken.ReadByHash(ctx, db, params.TokenHash)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.Equal(t, params.FamilyID, got.FamilyID)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := refreshtoken.ReadByHash(ctx, db, random.String(64))
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestReadByHashForUpdate(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback() //nolint:errcheck
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := refreshtoken.ReadByHashForUpdate(ctx, tx, random.String(64))
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestMarkUsed(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams(uuid.New())
+	require.NoError(t, refreshtoken.Insert(ctx, db, params))
+
+	require.NoError(t, refreshtoken.MarkUsed(ctx, db, params.ID, time.Now()))
+
+	t.Run("already used", func(t *testing.T) {
+		err := refreshtoken.MarkUsed(ctx, db, params.ID, time.Now())
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+}
+
+func TestRevokeFamily(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	familyID := uuid.New()
+	for range 3 {
+		require.NoError(t, refreshtoken.Insert(ctx, db, randomParams(familyID)))
+	}
+	other := randomParams(uuid.New())
+	require.NoError(t, refreshtoken.Insert(ctx, db, other))
+
+	n, err := refreshtoken.RevokeFamily(ctx, db, familyID, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), n)
+
+	n, err = refreshtoken.RevokeFamily(ctx, db, familyID, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(0), n)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback() //nolint:errcheck
+
+	got, err := refreshtoken.ReadByHashForUpdate(ctx, tx, other.TokenHash)
+	require.NoError(t, err)
+	require.Nil(t, got.RevokedAt)
+}
+
+func TestRevokeFamilyByHash(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	familyID := uuid.New()
+	params := randomParams(familyID)
+	require.NoError(t, refreshtoken.Insert(ctx, db, params))
+	require.NoError(t, refreshtoken.Insert(ctx, db, randomParams(familyID)))
+
+	n, err := refreshtoken.RevokeFamilyByHash(ctx, db, params.TokenHash, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	t.Run("unknown hash", func(t *testing.T) {
+		n, err := refreshtoken.RevokeFamilyByHash(ctx, db, random.String(64), time.Now())
+		require.NoError(t, err)
+		require.Equal(t, int64(0), n)
+	})
+}
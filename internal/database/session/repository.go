@@ -0,0 +1,279 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("session")
+
+const Tablename = "sessions"
+
+// Entry defines an entry in the sessions table.
+// A session is started by every authentication and its refresh token family has its ID.
+type Entry struct {
+	ID         uuid.UUID `db:"id"`
+	UserID     uuid.UUID `db:"user_id"`
+	Strategy   string    `db:"strategy"`
+	UserAgent  string    `db:"user_agent"`
+	ClientIP   string    `db:"client_ip"`
+	RememberMe bool      `db:"remember_me"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+	// ExpiresAt is when the session times out unless it is renewed, it moves with every renewal.
+	ExpiresAt time.Time `db:"expires_at"`
+	// AbsoluteExpiresAt is when the session ends however often it is renewed, nil if never.
+	AbsoluteExpiresAt *time.Time `db:"absolute_expires_at"`
+	RevokedAt         *time.Time `db:"revoked_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Strategy   string
+	UserAgent  string
+	ClientIP   string
+	RememberMe bool
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	// AbsoluteExpiresAt is optional.
+	AbsoluteExpiresAt *time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("user_id", x.UserID.String()),
+		attribute.String("strategy", x.Strategy),
+	}
+}
+
+const columns = `id, user_id, strategy, user_agent, client_ip, remember_me,
+        created_at, last_seen_at, expires_at, absolute_expires_at, revoked_at`
+
+// Insert a new session entry, last seen when it is created.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO sessions (
+        id, user_id, strategy, user_agent, client_ip, remember_me,
+        created_at, last_seen_at, expires_at, absolute_expires_at
+    )
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8, $9)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.UserID,
+		params.Strategy,
+		params.UserAgent,
+		params.ClientIP,
+		params.RememberMe,
+		params.CreatedAt,
+		params.ExpiresAt,
+		params.AbsoluteExpiresAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "session")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// Read reads a session [Entry] by its ID.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func Read(ctx context.Context, db database.Querier, id uuid.UUID) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "Read")
+	defer span.End()
+
+	query := `
+        SELECT ` + columns + `
+        FROM sessions
+        WHERE id = $1
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	entry, err := scan(db.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "session", "id")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entry, nil
+}
+
+// ListActive lists the sessions of a user that are neither revoked nor expired at now,
+// most recently seen first. Returns [database.OperationFailedError] on failure.
+func ListActive(ctx context.Context, db database.Querier, userID uuid.UUID, now time.Time) ([]Entry, error) {
+	ctx, span := tracer.Start(ctx, "ListActive")
+	defer span.End()
+
+	query := `
+        SELECT ` + columns + `
+        FROM sessions
+        WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+        ORDER BY last_seen_at DESC
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	rows, err := db.QueryContext(ctx, query, userID, now)
+	if err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	entries, err := scanAll(rows)
+	if err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entries, nil
+}
+
+// Touch marks a session as seen at the given time and moves its expiry,
+// done whenever its refresh token is renewed.
+// Refresh token families issued before sessions existed have no session, so touching
+// an unknown session is not an error. Returns [database.OperationFailedError] on failure.
+func Touch(ctx context.Context, db database.Querier, id uuid.UUID, at, expiresAt time.Time) error {
+	ctx, span := tracer.Start(ctx, "Touch")
+	defer span.End()
+
+	query := `
+        UPDATE sessions
+        SET last_seen_at = $1, expires_at = $2
+        WHERE id = $3
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	if _, err := db.ExecContext(ctx, query, at, expiresAt, id); err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+
+	return nil
+}
+
+// Revoke revokes a session of a user, unless it is already revoked.
+// Returns the revoked [Entry], [database.NotFoundError] if the user has no such session
+// to revoke, otherwise [database.OperationFailedError].
+func Revoke(ctx context.Context, db database.Querier, id, userID uuid.UUID, at time.Time) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "Revoke")
+	defer span.End()
+
+	query := `
+        UPDATE sessions
+        SET revoked_at = $1
+        WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL
+        RETURNING ` + columns
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	entry, err := scan(db.QueryRowContext(ctx, query, at, id, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "session", "id")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entry, nil
+}
+
+// RevokeByUser revokes every session of a user that is not already revoked, except the given one,
+// which can be [uuid.Nil]. Returns the revoked entries or [database.OperationFailedError].
+func RevokeByUser(ctx context.Context, db database.Querier, userID, except uuid.UUID, at time.Time) ([]Entry, error) {
+	ctx, span := tracer.Start(ctx, "RevokeByUser")
+	defer span.End()
+
+	query := `
+        UPDATE sessions
+        SET revoked_at = $1
+        WHERE user_id = $2 AND id <> $3 AND revoked_at IS NULL
+        RETURNING ` + columns
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	rows, err := db.QueryContext(ctx, query, at, userID, except)
+	if err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+	entries, err := scanAll(rows)
+	if err != nil {
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return entries, nil
+}
+
+func scan(row interface{ Scan(...any) error }) (*Entry, error) {
+	var entry Entry
+	if err := row.Scan(
+		&entry.ID,
+		&entry.UserID,
+		&entry.Strategy,
+		&entry.UserAgent,
+		&entry.ClientIP,
+		&entry.RememberMe,
+		&entry.CreatedAt,
+		&entry.LastSeenAt,
+		&entry.ExpiresAt,
+		&entry.AbsoluteExpiresAt,
+		&entry.RevokedAt,
+	); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+func scanAll(rows *sql.Rows) ([]Entry, error) {
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		entry, err := scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, *entry)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,154 @@
+//go:build testdb
+// +build testdb
+
+package session_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/session"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams(userID uuid.UUID) session.InsertParams {
+	absolute := time.Now().Add(24 * time.Hour).UTC().Truncate(time.Second)
+	return session.InsertParams{
+		ID:                uuid.New(),
+		UserID:            userID,
+		Strategy:          "Credentials",
+		UserAgent:         "test-agent",
+		ClientIP:          "127.0.0.1:1234",
+		CreatedAt:         time.Now().UTC().Truncate(time.Second),
+		ExpiresAt:         time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+		RememberMe:        true,
+		AbsoluteExpiresAt: &absolute,
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams(uuid.New())
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, session.Insert(ctx, db, params))
+
+		got, err := session.Read(ctx, db, params.ID)
+		require.NoError(t, err)
+		require.Equal(t, params.UserID, got.UserID)
+		require.Equal(t, params.Strategy, got.Strategy)
+		require.Equal(t, params.UserAgent, got.UserAgent)
+		require.Equal(t, params.ClientIP, got.ClientIP)
+		require.True(t, params.CreatedAt.Equal(got.LastSeenAt))
+		require.True(t, params.ExpiresAt.Equal(got.ExpiresAt))
+		require.True(t, got.RememberMe)
+		require.NotNil(t, got.AbsoluteExpiresAt)
+		require.True(t, params.AbsoluteExpiresAt.Equal(*got.AbsoluteExpiresAt))
+		require.Nil(t, got.RevokedAt)
+	})
+
+	t.Run("without absolute timeout", func(t *testing.T) {
+		p := randomParams(uuid.New())
+		p.AbsoluteExpiresAt = nil
+		require.NoError(t, session.Insert(ctx, db, p))
+
+		got, err := session.Read(ctx, db, p.ID)
+		require.NoError(t, err)
+		require.Nil(t, got.AbsoluteExpiresAt)
+	})
+
+	t.Run("duplicate ID returns error", func(t *testing.T) {
+		err := session.Insert(ctx, db, params)
+		require.Error(t, err)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := session.Read(ctx, db, uuid.New())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestListActive(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	older, newer, expired := randomParams(userID), randomParams(userID), randomParams(userID)
+	expired.ExpiresAt = time.Now().Add(-time.Minute)
+	for _, p := range []session.InsertParams{older, newer, expired, randomParams(uuid.New())} {
+		require.NoError(t, session.Insert(ctx, db, p))
+	}
+	require.NoError(t, session.Touch(ctx, db, newer.ID, time.Now().Add(time.Minute), newer.ExpiresAt))
+
+	entries, err := session.ListActive(ctx, db, userID, time.Now())
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	require.Equal(t, newer.ID, entries[0].ID)
+	require.Equal(t, older.ID, entries[1].ID)
+
+	_, err = session.Revoke(ctx, db, older.ID, userID, time.Now())
+	require.NoError(t, err)
+	entries, err = session.ListActive(ctx, db, userID, time.Now())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestRevoke(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams(uuid.New())
+	require.NoError(t, session.Insert(ctx, db, params))
+
+	t.Run("other user", func(t *testing.T) {
+		_, err := session.Revoke(ctx, db, params.ID, uuid.New(), time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("ok", func(t *testing.T) {
+		entry, err := session.Revoke(ctx, db, params.ID, params.UserID, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, params.ID, entry.ID)
+		require.NotNil(t, entry.RevokedAt)
+	})
+
+	t.Run("already revoked", func(t *testing.T) {
+		_, err := session.Revoke(ctx, db, params.ID, params.UserID, time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestRevokeByUser(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	current, other, another := randomParams(userID), randomParams(userID), randomParams(uuid.New())
+	for _, p := range []session.InsertParams{current, other, another} {
+		require.NoError(t, session.Insert(ctx, db, p))
+	}
+
+	entries, err := session.RevokeByUser(ctx, db, userID, current.ID, time.Now())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, other.ID, entries[0].ID)
+
+	entries, err = session.RevokeByUser(ctx, db, userID, uuid.Nil, time.Now())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	require.Equal(t, current.ID, entries[0].ID)
+
+	got, err := session.Read(ctx, db, another.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.RevokedAt)
+}
@@ -0,0 +1,157 @@
+package totp
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+var tracer = otel.Tracer("totp")
+
+const Tablename = "totp_secrets"
+
+// Entry defines an entry in the TOTP secrets table, there is at most one per user.
+type Entry struct {
+	UserID uuid.UUID `db:"user_id"`
+	// Secret is encrypted, it is never stored in plain text.
+	Secret []byte `db:"secret"`
+	// LastUsedStep is the time step of the last accepted code, a code is only accepted once.
+	LastUsedStep int64      `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+}
+
+// Upsert inserts a new unconfirmed secret for a user, replacing an unconfirmed one.
+// Returns [database.DuplicateEntryError] if the user already has a confirmed secret,
+// otherwise [database.OperationFailedError].
+func Upsert(ctx context.Context, db database.Querier, userID uuid.UUID, secret []byte, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "Upsert")
+	defer span.End()
+
+	query := `
+    INSERT INTO totp_secrets (user_id, secret, created_at)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (user_id) DO UPDATE
+    SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+    WHERE totp_secrets.confirmed_at IS NULL
+    `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, userID, secret, at)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewDuplicateEntryError(ctx, errors.New("totp: secret already confirmed"), "totp secret")
+	}
+
+	return nil
+}
+
+// Read reads the TOTP secret [Entry] of a user.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func Read(ctx context.Context, db database.Querier, userID uuid.UUID) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "Read")
+	defer span.End()
+
+	query := `
+        SELECT user_id, secret, last_used_step, created_at, confirmed_at
+        FROM totp_secrets
+        WHERE user_id = $1
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, userID).Scan(
+		&entry.UserID,
+		&entry.Secret,
+		&entry.LastUsedStep,
+		&entry.CreatedAt,
+		&entry.ConfirmedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "totp secret", "user_id")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// UseStep records the time step of an accepted code and confirms the secret if it is not yet.
+// Returns [database.RowsAffectedError] if the step is not after the last used one,
+// which means the code was already used, otherwise [database.OperationFailedError].
+func UseStep(ctx context.Context, db database.Querier, userID uuid.UUID, step int64, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "UseStep")
+	defer span.End()
+
+	query := `
+        UPDATE totp_secrets
+        SET last_used_step = $1, confirmed_at = COALESCE(confirmed_at, $2)
+        WHERE user_id = $3 AND last_used_step < $1
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, step, at, userID)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// Delete deletes the TOTP secret of a user.
+// Returns [database.RowsAffectedError] if there is none, otherwise [database.OperationFailedError].
+func Delete(ctx context.Context, db database.Querier, userID uuid.UUID) error {
+	ctx, span := tracer.Start(ctx, "Delete")
+	defer span.End()
+
+	query := `
+        DELETE FROM totp_secrets
+        WHERE user_id = $1
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, userID)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
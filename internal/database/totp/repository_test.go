@@ -0,0 +1,104 @@
+//go:build testdb
+// +build testdb
+
+package totp_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/totp"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, totp.Upsert(ctx, db, userID, []byte("secret"), now))
+
+		got, err := totp.Read(ctx, db, userID)
+		require.NoError(t, err)
+		require.Equal(t, []byte("secret"), got.Secret)
+		require.Zero(t, got.LastUsedStep)
+		require.Nil(t, got.ConfirmedAt)
+	})
+
+	t.Run("replaces unconfirmed secret", func(t *testing.T) {
+		require.NoError(t, totp.Upsert(ctx, db, userID, []byte("other"), now))
+
+		got, err := totp.Read(ctx, db, userID)
+		require.NoError(t, err)
+		require.Equal(t, []byte("other"), got.Secret)
+	})
+
+	t.Run("confirmed secret returns error", func(t *testing.T) {
+		require.NoError(t, totp.UseStep(ctx, db, userID, 1, now))
+
+		err := totp.Upsert(ctx, db, userID, []byte("third"), now)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		_, err := totp.Read(ctx, db, uuid.New())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestUseStep(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, totp.Upsert(ctx, db, userID, []byte("secret"), now))
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, totp.UseStep(ctx, db, userID, 10, now))
+
+		got, err := totp.Read(ctx, db, userID)
+		require.NoError(t, err)
+		require.Equal(t, int64(10), got.LastUsedStep)
+		require.NotNil(t, got.ConfirmedAt)
+	})
+
+	t.Run("replayed step returns error", func(t *testing.T) {
+		err := totp.UseStep(ctx, db, userID, 10, now)
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+
+	t.Run("older step returns error", func(t *testing.T) {
+		err := totp.UseStep(ctx, db, userID, 9, now)
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	userID := uuid.New()
+	require.NoError(t, totp.Upsert(ctx, db, userID, []byte("secret"), time.Now()))
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, totp.Delete(ctx, db, userID))
+
+		_, err := totp.Read(ctx, db, userID)
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		err := totp.Delete(ctx, db, userID)
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+}
@@ -0,0 +1,228 @@
+package verificationtoken
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("verificationtoken")
+
+const Tablename = "verification_tokens"
+
+const (
+	// PurposeEmailVerification tokens verify the email of a credentials entry.
+	PurposeEmailVerification = "email_verification"
+	// PurposePasswordReset tokens reset the password of a credentials entry.
+	PurposePasswordReset = "password_reset"
+	// PurposeEmailChange tokens change the email of a credentials entry to their email.
+	PurposeEmailChange = "email_change"
+	// PurposeEmailRevert tokens change the email of a credentials entry back to their email.
+	PurposeEmailRevert = "email_revert"
+)
+
+// Entry defines an entry in the verification tokens table.
+// A token is emailed to a user to prove they own the email, only its hash is stored.
+type Entry struct {
+	ID        uuid.UUID  `db:"id"`
+	TokenHash string     `db:"token_hash"`
+	UserID    uuid.UUID  `db:"user_id"`
+	Purpose   string     `db:"purpose"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	CreatedAt time.Time  `db:"created_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	// Email is the address the token changes the email to, nil unless it is for an email change or revert.
+	Email *string `db:"email"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID        uuid.UUID
+	TokenHash string
+	UserID    uuid.UUID
+	Purpose   string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	Email     *string
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("user_id", x.UserID.String()),
+		attribute.String("purpose", x.Purpose),
+	}
+}
+
+// Insert a new verification token entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO verification_tokens (id, token_hash, user_id, purpose, expires_at, created_at, email)
+    VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.TokenHash,
+		params.UserID,
+		params.Purpose,
+		params.ExpiresAt,
+		params.CreatedAt,
+		params.Email,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "verification token")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// ReadByHashForUpdate reads a verification token [Entry] by its hash and locks the row
+// until the surrounding transaction ends, so a token is used once.
+// Returns [database.NotFoundError] if entry is not found, otherwise [database.OperationFailedError].
+func ReadByHashForUpdate(ctx context.Context, tx *sql.Tx, hash string) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "ReadByHashForUpdate")
+	defer span.End()
+
+	if hash == "" {
+		return nil, database.NewInputError(ctx, nil, "token_hash", hash)
+	}
+
+	query := `
+        SELECT id, token_hash, user_id, purpose, expires_at, created_at, used_at, email
+        FROM verification_tokens
+        WHERE token_hash = $1
+        FOR UPDATE
+        `
+	span.SetAttributes(attribute.String("query", query))
+
+	var entry Entry
+	if err := tx.QueryRowContext(ctx, query, hash).Scan(
+		&entry.ID,
+		&entry.TokenHash,
+		&entry.UserID,
+		&entry.Purpose,
+		&entry.ExpiresAt,
+		&entry.CreatedAt,
+		&entry.UsedAt,
+		&entry.Email,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "verification token", "hash")
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
+
+// MarkUsed marks a verification token as used.
+// Returns [database.RowsAffectedError] if the entry does not exist or is already used,
+// otherwise [database.OperationFailedError].
+func MarkUsed(ctx context.Context, db database.Querier, id uuid.UUID, at time.Time) error {
+	ctx, span := tracer.Start(ctx, "MarkUsed")
+	defer span.End()
+
+	query := `
+        UPDATE verification_tokens
+        SET used_at = $1
+        WHERE id = $2 AND used_at IS NULL
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, id)
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// CountSince counts the verification tokens of purpose handed out to a user since a point in time,
+// used or not. Returns [database.OperationFailedError] on failure.
+func CountSince(ctx context.Context, db database.Querier, userID uuid.UUID, purpose string, since time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "CountSince")
+	defer span.End()
+
+	query := `
+        SELECT count(*)
+        FROM verification_tokens
+        WHERE user_id = $1 AND purpose = $2 AND created_at > $3
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("purpose", purpose),
+		attribute.String("query", query),
+	)
+
+	var count int
+	if err := db.QueryRowContext(ctx, query, userID, purpose, since).Scan(&count); err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	return count, nil
+}
+
+// ExpireUnused expires the unused verification tokens of purpose handed out to a user, at a point in time.
+// Returns the number of expired tokens or [database.OperationFailedError].
+func ExpireUnused(ctx context.Context, db database.Querier, userID uuid.UUID, purpose string, at time.Time) (int, error) {
+	ctx, span := tracer.Start(ctx, "ExpireUnused")
+	defer span.End()
+
+	query := `
+        UPDATE verification_tokens
+        SET expires_at = $1
+        WHERE user_id = $2 AND purpose = $3 AND used_at IS NULL AND expires_at > $1
+        `
+	span.SetAttributes(
+		attribute.String("user_id", userID.String()),
+		attribute.String("purpose", purpose),
+		attribute.String("query", query),
+	)
+
+	res, err := db.ExecContext(ctx, query, at, userID, purpose)
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return 0, database.NewOperationFailedError(ctx, err)
+	}
+
+	return int(rowsAffected), nil
+}
@@ -0,0 +1,168 @@
+//go:build testdb
+// +build testdb
+
+package verificationtoken_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/verificationtoken"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams() verificationtoken.InsertParams {
+	return verificationtoken.InsertParams{
+		ID:        uuid.New(),
+		TokenHash: uuid.NewString(),
+		UserID:    uuid.New(),
+		Purpose:   verificationtoken.PurposeEmailVerification,
+		ExpiresAt: time.Now().Add(time.Hour).UTC().Truncate(time.Second),
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+
+	t.Run("ok", func(t *testing.T) {
+		require.NoError(t, verificationtoken.Insert(ctx, db, params))
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		got, err := verificationtoken.ReadByHashForUpdate(ctx, tx, params.TokenHash)
+		require.NoError(t, err)
+		require.Equal(t, params.ID, got.ID)
+		require.Equal(t, params.UserID, got.UserID)
+		require.Equal(t, params.Purpose, got.Purpose)
+		require.True(t, params.ExpiresAt.Equal(got.ExpiresAt))
+		require.Nil(t, got.UsedAt)
+		require.Nil(t, got.Email)
+	})
+
+	t.Run("with email", func(t *testing.T) {
+		address := "new@email.com"
+		p := randomParams()
+		p.Purpose = verificationtoken.PurposeEmailChange
+		p.Email = &address
+		require.NoError(t, verificationtoken.Insert(ctx, db, p))
+
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		got, err := verificationtoken.ReadByHashForUpdate(ctx, tx, p.TokenHash)
+		require.NoError(t, err)
+		require.NotNil(t, got.Email)
+		require.Equal(t, address, *got.Email)
+	})
+
+	t.Run("duplicate hash returns error", func(t *testing.T) {
+		p := randomParams()
+		p.TokenHash = params.TokenHash
+		err := verificationtoken.Insert(ctx, db, p)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		tx, err := db.BeginTx(ctx, nil)
+		require.NoError(t, err)
+		defer tx.Rollback() //nolint:errcheck
+
+		_, err = verificationtoken.ReadByHashForUpdate(ctx, tx, "unknown")
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+}
+
+func TestMarkUsed(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	require.NoError(t, verificationtoken.Insert(ctx, db, params))
+	require.NoError(t, verificationtoken.MarkUsed(ctx, db, params.ID, time.Now()))
+
+	t.Run("already used returns error", func(t *testing.T) {
+		err := verificationtoken.MarkUsed(ctx, db, params.ID, time.Now())
+		require.ErrorAs(t, err, &database.RowsAffectedError{})
+	})
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	defer tx.Rollback() //nolint:errcheck
+
+	got, err := verificationtoken.ReadByHashForUpdate(ctx, tx, params.TokenHash)
+	require.NoError(t, err)
+	require.NotNil(t, got.UsedAt)
+}
+
+func TestCountSince(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	params.CreatedAt = time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+	require.NoError(t, verificationtoken.Insert(ctx, db, params))
+	p := randomParams()
+	p.UserID = params.UserID
+	require.NoError(t, verificationtoken.Insert(ctx, db, p))
+
+	count, err := verificationtoken.CountSince(ctx, db, params.UserID, params.Purpose, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	count, err = verificationtoken.CountSince(ctx, db, params.UserID, params.Purpose, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = verificationtoken.CountSince(ctx, db, uuid.New(), params.Purpose, time.Now().Add(-2*time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
+
+func TestExpireUnused(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	params := randomParams()
+	require.NoError(t, verificationtoken.Insert(ctx, db, params))
+	used := randomParams()
+	used.UserID = params.UserID
+	used.Purpose = params.Purpose
+	require.NoError(t, verificationtoken.Insert(ctx, db, used))
+	require.NoError(t, verificationtoken.MarkUsed(ctx, db, used.ID, time.Now()))
+	other := randomParams()
+	other.UserID = params.UserID
+	other.Purpose = verificationtoken.PurposeEmailRevert
+	require.NoError(t, verificationtoken.Insert(ctx, db, other))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	expired, err := verificationtoken.ExpireUnused(ctx, tx, params.UserID, params.Purpose, now)
+	require.NoError(t, err)
+	require.Equal(t, 1, expired)
+
+	got, err := verificationtoken.ReadByHashForUpdate(ctx, tx, params.TokenHash)
+	require.NoError(t, err)
+	require.False(t, got.ExpiresAt.After(now))
+	got, err = verificationtoken.ReadByHashForUpdate(ctx, tx, used.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, used.ExpiresAt.Unix(), got.ExpiresAt.Unix(), "a used token is kept")
+	got, err = verificationtoken.ReadByHashForUpdate(ctx, tx, other.TokenHash)
+	require.NoError(t, err)
+	require.Equal(t, other.ExpiresAt.Unix(), got.ExpiresAt.Unix(), "another purpose is kept")
+	require.NoError(t, tx.Commit())
+}
@@ -0,0 +1,142 @@
+package webauthnchallenge
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("webauthnchallenge")
+
+const Tablename = "webauthn_challenges"
+
+// Ceremonies a challenge is handed out for.
+const (
+	CeremonyRegistration   = "registration"
+	CeremonyAuthentication = "authentication"
+)
+
+// Entry defines an entry in the WebAuthn challenges table.
+// It keeps the state of a WebAuthn ceremony between its begin and finish.
+type Entry struct {
+	ID       uuid.UUID `db:"id"`
+	Ceremony string    `db:"ceremony"`
+	// UserID is the user the ceremony is for, nil for authentications with a discoverable credential.
+	UserID *uuid.UUID `db:"user_id"`
+	// Name and DisplayName are chosen by a registering user.
+	Name        string     `db:"name"`
+	DisplayName string     `db:"display_name"`
+	SessionData []byte     `db:"session_data"`
+	ExpiresAt   time.Time  `db:"expires_at"`
+	CreatedAt   time.Time  `db:"created_at"`
+	UsedAt      *time.Time `db:"used_at"`
+}
+
+// InsertParams defines the parameters for inserts.
+type InsertParams struct {
+	ID          uuid.UUID
+	Ceremony    string
+	UserID      *uuid.UUID
+	Name        string
+	DisplayName string
+	SessionData []byte
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+func (x InsertParams) SpanAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("id", x.ID.String()),
+		attribute.String("ceremony", x.Ceremony),
+	}
+}
+
+// Insert a new WebAuthn challenge entry.
+// Returns [database.DuplicateEntryError] on duplicate entry,
+// [database.RowsAffectedError] or [database.OperationFailedError].
+func Insert(ctx context.Context, db database.Querier, params InsertParams) error {
+	ctx, span := tracer.Start(ctx, "Insert", trace.WithAttributes(params.SpanAttributes()...))
+	defer span.End()
+
+	query := `
+    INSERT INTO webauthn_challenges (id, ceremony, user_id, name, display_name, session_data, expires_at, created_at)
+    VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	span.SetAttributes(attribute.String("query", query))
+
+	res, err := db.ExecContext(
+		ctx,
+		query,
+		params.ID,
+		params.Ceremony,
+		params.UserID,
+		params.Name,
+		params.DisplayName,
+		params.SessionData,
+		params.ExpiresAt,
+		params.CreatedAt,
+	)
+	if err != nil {
+		if database.IsPSQLDuplicateEntryError(err) {
+			return database.NewDuplicateEntryError(ctx, err, "webauthn challenge")
+		}
+		return database.NewOperationFailedError(ctx, err)
+	}
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return database.NewOperationFailedError(ctx, err)
+	}
+	if rowsAffected != 1 {
+		return database.NewRowsAffectedError(ctx, database.ErrUnexpectedRowsAffectedError, 1, rowsAffected)
+	}
+
+	return nil
+}
+
+// Consume marks a challenge of the given ceremony as used and returns it.
+// A challenge can be consumed once and only before it expires.
+// Returns [database.NotFoundError] if there is no such challenge to consume,
+// otherwise [database.OperationFailedError].
+func Consume(ctx context.Context, db database.Querier, id uuid.UUID, ceremony string, at time.Time) (*Entry, error) {
+	ctx, span := tracer.Start(ctx, "Consume")
+	defer span.End()
+
+	query := `
+        UPDATE webauthn_challenges
+        SET used_at = $1
+        WHERE id = $2 AND ceremony = $3 AND used_at IS NULL AND expires_at > $1
+        RETURNING id, ceremony, user_id, name, display_name, session_data, expires_at, created_at, used_at
+        `
+	span.SetAttributes(
+		attribute.String("id", id.String()),
+		attribute.String("ceremony", ceremony),
+		attribute.String("query", query),
+	)
+
+	var entry Entry
+	if err := db.QueryRowContext(ctx, query, at, id, ceremony).Scan(
+		&entry.ID,
+		&entry.Ceremony,
+		&entry.UserID,
+		&entry.Name,
+		&entry.DisplayName,
+		&entry.SessionData,
+		&entry.ExpiresAt,
+		&entry.CreatedAt,
+		&entry.UsedAt,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, database.NewNotFoundError(ctx, err, "webauthn challenge", id.String())
+		}
+		return nil, database.NewOperationFailedError(ctx, err)
+	}
+
+	return &entry, nil
+}
@@ -0,0 +1,85 @@
+//go:build testdb
+// +build testdb
+
+package webauthnchallenge_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/webauthnchallenge"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func randomParams(ceremony string) webauthnchallenge.InsertParams {
+	userID := uuid.New()
+	return webauthnchallenge.InsertParams{
+		ID:          uuid.New(),
+		Ceremony:    ceremony,
+		UserID:      &userID,
+		Name:        "name",
+		DisplayName: "display name",
+		SessionData: []byte(`{"challenge":"abc"}`),
+		ExpiresAt:   time.Now().Add(time.Minute).UTC().Truncate(time.Second),
+		CreatedAt:   time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+func TestConsume(t *testing.T) {
+	ctx := context.Background()
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	t.Run("ok", func(t *testing.T) {
+		params := randomParams(webauthnchallenge.CeremonyRegistration)
+		require.NoError(t, webauthnchallenge.Insert(ctx, db, params))
+
+		got, err := webauthnchallenge.Consume(ctx, db, params.ID, webauthnchallenge.CeremonyRegistration, time.Now())
+		require.NoError(t, err)
+		require.Equal(t, params.UserID, got.UserID)
+		require.Equal(t, params.Name, got.Name)
+		require.Equal(t, params.DisplayName, got.DisplayName)
+		require.JSONEq(t, string(params.SessionData), string(got.SessionData))
+		require.NotNil(t, got.UsedAt)
+
+		_, err = webauthnchallenge.Consume(ctx, db, params.ID, webauthnchallenge.CeremonyRegistration, time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{}, "a challenge can be consumed once")
+	})
+
+	t.Run("without user", func(t *testing.T) {
+		params := randomParams(webauthnchallenge.CeremonyAuthentication)
+		params.UserID = nil
+		require.NoError(t, webauthnchallenge.Insert(ctx, db, params))
+
+		got, err := webauthnchallenge.Consume(ctx, db, params.ID, webauthnchallenge.CeremonyAuthentication, time.Now())
+		require.NoError(t, err)
+		require.Nil(t, got.UserID)
+	})
+
+	t.Run("other ceremony returns error", func(t *testing.T) {
+		params := randomParams(webauthnchallenge.CeremonyRegistration)
+		require.NoError(t, webauthnchallenge.Insert(ctx, db, params))
+
+		_, err := webauthnchallenge.Consume(ctx, db, params.ID, webauthnchallenge.CeremonyAuthentication, time.Now())
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("expired returns error", func(t *testing.T) {
+		params := randomParams(webauthnchallenge.CeremonyRegistration)
+		require.NoError(t, webauthnchallenge.Insert(ctx, db, params))
+
+		_, err := webauthnchallenge.Consume(ctx, db, params.ID, webauthnchallenge.CeremonyRegistration, params.ExpiresAt)
+		require.ErrorAs(t, err, &database.NotFoundError{})
+	})
+
+	t.Run("duplicate ID returns error", func(t *testing.T) {
+		params := randomParams(webauthnchallenge.CeremonyRegistration)
+		require.NoError(t, webauthnchallenge.Insert(ctx, db, params))
+
+		err := webauthnchallenge.Insert(ctx, db, params)
+		require.ErrorAs(t, err, &database.DuplicateEntryError{})
+	})
+}
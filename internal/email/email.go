@@ -18,9 +18,7 @@ const (
 	IngestedEvent = "email_ingested"
 
 	// TODO(kg): Remove this.
-	TestSubject = "You have requested to create an identity."
-	TestBody    = "Do the following step to verify your identity."
-	TestFrom    = "fugaziindustries@proton.me"
+	TestFrom = "fugaziindustries@proton.me"
 )
 
 type Email struct {
@@ -30,16 +28,25 @@ type Email struct {
 	From    string
 }
 
+// TraceAttributes leaves out the body, which can hold one time codes and links.
 func (x Email) TraceAttributes() []attribute.KeyValue {
 	return []attribute.KeyValue{
 		attribute.String("to", x.To),
 		attribute.String("subject", x.Subject),
-		attribute.String("Body", x.Body),
+		attribute.Int("body length", len(x.Body)),
 		attribute.String("From", x.From),
 	}
 }
 
-func Ingest(ctx context.Context, natsConn *nats.Conn, email Email) error {
+// Publisher publishes a message on a subject. [*nats.Conn] implements it.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+var _ Publisher = (*nats.Conn)(nil)
+
+// Ingest publishes an [Email] as an [IngestedEvent] to be sent by a worker.
+func Ingest(ctx context.Context, publisher Publisher, email Email) error {
 	_, span := tracer.Start(ctx, "Ingest", trace.WithAttributes(email.TraceAttributes()...))
 	defer span.End()
 
@@ -47,7 +54,7 @@ func Ingest(ctx context.Context, natsConn *nats.Conn, email Email) error {
 	if err := gob.NewEncoder(&buf).Encode(email); err != nil {
 		return err
 	}
-	if err := natsConn.Publish(IngestedEvent, buf.Bytes()); err != nil {
+	if err := publisher.Publish(IngestedEvent, buf.Bytes()); err != nil {
 		return err
 	}
 	return nil
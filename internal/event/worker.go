@@ -7,19 +7,23 @@ import (
 	"log/slog"
 
 	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/internal/sms"
 	"github.com/nats-io/nats.go"
 )
 
 type Worker struct {
 	mailSender email.Sender
+	smsSender  sms.Sender
 }
 
-func NewWorker(sender email.Sender) *Worker {
+func NewWorker(mailSender email.Sender, smsSender sms.Sender) *Worker {
 	return &Worker{
-		mailSender: sender,
+		mailSender: mailSender,
+		smsSender:  smsSender,
 	}
 }
 
+// Work sends the [email.IngestedEvent] and [sms.IngestedEvent] messages of natsCh until ctx is done.
 func (x *Worker) Work(ctx context.Context, natsCh chan *nats.Msg) {
 	for {
 		select {
@@ -27,6 +31,10 @@ func (x *Worker) Work(ctx context.Context, natsCh chan *nats.Msg) {
 			slog.InfoContext(ctx, "event: context done, shutting down worker...")
 			return
 		case msg := <-natsCh:
+			if msg.Subject == sms.IngestedEvent {
+				x.sendSMS(ctx, msg)
+				continue
+			}
 			var m email.Email
 			if err := gob.NewDecoder(bytes.NewReader(msg.Data)).Decode(&m); err != nil {
 				slog.WarnContext(ctx, "event: decoding message", "err", err)
@@ -43,3 +51,14 @@ func (x *Worker) Work(ctx context.Context, natsCh chan *nats.Msg) {
 		}
 	}
 }
+
+func (x *Worker) sendSMS(ctx context.Context, msg *nats.Msg) {
+	var m sms.SMS
+	if err := gob.NewDecoder(bytes.NewReader(msg.Data)).Decode(&m); err != nil {
+		slog.WarnContext(ctx, "event: decoding sms", "err", err)
+		return
+	}
+	if err := x.smsSender.SendSMS(ctx, m); err != nil {
+		slog.WarnContext(ctx, "event: sending sms", "err", err)
+	}
+}
@@ -0,0 +1,121 @@
+package interceptors
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/Salam4nder/identity/internal/token"
+	"go.opentelemetry.io/otel/attribute"
+	otelCode "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Authenticator verifies the bearer access token in the authorization metadata of every call
+// to a protected method and puts its [token.Claims] into the context, see [token.FromContext].
+// Every method is protected unless it is declared public.
+type Authenticator struct {
+	maker  token.Maker
+	public map[string]struct{}
+}
+
+// NewAuthenticator returns a new [Authenticator] that lets calls to the public methods through.
+// A public method is either a full method name, like "/gen.Identity/Register",
+// or every method of a service, like "/grpc.health.v1.Health/*".
+func NewAuthenticator(maker token.Maker, public ...string) *Authenticator {
+	m := make(map[string]struct{}, len(public))
+	for _, p := range public {
+		m[p] = struct{}{}
+	}
+	return &Authenticator{maker: maker, public: m}
+}
+
+// Unary returns a unary server interceptor that authenticates calls to protected methods.
+func (x *Authenticator) Unary() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := x.authenticate(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// Stream returns a stream server interceptor that authenticates calls to protected methods.
+func (x *Authenticator) Stream() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := x.authenticate(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate returns ctx with the claims of its access token,
+// or ctx as is if method is public. Returned errors are ready to be sent to the client.
+func (x *Authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	if x.isPublic(method) {
+		return ctx, nil
+	}
+	span := trace.SpanFromContext(ctx)
+
+	t, err := bearerToken(ctx)
+	if err != nil {
+		span.RecordError(err)
+		return nil, status.Error(codes.Unauthenticated, "missing access token")
+	}
+
+	claims, err := x.maker.Verify(ctx, t, token.TypeAccess)
+	if err != nil {
+		span.SetStatus(otelCode.Error, err.Error())
+		span.RecordError(err)
+		if errors.Is(err, token.ErrInvalid) || errors.Is(err, token.ErrRevoked) {
+			return nil, status.Error(codes.Unauthenticated, "invalid access token")
+		}
+		return nil, status.Error(codes.Internal, "internal server error, please provide the traceID to support")
+	}
+	span.SetAttributes(attribute.String("user_id", claims.Subject.String()))
+
+	return token.NewContext(ctx, claims), nil
+}
+
+func (x *Authenticator) isPublic(method string) bool {
+	if _, ok := x.public[method]; ok {
+		return true
+	}
+	if i := strings.LastIndex(method, "/"); i > 0 {
+		_, ok := x.public[method[:i+1]+"*"]
+		return ok
+	}
+	return false
+}
+
+// bearerToken returns the token of the bearer authorization metadata of ctx.
+func bearerToken(ctx context.Context) (token.SafeString, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return "", errors.New("interceptors: expected a single authorization metadata value")
+	}
+
+	const prefix = "bearer "
+	if len(values[0]) <= len(prefix) || !strings.EqualFold(values[0][:len(prefix)], prefix) {
+		return "", errors.New("interceptors: expected bearer authorization")
+	}
+	return token.SafeString(values[0][len(prefix):]), nil
+}
+
+// authenticatedStream overrides the context of a stream with the authenticated one.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (x *authenticatedStream) Context() context.Context {
+	return x.ctx
+}
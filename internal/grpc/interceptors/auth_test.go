@@ -0,0 +1,157 @@
+package interceptors
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestAuthenticator(t *testing.T) (*Authenticator, token.Maker) {
+	t.Helper()
+
+	ring, err := token.NewKeyRing(token.Key{ID: "test", Secret: []byte(random.String(token.KeySize))})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	maker, err := token.BootstrapPasetoMaker(
+		token.MakerOpts{AccessDuration: time.Minute, RefreshDuration: time.Hour},
+		ring,
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	return NewAuthenticator(maker, "/gen.Identity/Register", "/grpc.health.v1.Health/*"), maker
+}
+
+func withAuthorization(value string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", value))
+}
+
+func TestAuthenticatorUnary(t *testing.T) {
+	a, maker := newTestAuthenticator(t)
+	userID := uuid.New()
+	access, _, err := maker.MakeAccessToken(context.Background(), token.Params{Subject: userID})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	refresh, _, err := maker.MakeRefreshToken(context.Background(), token.Params{Subject: userID})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	call := func(ctx context.Context, method string) (token.Claims, bool, error) {
+		var (
+			claims token.Claims
+			ok     bool
+		)
+		_, err := a.Unary()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ any) (any, error) {
+			claims, ok = token.FromContext(ctx)
+			return nil, nil
+		})
+		return claims, ok, err
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		claims, ok, err := call(withAuthorization("Bearer "+string(access)), "/gen.Identity/Protected")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if !ok || claims.Subject != userID {
+			t.Errorf("expected claims of %s in context, got %v", userID, claims)
+		}
+	})
+
+	t.Run("public methods", func(t *testing.T) {
+		for _, method := range []string{"/gen.Identity/Register", "/grpc.health.v1.Health/Check"} {
+			_, ok, err := call(context.Background(), method)
+			if err != nil {
+				t.Errorf("expected no error calling %s, got %s", method, err.Error())
+			}
+			if ok {
+				t.Errorf("expected no claims calling %s", method)
+			}
+		}
+	})
+
+	t.Run("missing token", func(t *testing.T) {
+		_, _, err := call(context.Background(), "/gen.Identity/Protected")
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated, got %v", err)
+		}
+	})
+
+	t.Run("not a bearer token", func(t *testing.T) {
+		_, _, err := call(withAuthorization("Basic "+string(access)), "/gen.Identity/Protected")
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated, got %v", err)
+		}
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		_, _, err := call(withAuthorization("Bearer "+string(refresh)), "/gen.Identity/Protected")
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated, got %v", err)
+		}
+	})
+}
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (x testStream) Context() context.Context {
+	return x.ctx
+}
+
+func TestAuthenticatorStream(t *testing.T) {
+	a, maker := newTestAuthenticator(t)
+	userID := uuid.New()
+	access, _, err := maker.MakeAccessToken(context.Background(), token.Params{Subject: userID})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	call := func(ctx context.Context, method string) (token.Claims, bool, error) {
+		var (
+			claims token.Claims
+			ok     bool
+		)
+		err := a.Stream()(nil, testStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: method}, func(_ any, ss grpc.ServerStream) error {
+			claims, ok = token.FromContext(ss.Context())
+			return nil
+		})
+		return claims, ok, err
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		claims, ok, err := call(withAuthorization("bearer "+string(access)), "/gen.Identity/Watch")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if !ok || claims.Subject != userID {
+			t.Errorf("expected claims of %s in context, got %v", userID, claims)
+		}
+	})
+
+	t.Run("public service", func(t *testing.T) {
+		if _, _, err := call(context.Background(), "/grpc.health.v1.Health/Watch"); err != nil {
+			t.Errorf("expected no error, got %s", err.Error())
+		}
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, _, err := call(withAuthorization("Bearer ass"), "/gen.Identity/Watch")
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected Unauthenticated, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,152 @@
+//go:build testdb
+// +build testdb
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/protocol/webauthncbor"
+	"github.com/go-webauthn/webauthn/protocol/webauthncose"
+	"github.com/stretchr/testify/require"
+)
+
+// softAuthenticator is a passkey in memory, it answers WebAuthn options like a browser and authenticator would.
+type softAuthenticator struct {
+	rpID         string
+	origin       string
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newSoftAuthenticator(t *testing.T, rpID, origin string) *softAuthenticator {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	id := make([]byte, 16)
+	_, err = rand.Read(id)
+	require.NoError(t, err)
+
+	return &softAuthenticator{rpID: rpID, origin: origin, key: key, credentialID: id}
+}
+
+// create answers the options of navigator.credentials.create with a new passkey and "none" attestation.
+func (x *softAuthenticator) create(t *testing.T, options []byte) []byte {
+	t.Helper()
+
+	var creation protocol.CredentialCreation
+	require.NoError(t, json.Unmarshal(options, &creation))
+	userID, ok := creation.Response.User.ID.(string)
+	require.True(t, ok, "expected the user ID to be base64 encoded")
+	handle, err := base64.RawURLEncoding.DecodeString(userID)
+	require.NoError(t, err)
+	x.userHandle = handle
+
+	publicKey, err := webauthncbor.Marshal(webauthncose.EC2PublicKeyData{
+		PublicKeyData: webauthncose.PublicKeyData{
+			KeyType:   int64(webauthncose.EllipticKey),
+			Algorithm: int64(webauthncose.AlgES256),
+		},
+		Curve:  int64(webauthncose.P256),
+		XCoord: x.key.X.FillBytes(make([]byte, 32)),
+		YCoord: x.key.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+
+	authData := x.authData(protocol.FlagAttestedCredentialData)
+	authData = append(authData, make([]byte, 16)...)
+	authData = binary.BigEndian.AppendUint16(authData, uint16(len(x.credentialID)))
+	authData = append(authData, x.credentialID...)
+	authData = append(authData, publicKey...)
+
+	attestation, err := webauthncbor.Marshal(map[string]any{
+		"fmt":      "none",
+		"attStmt":  map[string]any{},
+		"authData": authData,
+	})
+	require.NoError(t, err)
+
+	res, err := json.Marshal(protocol.CredentialCreationResponse{
+		PublicKeyCredential: x.publicKeyCredential(),
+		AttestationResponse: protocol.AuthenticatorAttestationResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{
+				ClientDataJSON: x.clientData(t, protocol.CreateCeremony, creation.Response.Challenge),
+			},
+			AttestationObject: attestation,
+			Transports:        []string{string(protocol.Internal)},
+		},
+	})
+	require.NoError(t, err)
+	return res
+}
+
+// get answers the options of navigator.credentials.get with an assertion signed by the passkey.
+func (x *softAuthenticator) get(t *testing.T, options []byte) []byte {
+	t.Helper()
+
+	var assertion protocol.CredentialAssertion
+	require.NoError(t, json.Unmarshal(options, &assertion))
+
+	x.signCount++
+	authData := x.authData(0)
+	clientData := x.clientData(t, protocol.AssertCeremony, assertion.Response.Challenge)
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(authData, clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, x.key, digest[:])
+	require.NoError(t, err)
+
+	res, err := json.Marshal(protocol.CredentialAssertionResponse{
+		PublicKeyCredential: x.publicKeyCredential(),
+		AssertionResponse: protocol.AuthenticatorAssertionResponse{
+			AuthenticatorResponse: protocol.AuthenticatorResponse{ClientDataJSON: clientData},
+			AuthenticatorData:     authData,
+			Signature:             signature,
+			UserHandle:            x.userHandle,
+		},
+	})
+	require.NoError(t, err)
+	return res
+}
+
+func (x *softAuthenticator) authData(flags protocol.AuthenticatorFlags) []byte {
+	rpIDHash := sha256.Sum256([]byte(x.rpID))
+	data := append(rpIDHash[:], byte(protocol.FlagUserPresent|protocol.FlagUserVerified|flags))
+	return binary.BigEndian.AppendUint32(data, x.signCount)
+}
+
+func (x *softAuthenticator) clientData(
+	t *testing.T,
+	ceremony protocol.CeremonyType,
+	challenge protocol.URLEncodedBase64,
+) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(protocol.CollectedClientData{
+		Type:      ceremony,
+		Challenge: challenge.String(),
+		Origin:    x.origin,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func (x *softAuthenticator) publicKeyCredential() protocol.PublicKeyCredential {
+	return protocol.PublicKeyCredential{
+		Credential: protocol.Credential{
+			ID:   base64.RawURLEncoding.EncodeToString(x.credentialID),
+			Type: string(protocol.PublicKeyCredentialType),
+		},
+		RawID: x.credentialID,
+	}
+}
@@ -40,17 +40,38 @@ func alreadyExistsError(ctx context.Context, err error, msg string) error {
 	return status.Error(codes.AlreadyExists, msg)
 }
 
-// func unauthenticatedError(err error, span trace.Span, msg string) error {
-// 	span.SetStatus(otelCode.Error, err.Error())
-// 	span.RecordError(err)
-// 	return status.Error(codes.Unauthenticated, msg)
-// }
-
-// func notFoundError(ctx context.Context, err error, msg string) error {
-// 	if err != nil {
-// 		span := trace.SpanFromContext(ctx)
-// 		span.SetStatus(otelCode.Error, err.Error())
-// 		span.RecordError(err)
-// 	}
-// 	return status.Error(codes.NotFound, msg)
-// }
+func unauthenticatedError(ctx context.Context, err error, msg string) error {
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(otelCode.Error, err.Error())
+		span.RecordError(err)
+	}
+	return status.Error(codes.Unauthenticated, msg)
+}
+
+func failedPreconditionError(ctx context.Context, err error, msg string) error {
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(otelCode.Error, err.Error())
+		span.RecordError(err)
+	}
+	return status.Error(codes.FailedPrecondition, msg)
+}
+
+func notFoundError(ctx context.Context, err error, msg string) error {
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(otelCode.Error, err.Error())
+		span.RecordError(err)
+	}
+	return status.Error(codes.NotFound, msg)
+}
+
+func resourceExhaustedError(ctx context.Context, err error, msg string) error {
+	if err != nil {
+		span := trace.SpanFromContext(ctx)
+		span.SetStatus(otelCode.Error, err.Error())
+		span.RecordError(err)
+	}
+	return status.Error(codes.ResourceExhausted, msg)
+}
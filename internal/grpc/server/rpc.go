@@ -2,20 +2,37 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
-	"log/slog"
+	"strings"
 
-	"github.com/Salam4nder/identity/internal/auth/strategy"
+	"github.com/Salam4nder/identity/internal/auth"
 	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/credentials"
 	"github.com/Salam4nder/identity/internal/observability/metrics"
+	"github.com/Salam4nder/identity/internal/token"
+	grpcutil "github.com/Salam4nder/identity/pkg/grpc"
+	"github.com/Salam4nder/identity/pkg/password"
+	"github.com/Salam4nder/identity/pkg/validation"
 	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 var tracer = otel.Tracer("server")
 
+// tokenTypeHints maps the token type hints of RFC 7009 to [token.Type]s.
+var tokenTypeHints = map[string]token.Type{
+	"access_token":  token.TypeAccess,
+	"refresh_token": token.TypeRefresh,
+}
+
 func (x *Identity) Register(ctx context.Context, req *gen.Input) (*emptypb.Empty, error) {
 	ctx, span := tracer.Start(ctx, "Register")
 	defer span.End()
@@ -24,42 +41,862 @@ func (x *Identity) Register(ctx context.Context, req *gen.Input) (*emptypb.Empty
 		return nil, requestIsNilError()
 	}
 
-	if req.GetStrategy() != x.strategy.ConfiguredStrategy() {
-		return nil, invalidArgumentError(
-			ctx,
-			nil,
-			fmt.Sprintf("invalid strategy, expecting %s", x.strategy.ConfiguredStrategy()),
-		)
+	return x.register(ctx, req)
+}
+
+// register registers a user with the strategy of req.
+func (x *Identity) register(ctx context.Context, req *gen.Input) (*emptypb.Empty, error) {
+	strategy, input, err := x.strategyInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = strategy.Register(ctx, input); err != nil {
+		if errors.As(err, &database.DuplicateEntryError{}) {
+			return nil, alreadyExistsError(ctx, err, "provided credentials already exist")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, unauthenticatedError(ctx, err, "invalid credentials")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	metrics.UsersActive.Inc()
+	metrics.UsersRegistered.Inc()
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) Authenticate(ctx context.Context, req *gen.Input) (*gen.AuthenticateResponse, error) {
+	ctx, span := tracer.Start(ctx, "Authenticate")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+
+	return x.authenticate(ctx, req)
+}
+
+func (x *Identity) VerifyMFA(ctx context.Context, req *gen.VerifyMFARequest) (*gen.AuthenticateResponse, error) {
+	ctx, span := tracer.Start(ctx, "VerifyMFA")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetMfaChallenge() == "" || req.GetCode() == "" {
+		return nil, invalidArgumentError(ctx, nil, "mfa challenge and code are required")
+	}
+
+	params, err := x.mfa.Verify(ctx, token.SafeString(req.GetMfaChallenge()), req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidMFAChallenge) {
+			return nil, unauthenticatedError(ctx, err, "invalid mfa challenge")
+		}
+		if errors.Is(err, auth.ErrInvalidMFACode) || errors.Is(err, auth.ErrMFANotEnrolled) {
+			return nil, unauthenticatedError(ctx, err, "invalid mfa code")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	md := grpcutil.MetadataFromContext(ctx)
+	pair, err := x.tokens.Issue(ctx, auth.IssueParams{
+		UserID:     params.UserID,
+		Strategy:   params.Strategy,
+		UserAgent:  md.UserAgent,
+		ClientIP:   md.ClientIP,
+		RememberMe: params.RememberMe,
+	})
+	if err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	return authenticateResponse(pair), nil
+}
+
+func (x *Identity) Renew(ctx context.Context, req *gen.RenewRequest) (*gen.AuthenticateResponse, error) {
+	ctx, span := tracer.Start(ctx, "Renew")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetRefreshToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "refresh token is required")
+	}
+
+	pair, err := x.tokens.Renew(ctx, token.SafeString(req.GetRefreshToken()))
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+			return nil, unauthenticatedError(ctx, err, "invalid refresh token")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return authenticateResponse(pair), nil
+}
+
+func (x *Identity) Revoke(ctx context.Context, req *gen.RevokeRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "Revoke")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetAccessToken() == "" && req.GetRefreshToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "access token or refresh token is required")
+	}
+
+	if err := x.tokens.Revoke(
+		ctx,
+		token.SafeString(req.GetAccessToken()),
+		token.SafeString(req.GetRefreshToken()),
+	); err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) PublicKeys(ctx context.Context, _ *emptypb.Empty) (*gen.PublicKeysResponse, error) {
+	ctx, span := tracer.Start(ctx, "PublicKeys")
+	defer span.End()
+
+	keys, ok := x.tokens.PublicKeys()
+	if !ok {
+		return nil, failedPreconditionError(ctx, nil, "tokens are not signed with public keys")
+	}
+
+	res := &gen.PublicKeysResponse{Keys: make([]*gen.PublicKey, 0, len(keys))}
+	for _, k := range keys {
+		res.Keys = append(res.Keys, &gen.PublicKey{
+			KeyId:     k.ID,
+			Algorithm: k.Algorithm,
+			PublicKey: k.Key,
+		})
+	}
+
+	return res, nil
+}
+
+func (x *Identity) Introspect(ctx context.Context, req *gen.IntrospectRequest) (*gen.IntrospectResponse, error) {
+	ctx, span := tracer.Start(ctx, "Introspect")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if err := x.authenticateClient(ctx); err != nil {
+		return nil, unauthenticatedError(ctx, err, "invalid client credentials")
+	}
+	if req.GetToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "token is required")
+	}
+
+	claims, active, err := x.tokens.Introspect(
+		ctx,
+		token.SafeString(req.GetToken()),
+		tokenTypeHints[req.GetTokenTypeHint()],
+	)
+	if err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+	if !active {
+		return &gen.IntrospectResponse{}, nil
+	}
+
+	return &gen.IntrospectResponse{
+		Active:    true,
+		Scope:     strings.Join(claims.Scopes(), " "),
+		TokenType: string(claims.Type) + "_token",
+		Exp:       timestamppb.New(claims.ExpiresAt),
+		Iat:       timestamppb.New(claims.IssuedAt),
+		Sub:       claims.Subject.String(),
+		Aud:       claims.Audience,
+		Iss:       claims.Issuer,
+		Jti:       claims.ID,
+	}, nil
+}
+
+func (x *Identity) Sessions(ctx context.Context, _ *emptypb.Empty) (*gen.SessionsResponse, error) {
+	ctx, span := tracer.Start(ctx, "Sessions")
+	defer span.End()
+
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+
+	entries, err := x.tokens.Sessions(ctx, claims.Subject)
+	if err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	res := &gen.SessionsResponse{Sessions: make([]*gen.Session, 0, len(entries))}
+	for _, e := range entries {
+		s := &gen.Session{
+			Id:         e.ID.String(),
+			Strategy:   e.Strategy,
+			UserAgent:  e.UserAgent,
+			ClientIp:   e.ClientIP,
+			CreatedAt:  timestamppb.New(e.CreatedAt),
+			LastSeenAt: timestamppb.New(e.LastSeenAt),
+			ExpiresAt:  timestamppb.New(e.ExpiresAt),
+			Current:    e.ID == claims.SessionID(),
+			RememberMe: e.RememberMe,
+		}
+		if e.AbsoluteExpiresAt != nil {
+			s.AbsoluteExpiresAt = timestamppb.New(*e.AbsoluteExpiresAt)
+		}
+		res.Sessions = append(res.Sessions, s)
+	}
+
+	return res, nil
+}
+
+func (x *Identity) RevokeSession(ctx context.Context, req *gen.RevokeSessionRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "RevokeSession")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+	id, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, invalidArgumentError(ctx, err, "invalid session id")
+	}
+
+	if err = x.tokens.RevokeSession(ctx, claims.Subject, id); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			return nil, notFoundError(ctx, err, "session not found")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) RevokeSessions(ctx context.Context, req *gen.RevokeSessionsRequest) (*gen.RevokeSessionsResponse, error) {
+	ctx, span := tracer.Start(ctx, "RevokeSessions")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+
+	except := uuid.Nil
+	if req.GetKeepCurrent() {
+		except = claims.SessionID()
+	}
+	n, err := x.tokens.RevokeSessions(ctx, claims.Subject, except)
+	if err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &gen.RevokeSessionsResponse{Revoked: int64(n)}, nil
+}
+
+func (x *Identity) EnrollTOTP(ctx context.Context, _ *emptypb.Empty) (*gen.EnrollTOTPResponse, error) {
+	ctx, span := tracer.Start(ctx, "EnrollTOTP")
+	defer span.End()
+
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+
+	// Users of other strategies have no email, authenticator apps show their ID instead.
+	account := claims.Subject.String()
+	entry, err := credentials.Read(ctx, x.db, claims.Subject)
+	if err != nil && !errors.As(err, &database.NotFoundError{}) {
+		return nil, internalServerError(ctx, err)
+	}
+	if entry != nil {
+		account = entry.Email
 	}
 
-	switch t := x.strategy.(type) {
-	case *strategy.Credentials:
-		attrs, err := GenSpanAttributes(req.GetCredentials())
-		if err == nil {
-			span.SetAttributes(attrs...)
-		} else {
-			slog.WarnContext(ctx, "server: getting span attributes", "err", err)
+	enrollment, err := x.mfa.Enroll(ctx, claims.Subject, account)
+	if err != nil {
+		if errors.Is(err, auth.ErrMFAAlreadyEnabled) {
+			return nil, failedPreconditionError(ctx, err, "totp is already enabled")
 		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &gen.EnrollTOTPResponse{Secret: string(enrollment.Secret), Uri: string(enrollment.URI)}, nil
+}
+
+func (x *Identity) ConfirmTOTP(ctx context.Context, req *gen.ConfirmTOTPRequest) (*gen.ConfirmTOTPResponse, error) {
+	ctx, span := tracer.Start(ctx, "ConfirmTOTP")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+	if req.GetCode() == "" {
+		return nil, invalidArgumentError(ctx, nil, "code is required")
+	}
 
-		if err = t.IngestInput(ctx, strategy.CredentialsInput{
-			Email:    req.GetCredentials().GetEmail(),
-			Password: req.GetCredentials().GetPassword(),
-		}); err != nil {
+	codes, err := x.mfa.Confirm(ctx, claims.Subject, req.GetCode())
+	if err != nil {
+		if errors.Is(err, auth.ErrMFANotEnrolled) {
+			return nil, failedPreconditionError(ctx, err, "totp is not enrolled")
+		}
+		if errors.Is(err, auth.ErrMFAAlreadyEnabled) {
+			return nil, failedPreconditionError(ctx, err, "totp is already enabled")
+		}
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			return nil, invalidArgumentError(ctx, err, "invalid code")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	res := &gen.ConfirmTOTPResponse{BackupCodes: make([]string, 0, len(codes))}
+	for _, c := range codes {
+		res.BackupCodes = append(res.BackupCodes, string(c))
+	}
+
+	return res, nil
+}
+
+func (x *Identity) DisableTOTP(ctx context.Context, req *gen.DisableTOTPRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "DisableTOTP")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+	if req.GetCode() == "" {
+		return nil, invalidArgumentError(ctx, nil, "code is required")
+	}
+
+	if err := x.mfa.Disable(ctx, claims.Subject, req.GetCode()); err != nil {
+		if errors.Is(err, auth.ErrMFANotEnrolled) {
+			return nil, failedPreconditionError(ctx, err, "totp is not enabled")
+		}
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			return nil, invalidArgumentError(ctx, err, "invalid code")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) BeginWebAuthnRegistration(
+	ctx context.Context,
+	req *gen.BeginWebAuthnRegistrationRequest,
+) (*gen.WebAuthnChallenge, error) {
+	ctx, span := tracer.Start(ctx, "BeginWebAuthnRegistration")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	strategy, err := x.challengeStrategy(ctx, gen.Strategy_WebAuthn)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := strategy.BeginRegistration(ctx, req.GetName(), req.GetDisplayName())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidName) {
 			return nil, invalidArgumentError(ctx, err, err.Error())
 		}
-		if err = t.Register(ctx); err != nil {
-			if errors.As(err, &database.DuplicateEntryError{}) {
-				return nil, alreadyExistsError(ctx, err, "provided credentials already exist")
-			}
-			return nil, internalServerError(ctx, err)
+		if errors.As(err, &database.DuplicateEntryError{}) {
+			return nil, alreadyExistsError(ctx, err, "name is taken")
 		}
-	default:
-		slog.ErrorContext(ctx, fmt.Sprintf("server: unsupported strategy %T,", t))
-		return nil, internalServerError(ctx, fmt.Errorf("unsupported strategy %T", t))
+		return nil, internalServerError(ctx, err)
 	}
 
-	metrics.UsersActive.Inc()
-	metrics.UsersRegistered.Inc()
+	return webAuthnChallenge(challenge), nil
+}
+
+func (x *Identity) FinishWebAuthnRegistration(
+	ctx context.Context,
+	req *gen.FinishWebAuthnRegistrationRequest,
+) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "FinishWebAuthnRegistration")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+
+	return x.register(ctx, &gen.Input{
+		Strategy: gen.Strategy_WebAuthn,
+		Data:     &gen.Input_WebAuthn{WebAuthn: req.GetInput()},
+	})
+}
+
+func (x *Identity) BeginWebAuthnAuthentication(
+	ctx context.Context,
+	req *gen.BeginWebAuthnAuthenticationRequest,
+) (*gen.WebAuthnChallenge, error) {
+	ctx, span := tracer.Start(ctx, "BeginWebAuthnAuthentication")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	strategy, err := x.challengeStrategy(ctx, gen.Strategy_WebAuthn)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := strategy.BeginAuthentication(ctx, req.GetName())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, unauthenticatedError(ctx, err, "invalid credentials")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return webAuthnChallenge(challenge), nil
+}
+
+func (x *Identity) FinishWebAuthnAuthentication(
+	ctx context.Context,
+	req *gen.FinishWebAuthnAuthenticationRequest,
+) (*gen.AuthenticateResponse, error) {
+	ctx, span := tracer.Start(ctx, "FinishWebAuthnAuthentication")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+
+	return x.authenticate(ctx, &gen.Input{
+		Strategy:   gen.Strategy_WebAuthn,
+		Data:       &gen.Input_WebAuthn{WebAuthn: req.GetInput()},
+		RememberMe: req.GetRememberMe(),
+	})
+}
+
+func (x *Identity) StartPasswordless(
+	ctx context.Context,
+	req *gen.StartPasswordlessRequest,
+) (*gen.PasswordlessChallenge, error) {
+	ctx, span := tracer.Start(ctx, "StartPasswordless")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	strategy, err := x.deliveryStrategy(ctx, req.GetStrategy())
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := strategy.Deliver(ctx, req.GetAddress(), req.GetMethod())
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidAddress) || errors.Is(err, auth.ErrUnsupportedDeliveryMethod) {
+			return nil, invalidArgumentError(ctx, err, err.Error())
+		}
+		if errors.Is(err, auth.ErrTooManyDeliveries) {
+			return nil, resourceExhaustedError(ctx, err, "too many deliveries, try again later")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &gen.PasswordlessChallenge{
+		ChallengeId: challenge.ID.String(),
+		ExpiresAt:   timestamppb.New(challenge.ExpiresAt),
+	}, nil
+}
+
+func (x *Identity) VerifyPasswordless(
+	ctx context.Context,
+	req *gen.VerifyPasswordlessRequest,
+) (*gen.AuthenticateResponse, error) {
+	ctx, span := tracer.Start(ctx, "VerifyPasswordless")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+
+	return x.authenticate(ctx, &gen.Input{
+		Strategy:   req.GetStrategy(),
+		Data:       &gen.Input_OneTime{OneTime: req.GetInput()},
+		RememberMe: req.GetRememberMe(),
+	})
+}
+
+func (x *Identity) VerifyEmail(ctx context.Context, req *gen.VerifyEmailRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "VerifyEmail")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "token is required")
+	}
+
+	if _, err := x.verification.Verify(ctx, token.SafeString(req.GetToken())); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return nil, unauthenticatedError(ctx, err, "invalid verification token")
+		}
+		return nil, internalServerError(ctx, err)
+	}
 
 	return &emptypb.Empty{}, nil
 }
+
+func (x *Identity) ResendVerificationEmail(
+	ctx context.Context,
+	req *gen.ResendVerificationEmailRequest,
+) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "ResendVerificationEmail")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetEmail() == "" {
+		return nil, invalidArgumentError(ctx, nil, "email is required")
+	}
+
+	if err := x.verification.Resend(ctx, req.GetEmail()); err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) RequestPasswordReset(
+	ctx context.Context,
+	req *gen.RequestPasswordResetRequest,
+) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "RequestPasswordReset")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetEmail() == "" {
+		return nil, invalidArgumentError(ctx, nil, "email is required")
+	}
+
+	if err := x.passwords.RequestReset(ctx, req.GetEmail()); err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) ResetPassword(ctx context.Context, req *gen.ResetPasswordRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "ResetPassword")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "token is required")
+	}
+	pw, err := password.FromString(req.GetNewPassword())
+	if err != nil {
+		return nil, invalidArgumentError(ctx, err, err.Error())
+	}
+
+	if _, err = x.passwords.Reset(ctx, token.SafeString(req.GetToken()), pw); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return nil, unauthenticatedError(ctx, err, "invalid password reset token")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) ChangePassword(ctx context.Context, req *gen.ChangePasswordRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "ChangePassword")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+	if req.GetCurrentPassword() == "" {
+		return nil, invalidArgumentError(ctx, nil, "current password is required")
+	}
+	pw, err := password.FromString(req.GetNewPassword())
+	if err != nil {
+		return nil, invalidArgumentError(ctx, err, err.Error())
+	}
+
+	if err = x.passwords.Change(ctx, auth.ChangeParams{
+		UserID:              claims.Subject,
+		SessionID:           claims.SessionID(),
+		Current:             password.SafeString(req.GetCurrentPassword()),
+		New:                 pw,
+		RevokeOtherSessions: req.GetRevokeOtherSessions(),
+	}); err != nil {
+		if errors.Is(err, auth.ErrNoPassword) {
+			return nil, failedPreconditionError(ctx, err, "user has no password")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, invalidArgumentError(ctx, err, "invalid current password")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) ChangeEmail(ctx context.Context, req *gen.ChangeEmailRequest) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "ChangeEmail")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	claims, ok := token.FromContext(ctx)
+	if !ok {
+		return nil, unauthenticatedError(ctx, nil, "missing access token")
+	}
+	if req.GetCurrentPassword() == "" {
+		return nil, invalidArgumentError(ctx, nil, "current password is required")
+	}
+	if err := validation.Email(req.GetNewEmail()); err != nil {
+		return nil, invalidArgumentError(ctx, err, err.Error())
+	}
+
+	if err := x.emailChanges.Request(ctx, auth.EmailChangeParams{
+		UserID:   claims.Subject,
+		Password: password.SafeString(req.GetCurrentPassword()),
+		NewEmail: req.GetNewEmail(),
+	}); err != nil {
+		if errors.Is(err, auth.ErrNoPassword) {
+			return nil, failedPreconditionError(ctx, err, "user has no password")
+		}
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, invalidArgumentError(ctx, err, "invalid current password")
+		}
+		if errors.Is(err, auth.ErrTooManyDeliveries) {
+			return nil, resourceExhaustedError(ctx, err, "too many email changes, try again later")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) ConfirmEmailChange(
+	ctx context.Context,
+	req *gen.ConfirmEmailChangeRequest,
+) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "ConfirmEmailChange")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "token is required")
+	}
+
+	if _, err := x.emailChanges.Confirm(ctx, token.SafeString(req.GetToken())); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return nil, unauthenticatedError(ctx, err, "invalid email change token")
+		}
+		if errors.As(err, &database.DuplicateEntryError{}) {
+			return nil, alreadyExistsError(ctx, err, "email is taken")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+func (x *Identity) RevertEmailChange(
+	ctx context.Context,
+	req *gen.RevertEmailChangeRequest,
+) (*emptypb.Empty, error) {
+	ctx, span := tracer.Start(ctx, "RevertEmailChange")
+	defer span.End()
+
+	if req == nil {
+		return nil, requestIsNilError()
+	}
+	if req.GetToken() == "" {
+		return nil, invalidArgumentError(ctx, nil, "token is required")
+	}
+
+	if _, err := x.emailChanges.Revert(ctx, token.SafeString(req.GetToken())); err != nil {
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			return nil, unauthenticatedError(ctx, err, "invalid email revert token")
+		}
+		if errors.As(err, &database.DuplicateEntryError{}) {
+			return nil, alreadyExistsError(ctx, err, "email is taken")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	return &emptypb.Empty{}, nil
+}
+
+// authenticateClient authenticates the service client in the basic authorization metadata of ctx.
+func (x *Identity) authenticateClient(ctx context.Context) error {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return errors.New("server: expected a single authorization metadata value")
+	}
+
+	const prefix = "basic "
+	if len(values[0]) < len(prefix) || !strings.EqualFold(values[0][:len(prefix)], prefix) {
+		return errors.New("server: expected basic authorization")
+	}
+	b, err := base64.StdEncoding.DecodeString(values[0][len(prefix):])
+	if err != nil {
+		return fmt.Errorf("server: decoding basic authorization, %w", err)
+	}
+	id, secret, ok := strings.Cut(string(b), ":")
+	if !ok {
+		return errors.New("server: malformed basic authorization")
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("client_id", id))
+
+	return x.clients.Authenticate(id, secret)
+}
+
+func authenticateResponse(pair auth.Pair) *gen.AuthenticateResponse {
+	return &gen.AuthenticateResponse{
+		Id:                    pair.UserID.String(),
+		AccessToken:           string(pair.AccessToken),
+		RefreshToken:          string(pair.RefreshToken),
+		CreatedAt:             timestamppb.New(pair.IssuedAt),
+		AccessTokenExpiresAt:  timestamppb.New(pair.AccessExpiresAt),
+		RefreshTokenExpiresAt: timestamppb.New(pair.RefreshExpiresAt),
+	}
+}
+
+// authenticate authenticates req with its strategy and returns the tokens,
+// or an MFA challenge for users with a second factor.
+// Returned errors are ready to be sent to the client.
+func (x *Identity) authenticate(ctx context.Context, req *gen.Input) (*gen.AuthenticateResponse, error) {
+	strategy, input, err := x.strategyInput(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := strategy.Authenticate(ctx, input)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			return nil, unauthenticatedError(ctx, err, "invalid credentials")
+		}
+		if errors.Is(err, auth.ErrEmailNotVerified) {
+			return nil, failedPreconditionError(ctx, err, "email is not verified")
+		}
+		return nil, internalServerError(ctx, err)
+	}
+
+	// Users with a second factor get a challenge to answer with VerifyMFA instead of tokens.
+	enabled, err := x.mfa.Enabled(ctx, res.UserID)
+	if err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+	if enabled {
+		challenge, err := x.mfa.Challenge(ctx, auth.ChallengeParams{
+			UserID:     res.UserID,
+			Strategy:   req.GetStrategy().String(),
+			RememberMe: req.GetRememberMe(),
+		})
+		if err != nil {
+			return nil, internalServerError(ctx, err)
+		}
+		return &gen.AuthenticateResponse{Id: res.UserID.String(), MfaChallenge: string(challenge)}, nil
+	}
+
+	md := grpcutil.MetadataFromContext(ctx)
+	pair, err := x.tokens.Issue(ctx, auth.IssueParams{
+		UserID:     res.UserID,
+		Strategy:   req.GetStrategy().String(),
+		UserAgent:  md.UserAgent,
+		ClientIP:   md.ClientIP,
+		RememberMe: req.GetRememberMe(),
+	})
+	if err != nil {
+		return nil, internalServerError(ctx, err)
+	}
+
+	return authenticateResponse(pair), nil
+}
+
+// strategyInput looks up the [auth.Strategy] requested by req and parses its [auth.Input].
+// Returned errors are ready to be sent to the client.
+func (x *Identity) strategyInput(ctx context.Context, req *gen.Input) (auth.Strategy, auth.Input, error) {
+	strategy, err := x.strategies.Get(req.GetStrategy())
+	if err != nil {
+		return nil, nil, invalidArgumentError(
+			ctx,
+			err,
+			fmt.Sprintf("unsupported strategy %s, expecting one of %v", req.GetStrategy(), x.strategies.Strategies()),
+		)
+	}
+
+	input, err := strategy.ParseInput(ctx, req)
+	if err != nil {
+		return nil, nil, invalidArgumentError(ctx, err, err.Error())
+	}
+	trace.SpanFromContext(ctx).SetAttributes(input.TraceAttributes()...)
+
+	return strategy, input, nil
+}
+
+// challengeStrategy returns the configured strategy s if it hands out challenges.
+func (x *Identity) challengeStrategy(ctx context.Context, s gen.Strategy) (auth.ChallengeStrategy, error) {
+	strategy, err := x.strategies.Get(s)
+	if err != nil {
+		return nil, failedPreconditionError(ctx, err, fmt.Sprintf("strategy %s is not configured", s))
+	}
+	challenger, ok := strategy.(auth.ChallengeStrategy)
+	if !ok {
+		return nil, internalServerError(ctx, fmt.Errorf("server: strategy %s does not hand out challenges", s))
+	}
+	return challenger, nil
+}
+
+// deliveryStrategy returns the configured strategy s if it delivers one time codes or links.
+func (x *Identity) deliveryStrategy(ctx context.Context, s gen.Strategy) (auth.DeliveryStrategy, error) {
+	strategy, err := x.strategies.Get(s)
+	if err != nil {
+		return nil, failedPreconditionError(ctx, err, fmt.Sprintf("strategy %s is not configured", s))
+	}
+	deliverer, ok := strategy.(auth.DeliveryStrategy)
+	if !ok {
+		return nil, invalidArgumentError(ctx, nil, fmt.Sprintf("strategy %s is not passwordless", s))
+	}
+	return deliverer, nil
+}
+
+func webAuthnChallenge(challenge auth.Challenge) *gen.WebAuthnChallenge {
+	return &gen.WebAuthnChallenge{
+		ChallengeId: challenge.ID.String(),
+		Options:     challenge.Options,
+		ExpiresAt:   timestamppb.New(challenge.ExpiresAt),
+	}
+}
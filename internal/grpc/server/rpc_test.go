@@ -0,0 +1,1268 @@
+//go:build testdb
+// +build testdb
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/auth"
+	"github.com/Salam4nder/identity/internal/auth/strategy"
+	"github.com/Salam4nder/identity/internal/database/credentials"
+	"github.com/Salam4nder/identity/internal/email"
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/Salam4nder/identity/internal/sms"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/pkg/password"
+	"github.com/Salam4nder/identity/pkg/random"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	testClientID     = "resource"
+	testClientSecret = "secret"
+	testRPID         = "localhost"
+	testRPOrigin     = "http://localhost:3000"
+)
+
+type publisherFunc func(subject string, data []byte) error
+
+func (f publisherFunc) Publish(subject string, data []byte) error {
+	return f(subject, data)
+}
+
+func newTestServer(t *testing.T, published *atomic.Int64) *Identity {
+	t.Helper()
+
+	return newTestServerWithPublisher(t, publisherFunc(func(string, []byte) error {
+		published.Add(1)
+		return nil
+	}))
+}
+
+func newTestServerWithPublisher(t *testing.T, publisher email.Publisher) *Identity {
+	t.Helper()
+
+	return newTestServerWithOpts(t, testServerOpts{publisher: publisher})
+}
+
+type testServerOpts struct {
+	publisher            email.Publisher
+	requireVerifiedEmail bool
+}
+
+func newTestServerWithOpts(t *testing.T, opts testServerOpts) *Identity {
+	t.Helper()
+
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	denylist := hotstorage.NewMemory()
+	ring, err := token.NewKeyRing(token.Key{ID: "test", Secret: []byte(random.String(token.KeySize))})
+	require.NoError(t, err)
+	maker, err := token.BootstrapPasetoMaker(
+		token.MakerOpts{AccessDuration: time.Minute, RefreshDuration: time.Hour, Denylist: denylist},
+		ring,
+	)
+	require.NoError(t, err)
+
+	rp, err := webauthn.New(&webauthn.Config{
+		RPID:          testRPID,
+		RPDisplayName: "identity",
+		RPOrigins:     []string{testRPOrigin},
+	})
+	require.NoError(t, err)
+
+	verification, err := auth.NewEmailVerification(db, opts.publisher, testRPOrigin+"/verify", opts.requireVerifiedEmail)
+	require.NoError(t, err)
+
+	registry, err := strategy.NewRegistry(
+		strategy.Dependencies{
+			DB:                db,
+			Publisher:         opts.publisher,
+			EmailVerification: verification,
+			RelyingParty:      rp,
+			OneTimeKey:        []byte(random.String(strategy.OneTimeKeySize)),
+			LinkURL:           testRPOrigin + "/sign-in",
+		},
+		gen.Strategy_Credentials,
+		gen.Strategy_WebAuthn,
+		gen.Strategy_Email,
+		gen.Strategy_SMS,
+	)
+	require.NoError(t, err)
+
+	secretHash := sha256.Sum256([]byte(testClientSecret))
+	clients, err := auth.NewServiceClients(map[string]string{testClientID: hex.EncodeToString(secretHash[:])})
+	require.NoError(t, err)
+
+	mfa, err := auth.NewMFA(db, "identity", []byte(random.String(auth.MFAEncryptionKeySize)))
+	require.NoError(t, err)
+
+	tokens := auth.NewTokens(db, maker, denylist, auth.SessionPolicies{})
+	passwords, err := auth.NewPasswords(db, opts.publisher, tokens, testRPOrigin+"/reset")
+	require.NoError(t, err)
+	emailChanges, err := auth.NewEmailChanges(
+		db,
+		opts.publisher,
+		tokens,
+		passwords,
+		testRPOrigin+"/confirm",
+		testRPOrigin+"/revert",
+	)
+	require.NoError(t, err)
+
+	srv, err := NewUserServer(
+		db,
+		health.NewServer(),
+		nil,
+		registry,
+		tokens,
+		clients,
+		mfa,
+		verification,
+		passwords,
+		emailChanges,
+	)
+	require.NoError(t, err)
+	return srv
+}
+
+// TestRegisterConcurrent is meant to be run with -race.
+func TestRegisterConcurrent(t *testing.T) {
+	const n = 32
+
+	var published atomic.Int64
+	srv := newTestServer(t, &published)
+	ctx := context.Background()
+
+	emails := make([]string, n)
+	passwords := make([]string, n)
+	errs := make([]error, n)
+	for i := range n {
+		emails[i] = random.Email()
+		passwords[i] = fmt.Sprintf("Passw0rd%d", i)
+	}
+
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, errs[i] = srv.Register(ctx, &gen.Input{
+				Strategy: gen.Strategy_Credentials,
+				Data: &gen.Input_Credentials{
+					Credentials: &gen.CredentialsInput{
+						Email:    emails[i],
+						Password: passwords[i],
+					},
+				},
+			})
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		require.NoError(t, errs[i])
+
+		entry, err := credentials.ReadByEmail(ctx, srv.db, emails[i])
+		require.NoError(t, err)
+		require.NoError(t, password.SafeString(passwords[i]).Compare(entry.PasswordHash))
+	}
+	require.Equal(t, int64(n), published.Load())
+}
+
+func TestIntrospect(t *testing.T) {
+	var published atomic.Int64
+	srv := newTestServer(t, &published)
+	ctx := context.Background()
+
+	input := &gen.Input{
+		Strategy: gen.Strategy_Credentials,
+		Data: &gen.Input_Credentials{
+			Credentials: &gen.CredentialsInput{Email: random.Email(), Password: "Passw0rd1"},
+		},
+	}
+	_, err := srv.Register(ctx, input)
+	require.NoError(t, err)
+	res, err := srv.Authenticate(ctx, input)
+	require.NoError(t, err)
+
+	clientCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(
+		"authorization",
+		"Basic "+base64.StdEncoding.EncodeToString([]byte(testClientID+":"+testClientSecret)),
+	))
+
+	t.Run("unauthenticated client", func(t *testing.T) {
+		_, err := srv.Introspect(ctx, &gen.IntrospectRequest{Token: res.GetAccessToken()})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+		wrongCtx := metadata.NewIncomingContext(ctx, metadata.Pairs(
+			"authorization",
+			"Basic "+base64.StdEncoding.EncodeToString([]byte(testClientID+":wrong")),
+		))
+		_, err = srv.Introspect(wrongCtx, &gen.IntrospectRequest{Token: res.GetAccessToken()})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("active access token", func(t *testing.T) {
+		got, err := srv.Introspect(clientCtx, &gen.IntrospectRequest{Token: res.GetAccessToken()})
+		require.NoError(t, err)
+		require.True(t, got.GetActive())
+		require.Equal(t, res.GetId(), got.GetSub())
+		require.Equal(t, "access_token", got.GetTokenType())
+	})
+
+	t.Run("active refresh token", func(t *testing.T) {
+		got, err := srv.Introspect(clientCtx, &gen.IntrospectRequest{
+			Token:         res.GetRefreshToken(),
+			TokenTypeHint: "refresh_token",
+		})
+		require.NoError(t, err)
+		require.True(t, got.GetActive())
+		require.Equal(t, "refresh_token", got.GetTokenType())
+	})
+
+	t.Run("invalid token is inactive", func(t *testing.T) {
+		got, err := srv.Introspect(clientCtx, &gen.IntrospectRequest{Token: "ass"})
+		require.NoError(t, err)
+		require.False(t, got.GetActive())
+		require.Empty(t, got.GetSub())
+	})
+
+	t.Run("revoked tokens are inactive", func(t *testing.T) {
+		_, err := srv.Revoke(ctx, &gen.RevokeRequest{
+			AccessToken:  res.GetAccessToken(),
+			RefreshToken: res.GetRefreshToken(),
+		})
+		require.NoError(t, err)
+
+		for _, tok := range []string{res.GetAccessToken(), res.GetRefreshToken()} {
+			got, err := srv.Introspect(clientCtx, &gen.IntrospectRequest{Token: tok})
+			require.NoError(t, err)
+			require.False(t, got.GetActive())
+		}
+	})
+}
+
+func TestPublicKeysSymmetric(t *testing.T) {
+	var published atomic.Int64
+	srv := newTestServer(t, &published)
+
+	_, err := srv.PublicKeys(context.Background(), &emptypb.Empty{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestSessions(t *testing.T) {
+	var published atomic.Int64
+	srv := newTestServer(t, &published)
+	ctx := context.Background()
+
+	input := &gen.Input{
+		Strategy: gen.Strategy_Credentials,
+		Data: &gen.Input_Credentials{
+			Credentials: &gen.CredentialsInput{Email: random.Email(), Password: "Passw0rd1"},
+		},
+	}
+	_, err := srv.Register(ctx, input)
+	require.NoError(t, err)
+
+	deviceCtx := metadata.NewIncomingContext(ctx, metadata.Pairs("user-agent", "test-agent"))
+	current, err := srv.Authenticate(deviceCtx, input)
+	require.NoError(t, err)
+	other, err := srv.Authenticate(deviceCtx, input)
+	require.NoError(t, err)
+
+	// authenticated returns the context the authenticator interceptor passes on with t.
+	authenticated := func(t *testing.T, access string) context.Context {
+		claims, active, err := srv.tokens.Introspect(ctx, token.SafeString(access), token.TypeAccess)
+		require.NoError(t, err)
+		require.True(t, active)
+		return token.NewContext(ctx, claims)
+	}
+	currentCtx := authenticated(t, current.GetAccessToken())
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := srv.Sessions(ctx, &emptypb.Empty{})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("list", func(t *testing.T) {
+		res, err := srv.Sessions(currentCtx, &emptypb.Empty{})
+		require.NoError(t, err)
+		require.Len(t, res.GetSessions(), 2)
+		var currents int
+		for _, s := range res.GetSessions() {
+			require.Equal(t, "test-agent", s.GetUserAgent())
+			require.Equal(t, gen.Strategy_Credentials.String(), s.GetStrategy())
+			if s.GetCurrent() {
+				currents++
+			}
+		}
+		require.Equal(t, 1, currents)
+	})
+
+	t.Run("revoke session", func(t *testing.T) {
+		_, err := srv.RevokeSession(currentCtx, &gen.RevokeSessionRequest{Id: "ass"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		otherClaims, _, err := srv.tokens.Introspect(ctx, token.SafeString(other.GetAccessToken()), "")
+		require.NoError(t, err)
+		_, err = srv.RevokeSession(currentCtx, &gen.RevokeSessionRequest{Id: otherClaims.SessionID().String()})
+		require.NoError(t, err)
+		_, err = srv.RevokeSession(currentCtx, &gen.RevokeSessionRequest{Id: otherClaims.SessionID().String()})
+		require.Equal(t, codes.NotFound, status.Code(err))
+
+		_, err = srv.Renew(ctx, &gen.RenewRequest{RefreshToken: other.GetRefreshToken()})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("revoke sessions", func(t *testing.T) {
+		res, err := srv.RevokeSessions(currentCtx, &gen.RevokeSessionsRequest{KeepCurrent: true})
+		require.NoError(t, err)
+		require.Zero(t, res.GetRevoked())
+
+		res, err = srv.RevokeSessions(currentCtx, &gen.RevokeSessionsRequest{})
+		require.NoError(t, err)
+		require.Equal(t, int64(1), res.GetRevoked())
+
+		_, active, err := srv.tokens.Introspect(ctx, token.SafeString(current.GetAccessToken()), "")
+		require.NoError(t, err)
+		require.False(t, active)
+	})
+}
+
+func TestTOTP(t *testing.T) {
+	var published atomic.Int64
+	srv := newTestServer(t, &published)
+	ctx := context.Background()
+
+	input := &gen.Input{
+		Strategy: gen.Strategy_Credentials,
+		Data: &gen.Input_Credentials{
+			Credentials: &gen.CredentialsInput{Email: random.Email(), Password: "Passw0rd1"},
+		},
+		RememberMe: true,
+	}
+	_, err := srv.Register(ctx, input)
+	require.NoError(t, err)
+	res, err := srv.Authenticate(ctx, input)
+	require.NoError(t, err)
+	require.Empty(t, res.GetMfaChallenge())
+
+	claims, active, err := srv.tokens.Introspect(ctx, token.SafeString(res.GetAccessToken()), token.TypeAccess)
+	require.NoError(t, err)
+	require.True(t, active)
+	authCtx := token.NewContext(ctx, claims)
+
+	_, err = srv.ConfirmTOTP(authCtx, &gen.ConfirmTOTPRequest{Code: "123456"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	enrollment, err := srv.EnrollTOTP(authCtx, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Contains(t, enrollment.GetUri(), input.GetCredentials().GetEmail())
+
+	_, err = srv.ConfirmTOTP(authCtx, &gen.ConfirmTOTPRequest{Code: "000000"})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	code, err := totp.GenerateCode(enrollment.GetSecret(), time.Now())
+	require.NoError(t, err)
+	confirmed, err := srv.ConfirmTOTP(authCtx, &gen.ConfirmTOTPRequest{Code: code})
+	require.NoError(t, err)
+	require.Len(t, confirmed.GetBackupCodes(), auth.BackupCodes)
+
+	_, err = srv.EnrollTOTP(authCtx, &emptypb.Empty{})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	t.Run("authenticate returns a challenge", func(t *testing.T) {
+		res, err := srv.Authenticate(ctx, input)
+		require.NoError(t, err)
+		require.NotEmpty(t, res.GetMfaChallenge())
+		require.Empty(t, res.GetAccessToken())
+
+		_, err = srv.VerifyMFA(ctx, &gen.VerifyMFARequest{MfaChallenge: res.GetMfaChallenge(), Code: code})
+		require.Equal(t, codes.Unauthenticated, status.Code(err), "a totp code can not be replayed")
+
+		verified, err := srv.VerifyMFA(ctx, &gen.VerifyMFARequest{
+			MfaChallenge: res.GetMfaChallenge(),
+			Code:         confirmed.GetBackupCodes()[0],
+		})
+		require.NoError(t, err)
+		require.NotEmpty(t, verified.GetAccessToken())
+
+		sessions, err := srv.tokens.Sessions(ctx, claims.Subject)
+		require.NoError(t, err)
+		require.True(t, sessions[0].RememberMe)
+
+		_, err = srv.VerifyMFA(ctx, &gen.VerifyMFARequest{
+			MfaChallenge: res.GetMfaChallenge(),
+			Code:         confirmed.GetBackupCodes()[1],
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err), "a challenge can be answered once")
+	})
+
+	t.Run("challenge runs out of attempts", func(t *testing.T) {
+		res, err := srv.Authenticate(ctx, input)
+		require.NoError(t, err)
+
+		for range auth.MFAChallengeAttempts {
+			_, err = srv.VerifyMFA(ctx, &gen.VerifyMFARequest{MfaChallenge: res.GetMfaChallenge(), Code: "wrong-code"})
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+		_, err = srv.VerifyMFA(ctx, &gen.VerifyMFARequest{
+			MfaChallenge: res.GetMfaChallenge(),
+			Code:         confirmed.GetBackupCodes()[1],
+		})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("disable", func(t *testing.T) {
+		_, err := srv.DisableTOTP(authCtx, &gen.DisableTOTPRequest{Code: confirmed.GetBackupCodes()[0]})
+		require.Equal(t, codes.InvalidArgument, status.Code(err), "a backup code can be used once")
+
+		_, err = srv.DisableTOTP(authCtx, &gen.DisableTOTPRequest{Code: confirmed.GetBackupCodes()[1]})
+		require.NoError(t, err)
+
+		res, err := srv.Authenticate(ctx, input)
+		require.NoError(t, err)
+		require.Empty(t, res.GetMfaChallenge())
+		require.NotEmpty(t, res.GetAccessToken())
+	})
+}
+
+func TestWebAuthn(t *testing.T) {
+	var published atomic.Int64
+	srv := newTestServer(t, &published)
+	ctx := context.Background()
+	passkey := newSoftAuthenticator(t, testRPID, testRPOrigin)
+	name := random.Email()
+
+	registration, err := srv.BeginWebAuthnRegistration(ctx, &gen.BeginWebAuthnRegistrationRequest{Name: name})
+	require.NoError(t, err)
+	require.True(t, registration.GetExpiresAt().AsTime().After(time.Now()))
+	credential := passkey.create(t, registration.GetOptions())
+
+	finishRegistration := func(challengeID string, credential []byte) error {
+		_, err := srv.FinishWebAuthnRegistration(ctx, &gen.FinishWebAuthnRegistrationRequest{
+			Input: &gen.WebAuthnInput{ChallengeId: challengeID, Credential: credential},
+		})
+		return err
+	}
+	finishAuthentication := func(challengeID string, credential []byte) (*gen.AuthenticateResponse, error) {
+		return srv.FinishWebAuthnAuthentication(ctx, &gen.FinishWebAuthnAuthenticationRequest{
+			Input: &gen.WebAuthnInput{ChallengeId: challengeID, Credential: credential},
+		})
+	}
+	require.NoError(t, finishRegistration(registration.GetChallengeId(), credential))
+
+	err = finishRegistration(registration.GetChallengeId(), credential)
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "a challenge can be answered once")
+
+	_, err = srv.BeginWebAuthnRegistration(ctx, &gen.BeginWebAuthnRegistrationRequest{Name: name})
+	require.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = srv.BeginWebAuthnRegistration(ctx, &gen.BeginWebAuthnRegistrationRequest{})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = srv.BeginWebAuthnAuthentication(ctx, &gen.BeginWebAuthnAuthenticationRequest{Name: random.Email()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	var userID string
+	for _, name := range []string{name, ""} {
+		challenge, err := srv.BeginWebAuthnAuthentication(ctx, &gen.BeginWebAuthnAuthenticationRequest{Name: name})
+		require.NoError(t, err)
+
+		res, err := finishAuthentication(challenge.GetChallengeId(), passkey.get(t, challenge.GetOptions()))
+		require.NoError(t, err)
+		require.NotEmpty(t, res.GetAccessToken())
+		if userID != "" {
+			require.Equal(t, userID, res.GetId())
+		}
+		userID = res.GetId()
+	}
+
+	t.Run("signature counter must grow", func(t *testing.T) {
+		challenge, err := srv.BeginWebAuthnAuthentication(ctx, &gen.BeginWebAuthnAuthenticationRequest{Name: name})
+		require.NoError(t, err)
+
+		passkey.signCount--
+		_, err = finishAuthentication(challenge.GetChallengeId(), passkey.get(t, challenge.GetOptions()))
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+		passkey.signCount++
+	})
+
+	t.Run("authenticate with the webauthn strategy", func(t *testing.T) {
+		challenge, err := srv.BeginWebAuthnAuthentication(ctx, &gen.BeginWebAuthnAuthenticationRequest{Name: name})
+		require.NoError(t, err)
+
+		res, err := srv.Authenticate(ctx, &gen.Input{
+			Strategy: gen.Strategy_WebAuthn,
+			Data: &gen.Input_WebAuthn{
+				WebAuthn: &gen.WebAuthnInput{
+					ChallengeId: challenge.GetChallengeId(),
+					Credential:  passkey.get(t, challenge.GetOptions()),
+				},
+			},
+		})
+		require.NoError(t, err)
+		require.Equal(t, userID, res.GetId())
+	})
+}
+
+func TestPasswordless(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		mails []email.Email
+	)
+	srv := newTestServerWithPublisher(t, publisherFunc(func(_ string, data []byte) error {
+		var mail email.Email
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&mail); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		mails = append(mails, mail)
+		return nil
+	}))
+	lastMail := func() email.Email {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, mails)
+		return mails[len(mails)-1]
+	}
+	ctx := context.Background()
+	address := random.Email()
+
+	start := func(method gen.DeliveryMethod) *gen.PasswordlessChallenge {
+		challenge, err := srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_Email,
+			Address:  address,
+			Method:   method,
+		})
+		require.NoError(t, err)
+		require.Equal(t, address, lastMail().To)
+		return challenge
+	}
+	verify := func(input *gen.OneTimeInput) (*gen.AuthenticateResponse, error) {
+		return srv.VerifyPasswordless(ctx, &gen.VerifyPasswordlessRequest{Strategy: gen.Strategy_Email, Input: input})
+	}
+	code := regexp.MustCompile(`\b\d{6}\b`)
+
+	challenge := start(gen.DeliveryMethod_Code)
+	sent := code.FindString(lastMail().Body)
+	require.NotEmpty(t, sent)
+
+	res, err := verify(&gen.OneTimeInput{ChallengeId: challenge.GetChallengeId(), Code: sent})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetAccessToken())
+	userID := res.GetId()
+
+	_, err = verify(&gen.OneTimeInput{ChallengeId: challenge.GetChallengeId(), Code: sent})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "a code can be used once")
+
+	t.Run("link signs in the same user", func(t *testing.T) {
+		start(gen.DeliveryMethod_Link)
+		link := regexp.MustCompile(`https?://\S+/sign-in\?\S+`).FindString(lastMail().Body)
+		require.NotEmpty(t, link)
+		u, err := url.Parse(strings.TrimSuffix(link, "."))
+		require.NoError(t, err)
+		tok := u.Query().Get("token")
+
+		tampered := []byte(tok)
+		tampered[len(tampered)-1] ^= 1
+		_, err = verify(&gen.OneTimeInput{Token: string(tampered)})
+		require.Contains(t, []codes.Code{codes.Unauthenticated, codes.InvalidArgument}, status.Code(err))
+
+		res, err := verify(&gen.OneTimeInput{Token: tok})
+		require.NoError(t, err)
+		require.Equal(t, userID, res.GetId())
+	})
+
+	t.Run("challenge runs out of attempts", func(t *testing.T) {
+		challenge := start(gen.DeliveryMethod_Code)
+		sent := code.FindString(lastMail().Body)
+		wrong := "000000"
+		if sent == wrong {
+			wrong = "111111"
+		}
+
+		for range strategy.OneTimeChallengeAttempts {
+			_, err := verify(&gen.OneTimeInput{ChallengeId: challenge.GetChallengeId(), Code: wrong})
+			require.Equal(t, codes.Unauthenticated, status.Code(err))
+		}
+		_, err := verify(&gen.OneTimeInput{ChallengeId: challenge.GetChallengeId(), Code: sent})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("deliveries are rate limited per address", func(t *testing.T) {
+		// The sign in and both subtests above used up the deliveries of address.
+		_, err := srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_Email,
+			Address:  address,
+			Method:   gen.DeliveryMethod_Code,
+		})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		_, err = srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_Email,
+			Address:  random.Email(),
+			Method:   gen.DeliveryMethod_Code,
+		})
+		require.NoError(t, err, "another address is not limited")
+	})
+
+	t.Run("invalid address", func(t *testing.T) {
+		_, err := srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_Email,
+			Address:  "not an email",
+			Method:   gen.DeliveryMethod_Code,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("strategy without deliveries", func(t *testing.T) {
+		_, err := srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_Credentials,
+			Address:  address,
+			Method:   gen.DeliveryMethod_Code,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
+
+func TestSMS(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		messages []sms.SMS
+	)
+	srv := newTestServerWithPublisher(t, publisherFunc(func(subject string, data []byte) error {
+		if subject != sms.IngestedEvent {
+			return nil
+		}
+		var message sms.SMS
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&message); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		messages = append(messages, message)
+		return nil
+	}))
+	ctx := context.Background()
+	phone := random.Phone()
+
+	start := func(method gen.DeliveryMethod) (*gen.PasswordlessChallenge, error) {
+		return srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_SMS,
+			Address:  phone,
+			Method:   method,
+		})
+	}
+
+	challenge, err := start(gen.DeliveryMethod_Code)
+	require.NoError(t, err)
+	mu.Lock()
+	require.Len(t, messages, 1)
+	message := messages[0]
+	mu.Unlock()
+	require.Equal(t, phone, message.To)
+	sent := regexp.MustCompile(`\b\d{6}\b`).FindString(message.Body)
+	require.NotEmpty(t, sent)
+
+	res, err := srv.VerifyPasswordless(ctx, &gen.VerifyPasswordlessRequest{
+		Strategy: gen.Strategy_SMS,
+		Input:    &gen.OneTimeInput{ChallengeId: challenge.GetChallengeId(), Code: sent},
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, res.GetAccessToken())
+
+	t.Run("links are not supported", func(t *testing.T) {
+		_, err := start(gen.DeliveryMethod_Link)
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid phone number", func(t *testing.T) {
+		_, err := srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_SMS,
+			Address:  "0701234567",
+			Method:   gen.DeliveryMethod_Code,
+		})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("deliveries are rate limited per number", func(t *testing.T) {
+		for range strategy.OneTimeDeliveries - 1 {
+			_, err := start(gen.DeliveryMethod_Code)
+			require.NoError(t, err)
+		}
+		_, err := start(gen.DeliveryMethod_Code)
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, messages, strategy.OneTimeDeliveries)
+
+		_, err = srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+			Strategy: gen.Strategy_SMS,
+			Address:  random.Phone(),
+			Method:   gen.DeliveryMethod_Code,
+		})
+		require.NoError(t, err, "another number is not limited")
+	})
+
+	t.Run("concurrent deliveries are rate limited", func(t *testing.T) {
+		phone := random.Phone()
+		var (
+			wg        sync.WaitGroup
+			delivered atomic.Int32
+		)
+		for range 3 * strategy.OneTimeDeliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := srv.StartPasswordless(ctx, &gen.StartPasswordlessRequest{
+					Strategy: gen.Strategy_SMS,
+					Address:  phone,
+					Method:   gen.DeliveryMethod_Code,
+				})
+				if err == nil {
+					delivered.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.EqualValues(t, strategy.OneTimeDeliveries, delivered.Load())
+	})
+}
+
+func TestEmailVerification(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		mails []email.Email
+	)
+	srv := newTestServerWithOpts(t, testServerOpts{
+		publisher: publisherFunc(func(_ string, data []byte) error {
+			var mail email.Email
+			if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&mail); err != nil {
+				return err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			mails = append(mails, mail)
+			return nil
+		}),
+		requireVerifiedEmail: true,
+	})
+	sentToken := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, mails)
+		link := regexp.MustCompile(`https?://\S+/verify\?\S+`).FindString(mails[len(mails)-1].Body)
+		require.NotEmpty(t, link)
+		u, err := url.Parse(strings.TrimSuffix(link, "."))
+		require.NoError(t, err)
+		return u.Query().Get("token")
+	}
+	ctx := context.Background()
+	address := random.Email()
+	input := &gen.Input{
+		Strategy: gen.Strategy_Credentials,
+		Data: &gen.Input_Credentials{
+			Credentials: &gen.CredentialsInput{Email: address, Password: "Passw0rd!"},
+		},
+	}
+
+	_, err := srv.Register(ctx, input)
+	require.NoError(t, err)
+	registered := sentToken()
+
+	_, err = srv.Authenticate(ctx, input)
+	require.Equal(t, codes.FailedPrecondition, status.Code(err), "an unverified email can not authenticate")
+
+	t.Run("resend is throttled", func(t *testing.T) {
+		for range auth.EmailVerificationSends - 1 {
+			_, err := srv.ResendVerificationEmail(ctx, &gen.ResendVerificationEmailRequest{Email: address})
+			require.NoError(t, err)
+		}
+		mu.Lock()
+		sent := len(mails)
+		mu.Unlock()
+
+		_, err := srv.ResendVerificationEmail(ctx, &gen.ResendVerificationEmailRequest{Email: address})
+		require.NoError(t, err, "a throttled resend is not told apart")
+
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, mails, sent)
+	})
+
+	t.Run("unknown email is not told apart", func(t *testing.T) {
+		_, err := srv.ResendVerificationEmail(ctx, &gen.ResendVerificationEmailRequest{Email: random.Email()})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		_, err := srv.VerifyEmail(ctx, &gen.VerifyEmailRequest{Token: "invalid"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("verified email authenticates", func(t *testing.T) {
+		_, err := srv.VerifyEmail(ctx, &gen.VerifyEmailRequest{Token: registered})
+		require.NoError(t, err)
+
+		_, err = srv.VerifyEmail(ctx, &gen.VerifyEmailRequest{Token: registered})
+		require.Equal(t, codes.Unauthenticated, status.Code(err), "a token can be used once")
+
+		res, err := srv.Authenticate(ctx, input)
+		require.NoError(t, err)
+		require.NotEmpty(t, res.GetAccessToken())
+
+		mu.Lock()
+		sent := len(mails)
+		mu.Unlock()
+		_, err = srv.ResendVerificationEmail(ctx, &gen.ResendVerificationEmailRequest{Email: address})
+		require.NoError(t, err)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Len(t, mails, sent, "a verified email is not sent another token")
+	})
+
+	t.Run("concurrent resends are throttled", func(t *testing.T) {
+		address := random.Email()
+		_, err := srv.Register(ctx, &gen.Input{
+			Strategy: gen.Strategy_Credentials,
+			Data: &gen.Input_Credentials{
+				Credentials: &gen.CredentialsInput{Email: address, Password: "Passw0rd!"},
+			},
+		})
+		require.NoError(t, err)
+
+		var wg sync.WaitGroup
+		for range 3 * auth.EmailVerificationSends {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, _ = srv.ResendVerificationEmail(ctx, &gen.ResendVerificationEmailRequest{Email: address})
+			}()
+		}
+		wg.Wait()
+
+		mu.Lock()
+		defer mu.Unlock()
+		var sent int
+		for _, mail := range mails {
+			if mail.To == address {
+				sent++
+			}
+		}
+		require.Equal(t, auth.EmailVerificationSends, sent)
+	})
+
+	t.Run("registered without the verification email", func(t *testing.T) {
+		srv := newTestServerWithOpts(t, testServerOpts{
+			publisher: publisherFunc(func(string, []byte) error {
+				return errors.New("unavailable")
+			}),
+			requireVerifiedEmail: true,
+		})
+		input := &gen.Input{
+			Strategy: gen.Strategy_Credentials,
+			Data: &gen.Input_Credentials{
+				Credentials: &gen.CredentialsInput{Email: random.Email(), Password: "Passw0rd!"},
+			},
+		}
+
+		_, err := srv.Register(ctx, input)
+		require.NoError(t, err, "a lost email can be resent")
+		_, err = srv.Register(ctx, input)
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+}
+
+func TestPasswordReset(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		mails []email.Email
+	)
+	srv := newTestServerWithPublisher(t, publisherFunc(func(_ string, data []byte) error {
+		var mail email.Email
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&mail); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		mails = append(mails, mail)
+		return nil
+	}))
+	sent := func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(mails)
+	}
+	resetToken := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, mails)
+		mail := mails[len(mails)-1]
+		require.Equal(t, "Reset your password", mail.Subject)
+		link := regexp.MustCompile(`https?://\S+/reset\?\S+`).FindString(mail.Body)
+		require.NotEmpty(t, link)
+		u, err := url.Parse(strings.TrimSuffix(link, "."))
+		require.NoError(t, err)
+		return u.Query().Get("token")
+	}
+	ctx := context.Background()
+	address := random.Email()
+	credentialsInput := func(pw string) *gen.Input {
+		return &gen.Input{
+			Strategy: gen.Strategy_Credentials,
+			Data: &gen.Input_Credentials{
+				Credentials: &gen.CredentialsInput{Email: address, Password: pw},
+			},
+		}
+	}
+
+	_, err := srv.Register(ctx, credentialsInput("Passw0rd!"))
+	require.NoError(t, err)
+	session, err := srv.Authenticate(ctx, credentialsInput("Passw0rd!"))
+	require.NoError(t, err)
+
+	t.Run("unknown email is not told apart", func(t *testing.T) {
+		before := sent()
+		_, err := srv.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: random.Email()})
+		require.NoError(t, err)
+		require.Equal(t, before, sent())
+	})
+
+	_, err = srv.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: address})
+	require.NoError(t, err)
+	older := resetToken()
+	_, err = srv.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: address})
+	require.NoError(t, err)
+	tok := resetToken()
+
+	t.Run("invalid new password", func(t *testing.T) {
+		_, err := srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: tok, NewPassword: "short"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	_, err = srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: tok, NewPassword: "N3wPassw0rd!"})
+	require.NoError(t, err)
+
+	_, err = srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: tok, NewPassword: "An0therPassw0rd!"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "a token can be used once")
+	_, err = srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: older, NewPassword: "An0therPassw0rd!"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "other tokens expire on reset")
+
+	_, err = srv.Authenticate(ctx, credentialsInput("Passw0rd!"))
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "the old password no longer works")
+	_, err = srv.Authenticate(ctx, credentialsInput("N3wPassw0rd!"))
+	require.NoError(t, err)
+
+	_, err = srv.Renew(ctx, &gen.RenewRequest{RefreshToken: session.GetRefreshToken()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "sessions end on reset")
+
+	t.Run("concurrent requests are throttled silently", func(t *testing.T) {
+		var wg sync.WaitGroup
+		for range 3 * auth.PasswordResetSends {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := srv.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: address}); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+		mu.Lock()
+		defer mu.Unlock()
+		var resets int
+		for _, mail := range mails {
+			if mail.Subject == "Reset your password" {
+				resets++
+			}
+		}
+		require.Equal(t, auth.PasswordResetSends, resets)
+	})
+}
+
+func TestChangePassword(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		mails []email.Email
+	)
+	srv := newTestServerWithPublisher(t, publisherFunc(func(_ string, data []byte) error {
+		var mail email.Email
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&mail); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		mails = append(mails, mail)
+		return nil
+	}))
+	ctx := context.Background()
+	address := random.Email()
+	credentialsInput := func(pw string) *gen.Input {
+		return &gen.Input{
+			Strategy: gen.Strategy_Credentials,
+			Data: &gen.Input_Credentials{
+				Credentials: &gen.CredentialsInput{Email: address, Password: pw},
+			},
+		}
+	}
+
+	_, err := srv.Register(ctx, credentialsInput("Passw0rd1"))
+	require.NoError(t, err)
+	current, err := srv.Authenticate(ctx, credentialsInput("Passw0rd1"))
+	require.NoError(t, err)
+	other, err := srv.Authenticate(ctx, credentialsInput("Passw0rd1"))
+	require.NoError(t, err)
+
+	claims, active, err := srv.tokens.Introspect(ctx, token.SafeString(current.GetAccessToken()), token.TypeAccess)
+	require.NoError(t, err)
+	require.True(t, active)
+	authCtx := token.NewContext(ctx, claims)
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := srv.ChangePassword(ctx, &gen.ChangePasswordRequest{CurrentPassword: "Passw0rd1", NewPassword: "Passw0rd2"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("wrong current password", func(t *testing.T) {
+		_, err := srv.ChangePassword(authCtx, &gen.ChangePasswordRequest{CurrentPassword: "wrong", NewPassword: "Passw0rd2"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid new password", func(t *testing.T) {
+		_, err := srv.ChangePassword(authCtx, &gen.ChangePasswordRequest{CurrentPassword: "Passw0rd1", NewPassword: "short"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	_, err = srv.ChangePassword(authCtx, &gen.ChangePasswordRequest{
+		CurrentPassword:     "Passw0rd1",
+		NewPassword:         "Passw0rd2",
+		RevokeOtherSessions: true,
+	})
+	require.NoError(t, err)
+
+	mu.Lock()
+	require.Equal(t, address, mails[len(mails)-1].To)
+	require.Equal(t, "Your password was changed", mails[len(mails)-1].Subject)
+	mu.Unlock()
+
+	_, err = srv.Authenticate(ctx, credentialsInput("Passw0rd1"))
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "the old password no longer works")
+	_, err = srv.Authenticate(ctx, credentialsInput("Passw0rd2"))
+	require.NoError(t, err)
+
+	_, err = srv.Renew(ctx, &gen.RenewRequest{RefreshToken: other.GetRefreshToken()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "other sessions end")
+	_, err = srv.Renew(ctx, &gen.RenewRequest{RefreshToken: current.GetRefreshToken()})
+	require.NoError(t, err, "the current session is kept")
+}
+
+func TestEmailChange(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		mails []email.Email
+	)
+	srv := newTestServerWithPublisher(t, publisherFunc(func(_ string, data []byte) error {
+		var mail email.Email
+		if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&mail); err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		mails = append(mails, mail)
+		return nil
+	}))
+	lastToken := func(to, subject, path string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		require.NotEmpty(t, mails)
+		mail := mails[len(mails)-1]
+		require.Equal(t, to, mail.To)
+		require.Equal(t, subject, mail.Subject)
+		link := regexp.MustCompile(`https?://\S+/` + path + `\?\S+`).FindString(mail.Body)
+		require.NotEmpty(t, link)
+		u, err := url.Parse(strings.TrimSuffix(link, "."))
+		require.NoError(t, err)
+		return u.Query().Get("token")
+	}
+	ctx := context.Background()
+	address := random.Email()
+	pw := "Passw0rd!"
+	credentialsInput := func(address string) *gen.Input {
+		return &gen.Input{
+			Strategy: gen.Strategy_Credentials,
+			Data: &gen.Input_Credentials{
+				Credentials: &gen.CredentialsInput{Email: address, Password: pw},
+			},
+		}
+	}
+	resetToken := func(to string) string {
+		mu.Lock()
+		defer mu.Unlock()
+		for i := len(mails) - 1; i >= 0; i-- {
+			if mails[i].To != to || mails[i].Subject != "Reset your password" {
+				continue
+			}
+			link := regexp.MustCompile(`https?://\S+/reset\?\S+`).FindString(mails[i].Body)
+			require.NotEmpty(t, link)
+			u, err := url.Parse(strings.TrimSuffix(link, "."))
+			require.NoError(t, err)
+			return u.Query().Get("token")
+		}
+		t.Fatalf("no password reset email to %s", to)
+		return ""
+	}
+
+	_, err := srv.Register(ctx, credentialsInput(address))
+	require.NoError(t, err)
+	session, err := srv.Authenticate(ctx, credentialsInput(address))
+	require.NoError(t, err)
+	claims, active, err := srv.tokens.Introspect(ctx, token.SafeString(session.GetAccessToken()), token.TypeAccess)
+	require.NoError(t, err)
+	require.True(t, active)
+	authCtx := token.NewContext(ctx, claims)
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		_, err := srv.ChangeEmail(ctx, &gen.ChangeEmailRequest{NewEmail: random.Email(), CurrentPassword: "Passw0rd!"})
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	})
+
+	t.Run("wrong current password", func(t *testing.T) {
+		_, err := srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: random.Email(), CurrentPassword: "wrong"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("invalid new email", func(t *testing.T) {
+		_, err := srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: "invalid", CurrentPassword: "Passw0rd!"})
+		require.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("taken email", func(t *testing.T) {
+		taken := random.Email()
+		_, err := srv.Register(ctx, credentialsInput(taken))
+		require.NoError(t, err)
+
+		_, err = srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: taken, CurrentPassword: "Passw0rd!"})
+		require.NoError(t, err)
+		tok := lastToken(taken, "Confirm your new email", "confirm")
+
+		_, err = srv.ConfirmEmailChange(ctx, &gen.ConfirmEmailChangeRequest{Token: tok})
+		require.Equal(t, codes.AlreadyExists, status.Code(err))
+	})
+
+	newAddress := random.Email()
+	_, err = srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: newAddress, CurrentPassword: "Passw0rd!"})
+	require.NoError(t, err)
+	tok := lastToken(newAddress, "Confirm your new email", "confirm")
+
+	_, err = srv.Authenticate(ctx, credentialsInput(newAddress))
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "the new email is pending until confirmed")
+
+	_, err = srv.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: address})
+	require.NoError(t, err)
+	previousReset := resetToken(address)
+
+	_, err = srv.ConfirmEmailChange(ctx, &gen.ConfirmEmailChangeRequest{Token: tok})
+	require.NoError(t, err)
+	_, err = srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: previousReset, NewPassword: "Changed0!"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "reset tokens of the previous email expire on confirm")
+	_, err = srv.ConfirmEmailChange(ctx, &gen.ConfirmEmailChangeRequest{Token: tok})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "a token can be used once")
+	revert := lastToken(address, "Your email was changed", "revert")
+
+	_, err = srv.Authenticate(ctx, credentialsInput(address))
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "the previous email no longer works")
+	_, err = srv.Authenticate(ctx, credentialsInput(newAddress))
+	require.NoError(t, err)
+
+	_, err = srv.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: newAddress})
+	require.NoError(t, err)
+	newReset := resetToken(newAddress)
+	pendingAddress := random.Email()
+	_, err = srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: pendingAddress, CurrentPassword: pw})
+	require.NoError(t, err)
+	pending := lastToken(pendingAddress, "Confirm your new email", "confirm")
+
+	_, err = srv.RevertEmailChange(ctx, &gen.RevertEmailChangeRequest{Token: tok})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "a confirm token can not revert")
+	_, err = srv.RevertEmailChange(ctx, &gen.RevertEmailChangeRequest{Token: revert})
+	require.NoError(t, err)
+
+	_, err = srv.Authenticate(ctx, credentialsInput(address))
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "the password is replaced on revert")
+	_, err = srv.Renew(ctx, &gen.RenewRequest{RefreshToken: session.GetRefreshToken()})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "sessions end on revert")
+	_, err = srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: newReset, NewPassword: "Changed0!"})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "reset tokens expire on revert")
+	_, err = srv.ConfirmEmailChange(ctx, &gen.ConfirmEmailChangeRequest{Token: pending})
+	require.Equal(t, codes.Unauthenticated, status.Code(err), "pending changes expire on revert")
+
+	pw = "Changed0!"
+	_, err = srv.ResetPassword(ctx, &gen.ResetPasswordRequest{Token: resetToken(address), NewPassword: pw})
+	require.NoError(t, err)
+	_, err = srv.Authenticate(ctx, credentialsInput(address))
+	require.NoError(t, err)
+
+	t.Run("requests are throttled", func(t *testing.T) {
+		for range auth.EmailChangeSends {
+			_, _ = srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: random.Email(), CurrentPassword: pw})
+		}
+		_, err := srv.ChangeEmail(authCtx, &gen.ChangeEmailRequest{NewEmail: random.Email(), CurrentPassword: pw})
+		require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+
+	t.Run("concurrent requests are throttled", func(t *testing.T) {
+		address := random.Email()
+		_, err := srv.Register(ctx, credentialsInput(address))
+		require.NoError(t, err)
+		session, err := srv.Authenticate(ctx, credentialsInput(address))
+		require.NoError(t, err)
+		claims, _, err := srv.tokens.Introspect(ctx, token.SafeString(session.GetAccessToken()), token.TypeAccess)
+		require.NoError(t, err)
+		authCtx := token.NewContext(ctx, claims)
+
+		var (
+			wg        sync.WaitGroup
+			requested atomic.Int32
+		)
+		for range 3 * auth.EmailChangeSends {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				req := &gen.ChangeEmailRequest{NewEmail: random.Email(), CurrentPassword: pw}
+				if _, err := srv.ChangeEmail(authCtx, req); err == nil {
+					requested.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		require.EqualValues(t, auth.EmailChangeSends, requested.Load())
+	})
+}
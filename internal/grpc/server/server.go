@@ -4,21 +4,29 @@ import (
 	"database/sql"
 
 	"github.com/Salam4nder/identity/internal/auth"
-	"github.com/Salam4nder/identity/internal/token"
 	"github.com/Salam4nder/identity/proto/gen"
 	"github.com/nats-io/nats.go"
 	"google.golang.org/grpc/health"
 )
 
+// PublicMethods of the [Identity] service can be called without an access token.
+// They are marked with the public option in proto/service.proto, see [gen.PublicMethods].
+var PublicMethods = gen.PublicMethods()
+
 // Identity contains all necessary dependencies to serve gRPC requests.
 type Identity struct {
 	gen.IdentityServer
 
-	db         *sql.DB
-	health     *health.Server
-	natsConn   *nats.Conn
-	strategy   auth.Strategy
-	tokenMaker token.Maker
+	db           *sql.DB
+	health       *health.Server
+	natsConn     *nats.Conn
+	strategies   *auth.Registry
+	tokens       *auth.Tokens
+	clients      *auth.ServiceClients
+	mfa          *auth.MFA
+	verification *auth.EmailVerification
+	passwords    *auth.Passwords
+	emailChanges *auth.EmailChanges
 }
 
 // NewUserServer returns a new UserService.
@@ -26,14 +34,24 @@ func NewUserServer(
 	db *sql.DB,
 	health *health.Server,
 	natsConn *nats.Conn,
-	strategy auth.Strategy,
-	tokenMaker token.Maker,
+	strategies *auth.Registry,
+	tokens *auth.Tokens,
+	clients *auth.ServiceClients,
+	mfa *auth.MFA,
+	verification *auth.EmailVerification,
+	passwords *auth.Passwords,
+	emailChanges *auth.EmailChanges,
 ) (*Identity, error) {
 	return &Identity{
-		strategy:   strategy,
-		tokenMaker: tokenMaker,
-		health:     health,
-		natsConn:   natsConn,
-		db:         db,
+		strategies:   strategies,
+		tokens:       tokens,
+		clients:      clients,
+		mfa:          mfa,
+		verification: verification,
+		passwords:    passwords,
+		emailChanges: emailChanges,
+		health:       health,
+		natsConn:     natsConn,
+		db:           db,
 	}, nil
 }
@@ -9,7 +9,7 @@ import (
 )
 
 type GenReq interface {
-	*gen.CredentialsInput | *gen.PersonalNumberInput
+	*gen.CredentialsInput | *gen.PersonalNumberInput | *gen.WebAuthnInput | *gen.OneTimeInput
 }
 
 // GenerateSpanAttributes returns span attributes for generated request structs.
@@ -29,6 +29,15 @@ func GenSpanAttributes[T GenReq](param T) ([]attribute.KeyValue, error) {
 		return []attribute.KeyValue{
 			attribute.Int64("number", int64(t.GetNumbers())),
 		}, nil
+	case *gen.WebAuthnInput:
+		return []attribute.KeyValue{
+			attribute.String("challenge_id", t.GetChallengeId()),
+		}, nil
+	case *gen.OneTimeInput:
+		return []attribute.KeyValue{
+			attribute.String("challenge_id", t.GetChallengeId()),
+			attribute.Bool("link", t.GetToken() != ""),
+		}, nil
 	default:
 		return nil, fmt.Errorf("server: span attributes, unsupported type %T", t)
 	}
@@ -0,0 +1,30 @@
+package hotstorage
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("hotstorage")
+
+// Denylist holds the IDs of revoked tokens.
+// An entry only has to be kept until the token it belongs to would have expired anyway.
+type Denylist interface {
+	// Deny adds id to the denylist until the given time.
+	Deny(ctx context.Context, id string, until time.Time) error
+	// IsDenied reports whether id is on the denylist.
+	IsDenied(ctx context.Context, id string) (bool, error)
+}
+
+// Cache holds copies of values that are slower to look up where they are kept.
+// An entry only has to be kept until the value it copies expires.
+type Cache interface {
+	// Set stores value under key until the given time.
+	Set(ctx context.Context, key string, value []byte, until time.Time) error
+	// Get returns the value stored under key and whether it was found.
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Delete removes key. Deleting an unknown key is not an error.
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,141 @@
+package hotstorage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func testDenylist(t *testing.T, d Denylist, fastForward func(time.Duration)) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("denied until expiry", func(t *testing.T) {
+		if err := d.Deny(ctx, "a", time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		denied, err := d.IsDenied(ctx, "a")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if !denied {
+			t.Error("expected a to be denied")
+		}
+
+		fastForward(2 * time.Second)
+		denied, err = d.IsDenied(ctx, "a")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if denied {
+			t.Error("expected a to have expired")
+		}
+	})
+
+	t.Run("unknown id is not denied", func(t *testing.T) {
+		denied, err := d.IsDenied(ctx, "b")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if denied {
+			t.Error("expected b not to be denied")
+		}
+	})
+
+	t.Run("already expired is not stored", func(t *testing.T) {
+		if err := d.Deny(ctx, "c", time.Now().Add(-time.Second)); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		denied, err := d.IsDenied(ctx, "c")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if denied {
+			t.Error("expected c not to be denied")
+		}
+	})
+}
+
+func testCache(t *testing.T, c Cache, fastForward func(time.Duration)) {
+	t.Helper()
+	ctx := context.Background()
+
+	t.Run("set until expiry", func(t *testing.T) {
+		if err := c.Set(ctx, "a", []byte("value"), time.Now().Add(time.Second)); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		value, ok, err := c.Get(ctx, "a")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if !ok || string(value) != "value" {
+			t.Errorf("expected value, got %q", value)
+		}
+
+		fastForward(2 * time.Second)
+		_, ok, err = c.Get(ctx, "a")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if ok {
+			t.Error("expected a to have expired")
+		}
+	})
+
+	t.Run("unknown key is not found", func(t *testing.T) {
+		_, ok, err := c.Get(ctx, "b")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if ok {
+			t.Error("expected b not to be found")
+		}
+	})
+
+	t.Run("deleted key is not found", func(t *testing.T) {
+		if err := c.Set(ctx, "c", []byte("value"), time.Now().Add(time.Minute)); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if err := c.Delete(ctx, "c"); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		_, ok, err := c.Get(ctx, "c")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if ok {
+			t.Error("expected c not to be found")
+		}
+		if err = c.Delete(ctx, "c"); err != nil {
+			t.Errorf("expected no error deleting an unknown key, got %s", err)
+		}
+	})
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory()
+	fastForward := func(d time.Duration) {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for k, v := range m.entries {
+			m.entries[k] = v.Add(-d)
+		}
+		for k, v := range m.cache {
+			m.cache[k] = cacheEntry{value: v.value, until: v.until.Add(-d)}
+		}
+	}
+	testDenylist(t, m, fastForward)
+	testCache(t, m, fastForward)
+}
+
+func TestRedis(t *testing.T) {
+	s := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	testDenylist(t, NewRedis(client), s.FastForward)
+	testCache(t, NewRedis(client), s.FastForward)
+}
@@ -0,0 +1,140 @@
+package hotstorage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ Denylist = (*Memory)(nil)
+	_ Cache    = (*Memory)(nil)
+)
+
+// sweepInterval is the minimum time between purges of expired entries.
+const sweepInterval = time.Minute
+
+// Memory is an in-memory implementation of the [Denylist] and [Cache] interfaces.
+// It is not shared between instances of the service and is meant
+// for development and single instance deployments.
+type Memory struct {
+	mu        sync.RWMutex
+	entries   map[string]time.Time
+	cache     map[string]cacheEntry
+	lastSweep time.Time
+}
+
+type cacheEntry struct {
+	value []byte
+	until time.Time
+}
+
+// NewMemory returns an empty [Memory] hot storage.
+func NewMemory() *Memory {
+	return &Memory{
+		entries:   make(map[string]time.Time),
+		cache:     make(map[string]cacheEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Deny adds id to the denylist until the given time.
+// Expired entries are purged at most every [sweepInterval].
+func (x *Memory) Deny(ctx context.Context, id string, until time.Time) error {
+	_, span := tracer.Start(ctx, "Memory.Deny", trace.WithAttributes(attribute.String("id", id)))
+	defer span.End()
+
+	now := time.Now()
+	if !until.After(now) {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.entries[id] = until
+	x.sweep(now)
+
+	return nil
+}
+
+// IsDenied reports whether id is on the denylist and has not expired yet.
+func (x *Memory) IsDenied(ctx context.Context, id string) (bool, error) {
+	_, span := tracer.Start(ctx, "Memory.IsDenied", trace.WithAttributes(attribute.String("id", id)))
+	defer span.End()
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	until, ok := x.entries[id]
+	return ok && until.After(time.Now()), nil
+}
+
+// Set stores value under key until the given time.
+// Expired entries are purged at most every [sweepInterval].
+func (x *Memory) Set(ctx context.Context, key string, value []byte, until time.Time) error {
+	_, span := tracer.Start(ctx, "Memory.Set", trace.WithAttributes(attribute.String("key", key)))
+	defer span.End()
+
+	now := time.Now()
+	if !until.After(now) {
+		return nil
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.cache[key] = cacheEntry{value: append([]byte(nil), value...), until: until}
+	x.sweep(now)
+
+	return nil
+}
+
+// Get returns the value stored under key if it has not expired yet.
+func (x *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	_, span := tracer.Start(ctx, "Memory.Get", trace.WithAttributes(attribute.String("key", key)))
+	defer span.End()
+
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	entry, ok := x.cache[key]
+	if !ok || !entry.until.After(time.Now()) {
+		return nil, false, nil
+	}
+	return append([]byte(nil), entry.value...), true, nil
+}
+
+// Delete removes key.
+func (x *Memory) Delete(ctx context.Context, key string) error {
+	_, span := tracer.Start(ctx, "Memory.Delete", trace.WithAttributes(attribute.String("key", key)))
+	defer span.End()
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	delete(x.cache, key)
+	return nil
+}
+
+// sweep purges expired entries if the last sweep was at least [sweepInterval] ago.
+// x.mu must be held.
+func (x *Memory) sweep(now time.Time) {
+	if now.Sub(x.lastSweep) < sweepInterval {
+		return
+	}
+	for k, v := range x.entries {
+		if !v.After(now) {
+			delete(x.entries, k)
+		}
+	}
+	for k, v := range x.cache {
+		if !v.until.After(now) {
+			delete(x.cache, k)
+		}
+	}
+	x.lastSweep = now
+}
@@ -0,0 +1,105 @@
+package hotstorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	_ Denylist = (*Redis)(nil)
+	_ Cache    = (*Redis)(nil)
+)
+
+const (
+	// denylistPrefix namespaces the denylist keys in Redis.
+	denylistPrefix = "denylist:"
+	// cachePrefix namespaces the cache keys in Redis.
+	cachePrefix = "cache:"
+)
+
+// Redis is a Redis implementation of the [Denylist] and [Cache] interfaces.
+// Entries are stored with a TTL, so Redis expires them on its own.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis returns a new [Redis] hot storage.
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+// Deny adds id to the denylist until the given time.
+func (x *Redis) Deny(ctx context.Context, id string, until time.Time) error {
+	ctx, span := tracer.Start(ctx, "Redis.Deny", trace.WithAttributes(attribute.String("id", id)))
+	defer span.End()
+
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := x.client.Set(ctx, denylistPrefix+id, 1, ttl).Err(); err != nil {
+		return fmt.Errorf("hotstorage: denying %s, %w", id, err)
+	}
+	return nil
+}
+
+// IsDenied reports whether id is on the denylist.
+func (x *Redis) IsDenied(ctx context.Context, id string) (bool, error) {
+	ctx, span := tracer.Start(ctx, "Redis.IsDenied", trace.WithAttributes(attribute.String("id", id)))
+	defer span.End()
+
+	n, err := x.client.Exists(ctx, denylistPrefix+id).Result()
+	if err != nil {
+		return false, fmt.Errorf("hotstorage: checking %s, %w", id, err)
+	}
+	return n > 0, nil
+}
+
+// Set stores value under key until the given time.
+func (x *Redis) Set(ctx context.Context, key string, value []byte, until time.Time) error {
+	ctx, span := tracer.Start(ctx, "Redis.Set", trace.WithAttributes(attribute.String("key", key)))
+	defer span.End()
+
+	ttl := time.Until(until)
+	if ttl <= 0 {
+		return nil
+	}
+
+	if err := x.client.Set(ctx, cachePrefix+key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("hotstorage: setting %s, %w", key, err)
+	}
+	return nil
+}
+
+// Get returns the value stored under key.
+func (x *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, span := tracer.Start(ctx, "Redis.Get", trace.WithAttributes(attribute.String("key", key)))
+	defer span.End()
+
+	value, err := x.client.Get(ctx, cachePrefix+key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("hotstorage: getting %s, %w", key, err)
+	}
+	return value, true, nil
+}
+
+// Delete removes key.
+func (x *Redis) Delete(ctx context.Context, key string) error {
+	ctx, span := tracer.Start(ctx, "Redis.Delete", trace.WithAttributes(attribute.String("key", key)))
+	defer span.End()
+
+	if err := x.client.Del(ctx, cachePrefix+key).Err(); err != nil {
+		return fmt.Errorf("hotstorage: deleting %s, %w", key, err)
+	}
+	return nil
+}
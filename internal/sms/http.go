@@ -0,0 +1,81 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ Sender = (*HTTPSender)(nil)
+
+// httpTimeout bounds a single request to the provider.
+const httpTimeout = 10 * time.Second
+
+// HTTPSender sends SMS through a provider with a JSON HTTP API.
+// Every SMS is a POST of {"from", "to", "body"} to the URL of the provider,
+// authenticated with a bearer token. Any 2xx response is a success.
+type HTTPSender struct {
+	client *http.Client
+	url    string
+	token  string
+	from   string
+}
+
+// NewHTTPSender returns a [HTTPSender] that posts to url as from, the sender ID or number shown to the recipient.
+// client defaults to an [http.Client] with a timeout.
+func NewHTTPSender(client *http.Client, url, token, from string) (*HTTPSender, error) {
+	if url == "" {
+		return nil, errors.New("sms: http sender url is empty")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+	return &HTTPSender{client: client, url: url, token: token, from: from}, nil
+}
+
+// SendSMS posts the SMS to the provider.
+func (x *HTTPSender) SendSMS(ctx context.Context, sms SMS) error {
+	ctx, span := tracer.Start(ctx, "SendSMS", trace.WithAttributes(sms.TraceAttributes()...))
+	defer span.End()
+
+	body, err := json.Marshal(struct {
+		From string `json:"from"`
+		To   string `json:"to"`
+		Body string `json:"body"`
+	}{From: x.from, To: sms.To, Body: sms.Body})
+	if err != nil {
+		return fmt.Errorf("sms: encoding request, %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, x.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("sms: creating request, %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if x.token != "" {
+		req.Header.Set("Authorization", "Bearer "+x.token)
+	}
+
+	res, err := x.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sms: sending request, %w", err)
+	}
+	defer res.Body.Close()
+	span.SetAttributes(attribute.Int("status", res.StatusCode))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("sms: provider responded %s, %s", res.Status, bytes.TrimSpace(msg))
+	}
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, res.Body)
+	return nil
+}
@@ -0,0 +1,65 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPSender(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if got["to"] == "+10000000000" {
+			http.Error(w, "unknown number", http.StatusUnprocessableEntity)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(srv.Close)
+
+	sender, err := NewHTTPSender(srv.Client(), srv.URL, "token", "Identity")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		if err := sender.SendSMS(context.Background(), SMS{To: "+46701234567", Body: "123456"}); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if got["from"] != "Identity" || got["to"] != "+46701234567" || got["body"] != "123456" {
+			t.Errorf("unexpected request %v", got)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		if err := sender.SendSMS(context.Background(), SMS{To: "+10000000000", Body: "123456"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		s, err := NewHTTPSender(srv.Client(), srv.URL, "wrong", "Identity")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		if err = s.SendSMS(context.Background(), SMS{To: "+46701234567", Body: "123456"}); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("missing url", func(t *testing.T) {
+		if _, err := NewHTTPSender(nil, "", "token", "Identity"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
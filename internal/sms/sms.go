@@ -0,0 +1,79 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"log/slog"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("sms")
+
+const IngestedEvent = "sms_ingested"
+
+type SMS struct {
+	// To is an E.164 phone number.
+	To   string
+	Body string
+}
+
+// TraceAttributes leaves out the body, which holds one time codes.
+func (x SMS) TraceAttributes() []attribute.KeyValue {
+	return []attribute.KeyValue{
+		attribute.String("to", x.To),
+		attribute.Int("body length", len(x.Body)),
+	}
+}
+
+// Publisher publishes a message on a subject. [*nats.Conn] implements it.
+type Publisher interface {
+	Publish(subject string, data []byte) error
+}
+
+var _ Publisher = (*nats.Conn)(nil)
+
+// Ingest publishes an [SMS] as an [IngestedEvent] to be sent by a worker.
+func Ingest(ctx context.Context, publisher Publisher, sms SMS) error {
+	_, span := tracer.Start(ctx, "Ingest", trace.WithAttributes(sms.TraceAttributes()...))
+	defer span.End()
+
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(sms); err != nil {
+		return err
+	}
+	if err := publisher.Publish(IngestedEvent, buf.Bytes()); err != nil {
+		return err
+	}
+	return nil
+}
+
+type Sender interface {
+	SendSMS(ctx context.Context, sms SMS) error
+}
+
+// NoOpSender is a no-op implementation of the Sender interface.
+// It logs a fake SMS send to the console.
+type NoOpSender struct{}
+
+func NewNoOpSender() *NoOpSender {
+	return &NoOpSender{}
+}
+
+// SendSMS logs the SMS to the console.
+func (x *NoOpSender) SendSMS(ctx context.Context, sms SMS) error {
+	ctx, span := tracer.Start(ctx, "SendSMS", trace.WithAttributes(sms.TraceAttributes()...))
+	defer span.End()
+
+	slog.InfoContext(
+		ctx,
+		"no-op sms sender: sending sms",
+		"to", sms.To,
+		"body", sms.Body,
+	)
+	return nil
+}
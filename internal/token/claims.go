@@ -0,0 +1,161 @@
+package token
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/google/uuid"
+)
+
+// Type is the type of a token, stored in its typ claim.
+type Type string
+
+const (
+	TypeAccess  Type = "access"
+	TypeRefresh Type = "refresh"
+)
+
+// Registered claim names. Custom claims can not use them.
+const (
+	claimID        = "jti"
+	claimSubject   = "sub"
+	claimIssuer    = "iss"
+	claimAudience  = "aud"
+	claimType      = "typ"
+	claimIssuedAt  = "iat"
+	claimNotBefore = "nbf"
+	claimExpires   = "exp"
+)
+
+var registeredClaims = map[string]struct{}{
+	claimID:        {},
+	claimSubject:   {},
+	claimIssuer:    {},
+	claimAudience:  {},
+	claimType:      {},
+	claimIssuedAt:  {},
+	claimNotBefore: {},
+	claimExpires:   {},
+}
+
+// ClaimScope is the custom claim holding the space separated scopes of a token, see RFC 7662.
+const ClaimScope = "scope"
+
+// ClaimSession is the custom claim holding the ID of the session a token belongs to.
+// Denying the session ID revokes every token of the session.
+const ClaimSession = "sid"
+
+// Params define the claims of a token to make.
+type Params struct {
+	// Subject is the ID of the user the token is made for.
+	Subject uuid.UUID
+	// Custom claims are added next to the registered ones and can not override them.
+	Custom map[string]string
+	// Duration overrides the access or refresh duration of the maker if set.
+	Duration time.Duration
+	// NotAfter caps the expiry of the token if set, like the absolute timeout of its session.
+	NotAfter time.Time
+	// DB is where a [Maker] that stores its tokens writes them, usually the transaction the token
+	// is issued in, so it is only stored if that commits. Makers use their own database if nil.
+	DB database.Querier
+}
+
+// Claims are the claims of a token.
+type Claims struct {
+	// ID uniquely identifies the token, it is used to revoke it.
+	ID        string
+	Subject   uuid.UUID
+	Issuer    string
+	Audience  string
+	Type      Type
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	Custom    map[string]string
+}
+
+// Scopes returns the scopes in the [ClaimScope] custom claim.
+func (x Claims) Scopes() []string {
+	return strings.Fields(x.Custom[ClaimScope])
+}
+
+// SessionID returns the session in the [ClaimSession] custom claim, or [uuid.Nil].
+func (x Claims) SessionID() uuid.UUID {
+	id, err := uuid.Parse(x.Custom[ClaimSession])
+	if err != nil {
+		return uuid.Nil
+	}
+	return id
+}
+
+// newClaims returns the [Claims] of a new token.
+// Times are truncated to seconds, the precision they are encoded with.
+func newClaims(typ Type, dur time.Duration, params Params, opts MakerOpts) (Claims, error) {
+	if params.Subject == uuid.Nil {
+		return Claims{}, fmt.Errorf("token: making %s token, subject is required", typ)
+	}
+	for k := range params.Custom {
+		if _, ok := registeredClaims[k]; ok {
+			return Claims{}, fmt.Errorf("token: making %s token, custom claim %q is registered", typ, k)
+		}
+	}
+
+	if params.Duration > 0 {
+		dur = params.Duration
+	}
+	now := time.Now().Truncate(time.Second)
+	expires := now.Add(dur)
+	if notAfter := params.NotAfter.Truncate(time.Second); !params.NotAfter.IsZero() && notAfter.Before(expires) {
+		expires = notAfter
+	}
+	if !expires.After(now) {
+		return Claims{}, fmt.Errorf("token: making %s token, it would already be expired", typ)
+	}
+
+	return Claims{
+		ID:        uuid.New().String(),
+		Subject:   params.Subject,
+		Issuer:    opts.Issuer,
+		Audience:  opts.Audience,
+		Type:      typ,
+		IssuedAt:  now,
+		ExpiresAt: expires,
+		Custom:    params.Custom,
+	}, nil
+}
+
+// check returns [ErrInvalid] if claims are not of the expected [Type]
+// or were not issued by and for the given opts.
+func (x Claims) check(expected Type, opts MakerOpts) error {
+	if x.Type != expected {
+		return fmt.Errorf("%w, expected %s token, got %q", ErrInvalid, expected, x.Type)
+	}
+	if x.Issuer != opts.Issuer {
+		return fmt.Errorf("%w, unexpected issuer %q", ErrInvalid, x.Issuer)
+	}
+	if x.Audience != opts.Audience {
+		return fmt.Errorf("%w, unexpected audience %q", ErrInvalid, x.Audience)
+	}
+	if x.ID == "" || x.Subject == uuid.Nil {
+		return fmt.Errorf("%w, missing jti or sub", ErrInvalid)
+	}
+	return nil
+}
+
+// customClaims returns every claim in m that is not registered and has a string value.
+func customClaims(m map[string]any) map[string]string {
+	custom := make(map[string]string)
+	for k, v := range m {
+		if _, ok := registeredClaims[k]; ok {
+			continue
+		}
+		if s, ok := v.(string); ok {
+			custom[k] = s
+		}
+	}
+	if len(custom) == 0 {
+		return nil
+	}
+	return custom
+}
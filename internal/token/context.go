@@ -0,0 +1,16 @@
+package token
+
+import "context"
+
+type claimsKey struct{}
+
+// NewContext returns a copy of ctx that carries the [Claims] of a verified token.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// FromContext returns the [Claims] carried by ctx, if any.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(Claims)
+	return claims, ok
+}
@@ -0,0 +1,239 @@
+package token
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var (
+	_ Maker           = (*JWTMaker)(nil)
+	_ PublicKeySource = (*JWTMaker)(nil)
+)
+
+// Signing algorithms of a [JWTMaker].
+const (
+	JWTAlgorithmEdDSA = "EdDSA"
+	JWTAlgorithmES256 = "ES256"
+)
+
+// JWTMaker makes signed JWTs for consumers that do not understand PASETO.
+// Tokens are signed with the key derived from the active key of its [KeyRing],
+// every key in the ring is used as a seed. The ID of that key is stored in the kid header.
+type JWTMaker struct {
+	opts      MakerOpts
+	ring      *KeyRing
+	algorithm string
+	parser    *jwt.Parser
+}
+
+// BootstrapJWTMaker returns a new [JWTMaker] signing with either
+// [JWTAlgorithmEdDSA] or [JWTAlgorithmES256]. Reloading ring rotates the keys of the maker.
+func BootstrapJWTMaker(opts MakerOpts, ring *KeyRing, algorithm string) (*JWTMaker, error) {
+	if ring == nil {
+		return nil, errors.New("token: key ring is nil")
+	}
+	if algorithm != JWTAlgorithmEdDSA && algorithm != JWTAlgorithmES256 {
+		return nil, fmt.Errorf("token: unsupported JWT algorithm %q", algorithm)
+	}
+
+	return &JWTMaker{
+		opts:      opts,
+		ring:      ring,
+		algorithm: algorithm,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{algorithm}),
+			jwt.WithExpirationRequired(),
+		),
+	}, nil
+}
+
+func (x *JWTMaker) MakeAccessToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(TypeAccess, x.opts.AccessDuration, params)
+}
+
+func (x *JWTMaker) MakeRefreshToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(TypeRefresh, x.opts.RefreshDuration, params)
+}
+
+func (x *JWTMaker) make(typ Type, dur time.Duration, params Params) (SafeString, Claims, error) {
+	claims, err := newClaims(typ, dur, params, x.opts)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	m := jwt.MapClaims{}
+	for k, v := range claims.Custom {
+		m[k] = v
+	}
+	m[claimID] = claims.ID
+	m[claimSubject] = claims.Subject.String()
+	m[claimIssuer] = claims.Issuer
+	m[claimAudience] = claims.Audience
+	m[claimType] = string(claims.Type)
+	m[claimIssuedAt] = jwt.NewNumericDate(claims.IssuedAt)
+	m[claimNotBefore] = jwt.NewNumericDate(claims.IssuedAt)
+	m[claimExpires] = jwt.NewNumericDate(claims.ExpiresAt)
+
+	key := x.ring.Active()
+	signingKey, err := x.signingKey(key)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(x.algorithm), m)
+	token.Header["kid"] = key.ID
+
+	s, err := token.SignedString(signingKey)
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("token: signing JWT, %w", err)
+	}
+
+	return fromString(s), claims, nil
+}
+
+func (x *JWTMaker) Verify(ctx context.Context, t SafeString, expected Type) (Claims, error) {
+	m := jwt.MapClaims{}
+	if _, err := x.parser.ParseWithClaims(string(t), m, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, err := x.ring.Get(kid)
+		if err != nil {
+			return nil, err
+		}
+		return x.verificationKey(key)
+	}); err != nil {
+		return Claims{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+
+	claims, err := jwtClaims(m)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err = verifyClaims(ctx, x.opts, claims, expected); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// PublicKeys returns the public key of every key in the ring,
+// including the verify-only ones so tokens made before a rotation stay verifiable.
+func (x *JWTMaker) PublicKeys() []PublicKey {
+	keys := x.ring.Keys()
+	pub := make([]PublicKey, 0, len(keys))
+	for _, k := range keys {
+		switch x.algorithm {
+		case JWTAlgorithmEdDSA:
+			pub = append(pub, PublicKey{ID: k.ID, Algorithm: AlgorithmEd25519, Key: publicKey(k)})
+		case JWTAlgorithmES256:
+			ecdhKey, err := ecdsaKey(k).PublicKey.ECDH()
+			if err != nil {
+				continue
+			}
+			pub = append(pub, PublicKey{ID: k.ID, Algorithm: AlgorithmP256, Key: ecdhKey.Bytes()})
+		}
+	}
+	return pub
+}
+
+func (x *JWTMaker) signingKey(k Key) (any, error) {
+	switch x.algorithm {
+	case JWTAlgorithmEdDSA:
+		return ed25519.NewKeyFromSeed(k.Secret), nil
+	case JWTAlgorithmES256:
+		return ecdsaKey(k), nil
+	}
+	return nil, fmt.Errorf("token: unsupported JWT algorithm %q", x.algorithm)
+}
+
+func (x *JWTMaker) verificationKey(k Key) (any, error) {
+	switch x.algorithm {
+	case JWTAlgorithmEdDSA:
+		return publicKey(k), nil
+	case JWTAlgorithmES256:
+		return &ecdsaKey(k).PublicKey, nil
+	}
+	return nil, fmt.Errorf("token: unsupported JWT algorithm %q", x.algorithm)
+}
+
+// ecdsaKey returns the P-256 private key derived from k.
+// The secret is reduced into the range of valid scalars, [1, N-1].
+func ecdsaKey(k Key) *ecdsa.PrivateKey {
+	curve := elliptic.P256()
+	d := new(big.Int).SetBytes(k.Secret)
+	d.Mod(d, new(big.Int).Sub(curve.Params().N, big.NewInt(1)))
+	d.Add(d, big.NewInt(1))
+
+	// The scalar is in range, so this can not fail.
+	ecdhKey, _ := ecdh.P256().NewPrivateKey(d.FillBytes(make([]byte, KeySize)))
+	point := ecdhKey.PublicKey().Bytes()
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(point[1 : 1+KeySize]),
+			Y:     new(big.Int).SetBytes(point[1+KeySize:]),
+		},
+		D: d,
+	}
+}
+
+// jwtClaims reads the [Claims] of a parsed JWT.
+func jwtClaims(m jwt.MapClaims) (Claims, error) {
+	var (
+		claims Claims
+		ok     bool
+		err    error
+	)
+	if claims.ID, ok = m[claimID].(string); !ok {
+		return Claims{}, fmt.Errorf("%w, reading jti", ErrInvalid)
+	}
+	sub, err := m.GetSubject()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, reading sub, %w", ErrInvalid, err)
+	}
+	if claims.Subject, err = uuid.Parse(sub); err != nil {
+		return Claims{}, fmt.Errorf("%w, parsing sub, %w", ErrInvalid, err)
+	}
+	if claims.Issuer, err = m.GetIssuer(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading iss, %w", ErrInvalid, err)
+	}
+	aud, err := m.GetAudience()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, reading aud, %w", ErrInvalid, err)
+	}
+	if len(aud) != 1 {
+		return Claims{}, fmt.Errorf("%w, expected a single aud, got %d", ErrInvalid, len(aud))
+	}
+	claims.Audience = aud[0]
+	typ, ok := m[claimType].(string)
+	if !ok {
+		return Claims{}, fmt.Errorf("%w, reading typ", ErrInvalid)
+	}
+	claims.Type = Type(typ)
+	iat, err := m.GetIssuedAt()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, reading iat, %w", ErrInvalid, err)
+	}
+	if iat == nil {
+		return Claims{}, fmt.Errorf("%w, missing iat", ErrInvalid)
+	}
+	claims.IssuedAt = iat.Time
+	// exp is required by the parser.
+	exp, err := m.GetExpirationTime()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, reading exp, %w", ErrInvalid, err)
+	}
+	claims.ExpiresAt = exp.Time
+	claims.Custom = customClaims(m)
+
+	return claims, nil
+}
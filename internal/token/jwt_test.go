@@ -0,0 +1,277 @@
+package token
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+var jwtAlgorithms = []string{JWTAlgorithmEdDSA, JWTAlgorithmES256}
+
+func bootstrapJWT(t *testing.T, algorithm string) *JWTMaker {
+	t.Helper()
+
+	t.Run("nil key ring", func(t *testing.T) {
+		if _, err := BootstrapJWTMaker(testOpts(), nil, algorithm); err == nil {
+			t.Error("expected err with nil key ring")
+		}
+	})
+
+	ring, err := NewKeyRing(testKey("test", 's'))
+	if err != nil {
+		t.Fatalf("expected no err, got %s", err.Error())
+	}
+
+	t.Run("unsupported algorithm", func(t *testing.T) {
+		if _, err := BootstrapJWTMaker(testOpts(), ring, "HS256"); err == nil {
+			t.Error("expected err with unsupported algorithm")
+		}
+	})
+
+	maker, err := BootstrapJWTMaker(testOpts(), ring, algorithm)
+	if err != nil {
+		t.Errorf("expected no err, got %s", err.Error())
+	}
+	return maker
+}
+
+func TestJWTMakeAccessToken(t *testing.T) {
+	for _, alg := range jwtAlgorithms {
+		t.Run(alg, func(t *testing.T) {
+			b := bootstrapJWT(t, alg)
+			s, claims, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err.Error())
+			}
+			if s == "" {
+				t.Error("token is empty")
+			}
+			if claims.Type != TypeAccess {
+				t.Errorf("expected type %s, got %s", TypeAccess, claims.Type)
+			}
+
+			t.Run("subject is required", func(t *testing.T) {
+				if _, _, err := b.MakeAccessToken(context.Background(), Params{}); err == nil {
+					t.Error("expected error without subject")
+				}
+			})
+
+			t.Run("registered custom claim", func(t *testing.T) {
+				_, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New(), Custom: map[string]string{"exp": "ass"}})
+				if err == nil {
+					t.Error("expected error with registered custom claim")
+				}
+			})
+		})
+	}
+}
+
+func TestJWTMakeRefreshToken(t *testing.T) {
+	for _, alg := range jwtAlgorithms {
+		t.Run(alg, func(t *testing.T) {
+			b := bootstrapJWT(t, alg)
+			s, claims, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New()})
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err.Error())
+			}
+			if s == "" {
+				t.Error("token is empty")
+			}
+			if claims.Type != TypeRefresh {
+				t.Errorf("expected type %s, got %s", TypeRefresh, claims.Type)
+			}
+		})
+	}
+}
+
+func TestJWTVerify(t *testing.T) {
+	for _, alg := range jwtAlgorithms {
+		t.Run(alg, func(t *testing.T) {
+			t.Run("OK", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				params := Params{Subject: uuid.New(), Custom: map[string]string{"strategy": "Credentials"}}
+				s, made, err := b.MakeAccessToken(context.Background(), params)
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				claims, err := b.Verify(context.Background(), s, TypeAccess)
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if claims.ID == "" || claims.ID != made.ID {
+					t.Errorf("expected token ID %s, got %s", made.ID, claims.ID)
+				}
+				if claims.Subject != params.Subject {
+					t.Errorf("expected subject %s, got %s", params.Subject, claims.Subject)
+				}
+				if claims.Issuer != b.opts.Issuer || claims.Audience != b.opts.Audience {
+					t.Errorf("expected issuer and audience, got %s and %s", claims.Issuer, claims.Audience)
+				}
+				if !claims.ExpiresAt.Equal(made.ExpiresAt) || !claims.ExpiresAt.After(time.Now()) {
+					t.Error("expected expiry in the future")
+				}
+				if claims.Custom["strategy"] != "Credentials" || len(claims.Custom) != 1 {
+					t.Errorf("expected custom claims, got %v", claims.Custom)
+				}
+			})
+
+			t.Run("unique IDs", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				_, a, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				_, c, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if a.ID == c.ID {
+					t.Error("expected unique token IDs")
+				}
+			})
+
+			t.Run("invalid returns error", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				if _, err := b.Verify(context.Background(), fromString("ass"), TypeAccess); !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, got %v", err)
+				}
+			})
+
+			t.Run("tampered returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				parts := strings.Split(string(s), ".")
+				other, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				parts[1] = strings.Split(string(other), ".")[1]
+				tampered := fromString(strings.Join(parts, "."))
+				if _, err = b.Verify(context.Background(), tampered, TypeAccess); !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, got %v", err)
+				}
+			})
+
+			t.Run("unsigned returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				s, err := jwt.NewWithClaims(jwt.SigningMethodNone, jwt.MapClaims{
+					"jti": uuid.New().String(),
+					"sub": uuid.New().String(),
+					"exp": jwt.NewNumericDate(time.Now().Add(time.Minute)),
+				}).SignedString(jwt.UnsafeAllowNoneSignatureType)
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if _, err = b.Verify(context.Background(), fromString(s), TypeAccess); !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, got %v", err)
+				}
+			})
+
+			t.Run("unexpected type returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				s, _, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, got %v", err)
+				}
+			})
+
+			t.Run("unexpected audience returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				other := *b
+				other.opts.Audience = "other"
+				if _, err = other.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid, got %v", err)
+				}
+			})
+
+			t.Run("denied returns ErrRevoked", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				s, claims, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if err = b.opts.Denylist.Deny(context.Background(), claims.ID, claims.ExpiresAt); err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrRevoked) {
+					t.Errorf("expected ErrRevoked, got %v", err)
+				}
+			})
+
+			t.Run("rotated key", func(t *testing.T) {
+				b := bootstrapJWT(t, alg)
+				s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+				if err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if err = b.ring.Reload(testKey("next", 'n'), testKey("test", 's')); err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if _, err = b.Verify(context.Background(), s, TypeAccess); err != nil {
+					t.Errorf("expected verify-only key to verify, got %s", err.Error())
+				}
+				if err = b.ring.Reload(testKey("next", 'n')); err != nil {
+					t.Fatalf("expected no error, got %s", err.Error())
+				}
+				if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+					t.Errorf("expected ErrInvalid with removed key, got %v", err)
+				}
+			})
+		})
+	}
+}
+
+func TestJWTPublicKeys(t *testing.T) {
+	for _, alg := range jwtAlgorithms {
+		t.Run(alg, func(t *testing.T) {
+			b := bootstrapJWT(t, alg)
+			keys := b.PublicKeys()
+			if len(keys) != 1 || keys[0].ID != "test" {
+				t.Fatalf("expected key test, got %v", keys)
+			}
+
+			// Verify offline, with nothing but the published key.
+			s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err.Error())
+			}
+			var pub any
+			switch alg {
+			case JWTAlgorithmEdDSA:
+				pub = ed25519.PublicKey(keys[0].Key)
+			case JWTAlgorithmES256:
+				if _, err = ecdh.P256().NewPublicKey(keys[0].Key); err != nil {
+					t.Fatalf("expected a P-256 point, got %s", err.Error())
+				}
+				pub = &ecdsa.PublicKey{
+					Curve: elliptic.P256(),
+					X:     new(big.Int).SetBytes(keys[0].Key[1 : 1+KeySize]),
+					Y:     new(big.Int).SetBytes(keys[0].Key[1+KeySize:]),
+				}
+			}
+			if _, err = jwt.Parse(string(s), func(*jwt.Token) (any, error) { return pub, nil }); err != nil {
+				t.Errorf("expected token to verify with the public key, got %s", err.Error())
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package token
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// KeySize is the size of every [Key] secret in bytes.
+const KeySize = 32
+
+// ErrUnknownKey is returned when a token was made with a key that is not in the [KeyRing].
+var ErrUnknownKey = errors.New("token: unknown key")
+
+// Key is a secret identified by its ID. The ID is stored with every token made with it,
+// so it should not be reused for a different secret.
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// KeyRing holds the active [Key] new tokens are made with and the keys
+// that are only used to verify tokens made before a rotation.
+// It is safe for concurrent use and can be reloaded without a restart.
+type KeyRing struct {
+	mu     sync.RWMutex
+	active Key
+	keys   map[string]Key
+}
+
+// NewKeyRing returns a new [KeyRing], see [KeyRing.Reload].
+func NewKeyRing(active Key, verifyOnly ...Key) (*KeyRing, error) {
+	var x KeyRing
+	if err := x.Reload(active, verifyOnly...); err != nil {
+		return nil, err
+	}
+	return &x, nil
+}
+
+// Reload replaces every key of the ring. To rotate, pass the new key as active
+// and the previous one as verifyOnly until the tokens made with it have expired.
+// The ring is left untouched if any key is invalid.
+func (x *KeyRing) Reload(active Key, verifyOnly ...Key) error {
+	keys := make(map[string]Key, len(verifyOnly)+1)
+	for _, k := range append([]Key{active}, verifyOnly...) {
+		if k.ID == "" {
+			return errors.New("token: key ID is empty")
+		}
+		if len(k.Secret) != KeySize {
+			return fmt.Errorf("token: key %q, expected %d bytes, got %d", k.ID, KeySize, len(k.Secret))
+		}
+		if _, ok := keys[k.ID]; ok {
+			return fmt.Errorf("token: duplicate key %q", k.ID)
+		}
+		keys[k.ID] = Key{ID: k.ID, Secret: append([]byte(nil), k.Secret...)}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	x.active = keys[active.ID]
+	x.keys = keys
+
+	return nil
+}
+
+// Active returns the key new tokens are made with.
+func (x *KeyRing) Active() Key {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	return x.active
+}
+
+// Get returns the key with the given ID.
+// Returns [ErrUnknownKey] if it is not in the ring.
+func (x *KeyRing) Get(id string) (Key, error) {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	k, ok := x.keys[id]
+	if !ok {
+		return Key{}, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return k, nil
+}
+
+// Keys returns every key in the ring, sorted by ID.
+func (x *KeyRing) Keys() []Key {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	keys := make([]Key, 0, len(x.keys))
+	for _, k := range x.keys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+	return keys
+}
+
+// IDs returns the sorted IDs of every key in the ring.
+func (x *KeyRing) IDs() []string {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+
+	ids := make([]string, 0, len(x.keys))
+	for id := range x.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
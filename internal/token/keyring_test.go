@@ -0,0 +1,75 @@
+package token
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func testKey(id string, b byte) Key {
+	return Key{ID: id, Secret: bytes.Repeat([]byte{b}, KeySize)}
+}
+
+func TestNewKeyRing(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ring, err := NewKeyRing(testKey("new", 'n'), testKey("old", 'o'))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if ring.Active().ID != "new" {
+			t.Errorf("expected active key new, got %s", ring.Active().ID)
+		}
+		if ids := ring.IDs(); len(ids) != 2 || ids[0] != "new" || ids[1] != "old" {
+			t.Errorf("expected IDs [new old], got %v", ids)
+		}
+	})
+
+	t.Run("empty ID", func(t *testing.T) {
+		if _, err := NewKeyRing(testKey("", 'n')); err == nil {
+			t.Error("expected error with empty key ID")
+		}
+	})
+
+	t.Run("invalid size", func(t *testing.T) {
+		if _, err := NewKeyRing(Key{ID: "new", Secret: []byte("short")}); err == nil {
+			t.Error("expected error with invalid key size")
+		}
+	})
+
+	t.Run("duplicate ID", func(t *testing.T) {
+		if _, err := NewKeyRing(testKey("new", 'n'), testKey("new", 'o')); err == nil {
+			t.Error("expected error with duplicate key ID")
+		}
+	})
+}
+
+func TestKeyRingReload(t *testing.T) {
+	ring, err := NewKeyRing(testKey("old", 'o'))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	if err = ring.Reload(testKey("new", 'n'), testKey("old", 'o')); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if ring.Active().ID != "new" {
+		t.Errorf("expected active key new, got %s", ring.Active().ID)
+	}
+	if _, err = ring.Get("old"); err != nil {
+		t.Errorf("expected old key, got %s", err.Error())
+	}
+
+	if err = ring.Reload(testKey("", 'n')); err == nil {
+		t.Error("expected error with empty key ID")
+	}
+	if ring.Active().ID != "new" {
+		t.Error("expected ring to be untouched by an invalid reload")
+	}
+
+	if err = ring.Reload(testKey("new", 'n')); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if _, err = ring.Get("old"); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey, got %v", err)
+	}
+}
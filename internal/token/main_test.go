@@ -0,0 +1,58 @@
+//go:build testdb
+// +build testdb
+
+package token
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	_ "github.com/lib/pq"
+
+	"github.com/Salam4nder/identity/internal/config"
+	"github.com/Salam4nder/identity/internal/database/referencetoken"
+	"github.com/stimtech/go-migration/v2"
+)
+
+const migrationFolder = "../database/migrations"
+
+var testConn *sql.DB
+
+func Conn() (*sql.DB, func()) {
+	return testConn, func() {
+		_, err := testConn.Exec(fmt.Sprintf("TRUNCATE %s CASCADE", referencetoken.Tablename))
+		if err != nil {
+			slog.Error(fmt.Sprintf("truncating table %s", referencetoken.Tablename), "err", err)
+		}
+	}
+}
+
+func TestMain(m *testing.M) {
+	cfg := config.PSQLTestConfig()
+
+	db, err := sql.Open(cfg.Driver(), cfg.Addr())
+	if err != nil {
+		slog.Error("token: opening sql", "err", err)
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		slog.Error("token: pinging", "err", err)
+		os.Exit(1)
+	}
+
+	if err := migration.New(db, migration.Config{MigrationFolder: migrationFolder}).Migrate(); err != nil {
+		slog.Error("token: migration", "err", err)
+		os.Exit(1)
+	}
+
+	testConn = db
+	os.Exit(m.Run())
+}
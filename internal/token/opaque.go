@@ -0,0 +1,176 @@
+package token
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/database"
+	"github.com/Salam4nder/identity/internal/database/referencetoken"
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/google/uuid"
+)
+
+var (
+	_ Maker   = (*OpaqueMaker)(nil)
+	_ Revoker = (*OpaqueMaker)(nil)
+)
+
+const (
+	// opaqueTokenSize is the amount of random bytes in an opaque token.
+	opaqueTokenSize = 32
+	// opaqueCachePrefix namespaces the cached claims of opaque tokens.
+	opaqueCachePrefix = "reftoken:"
+)
+
+// OpaqueMaker makes random opaque reference tokens.
+// Only the hash of a token is stored, in the reference tokens table next to its claims,
+// so a token reveals nothing to its holder and can be revoked instantly.
+type OpaqueMaker struct {
+	opts  MakerOpts
+	db    *sql.DB
+	cache hotstorage.Cache
+}
+
+// BootstrapOpaqueMaker returns a new [OpaqueMaker].
+// Verified claims are kept in cache until they expire, cache can be nil to always read the database.
+func BootstrapOpaqueMaker(opts MakerOpts, db *sql.DB, cache hotstorage.Cache) (*OpaqueMaker, error) {
+	if db == nil {
+		return nil, errors.New("token: database is nil")
+	}
+
+	return &OpaqueMaker{opts: opts, db: db, cache: cache}, nil
+}
+
+func (x *OpaqueMaker) MakeAccessToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(ctx, TypeAccess, x.opts.AccessDuration, params)
+}
+
+func (x *OpaqueMaker) MakeRefreshToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(ctx, TypeRefresh, x.opts.RefreshDuration, params)
+}
+
+func (x *OpaqueMaker) make(ctx context.Context, typ Type, dur time.Duration, params Params) (SafeString, Claims, error) {
+	claims, err := newClaims(typ, dur, params, x.opts)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	b := make([]byte, opaqueTokenSize)
+	if _, err = rand.Read(b); err != nil {
+		return "", Claims{}, fmt.Errorf("token: reading random bytes, %w", err)
+	}
+	t := fromString(base64.RawURLEncoding.EncodeToString(b))
+
+	id, err := uuid.Parse(claims.ID)
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("token: parsing jti, %w", err)
+	}
+	var db database.Querier = x.db
+	if params.DB != nil {
+		db = params.DB
+	}
+	if err = referencetoken.Insert(ctx, db, referencetoken.InsertParams{
+		ID:        id,
+		TokenHash: Hash(t),
+		Subject:   claims.Subject,
+		Type:      string(claims.Type),
+		Issuer:    claims.Issuer,
+		Audience:  claims.Audience,
+		Custom:    claims.Custom,
+		IssuedAt:  claims.IssuedAt,
+		ExpiresAt: claims.ExpiresAt,
+	}); err != nil {
+		return "", Claims{}, err
+	}
+
+	return t, claims, nil
+}
+
+func (x *OpaqueMaker) Verify(ctx context.Context, t SafeString, expected Type) (Claims, error) {
+	if t == "" {
+		return Claims{}, fmt.Errorf("%w, empty token", ErrInvalid)
+	}
+
+	claims, err := x.claims(ctx, Hash(t))
+	if err != nil {
+		return Claims{}, err
+	}
+	if !time.Now().Before(claims.ExpiresAt) {
+		return Claims{}, fmt.Errorf("%w, expired", ErrInvalid)
+	}
+	if err = verifyClaims(ctx, x.opts, claims, expected); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// Revoke revokes t in the database and drops its cached claims.
+// Revoking an unknown or already revoked token is not an error.
+func (x *OpaqueMaker) Revoke(ctx context.Context, t SafeString) error {
+	hash := Hash(t)
+	if _, err := referencetoken.RevokeByHash(ctx, x.db, hash, time.Now()); err != nil {
+		return err
+	}
+	if x.cache != nil {
+		if err := x.cache.Delete(ctx, opaqueCachePrefix+hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claims returns the claims stored for hash, from the cache if possible.
+// A failing cache is logged and skipped, the database is the source of truth.
+func (x *OpaqueMaker) claims(ctx context.Context, hash string) (Claims, error) {
+	if x.cache != nil {
+		b, ok, err := x.cache.Get(ctx, opaqueCachePrefix+hash)
+		if err != nil {
+			slog.WarnContext(ctx, "token: reading cached claims", "error", err)
+		}
+		var claims Claims
+		if ok && json.Unmarshal(b, &claims) == nil {
+			return claims, nil
+		}
+	}
+
+	entry, err := referencetoken.ReadByHash(ctx, x.db, hash)
+	if err != nil {
+		if errors.As(err, &database.NotFoundError{}) {
+			return Claims{}, fmt.Errorf("%w, unknown token", ErrInvalid)
+		}
+		return Claims{}, err
+	}
+	if entry.RevokedAt != nil {
+		return Claims{}, ErrRevoked
+	}
+
+	claims := Claims{
+		ID:        entry.ID.String(),
+		Subject:   entry.Subject,
+		Issuer:    entry.Issuer,
+		Audience:  entry.Audience,
+		Type:      Type(entry.Type),
+		IssuedAt:  entry.IssuedAt,
+		ExpiresAt: entry.ExpiresAt,
+		Custom:    entry.Custom,
+	}
+	if x.cache != nil {
+		b, err := json.Marshal(claims)
+		if err == nil {
+			err = x.cache.Set(ctx, opaqueCachePrefix+hash, b, claims.ExpiresAt)
+		}
+		if err != nil {
+			slog.WarnContext(ctx, "token: caching claims", "error", err)
+		}
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,92 @@
+//go:build testdb
+// +build testdb
+
+package token
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func bootstrapOpaque(t *testing.T, cache hotstorage.Cache) *OpaqueMaker {
+	t.Helper()
+
+	db, cleanup := Conn()
+	t.Cleanup(cleanup)
+
+	_, err := BootstrapOpaqueMaker(testOpts(), nil, cache)
+	require.Error(t, err)
+
+	maker, err := BootstrapOpaqueMaker(testOpts(), db, cache)
+	require.NoError(t, err)
+	return maker
+}
+
+func TestOpaqueVerify(t *testing.T) {
+	ctx := context.Background()
+
+	for name, cache := range map[string]hotstorage.Cache{"without cache": nil, "with cache": hotstorage.NewMemory()} {
+		t.Run(name, func(t *testing.T) {
+			t.Run("OK", func(t *testing.T) {
+				b := bootstrapOpaque(t, cache)
+				params := Params{Subject: uuid.New(), Custom: map[string]string{"strategy": "Credentials"}}
+				s, made, err := b.MakeAccessToken(ctx, params)
+				require.NoError(t, err)
+				require.NotEmpty(t, s)
+
+				for range 2 {
+					claims, err := b.Verify(ctx, s, TypeAccess)
+					require.NoError(t, err)
+					require.Equal(t, made.ID, claims.ID)
+					require.Equal(t, params.Subject, claims.Subject)
+					require.Equal(t, TypeAccess, claims.Type)
+					require.Equal(t, params.Custom, claims.Custom)
+					require.True(t, made.ExpiresAt.Equal(claims.ExpiresAt))
+				}
+			})
+
+			t.Run("unknown token returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapOpaque(t, cache)
+				_, err := b.Verify(ctx, fromString("ass"), TypeAccess)
+				require.ErrorIs(t, err, ErrInvalid)
+			})
+
+			t.Run("unexpected type returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapOpaque(t, cache)
+				s, _, err := b.MakeRefreshToken(ctx, Params{Subject: uuid.New()})
+				require.NoError(t, err)
+				_, err = b.Verify(ctx, s, TypeAccess)
+				require.ErrorIs(t, err, ErrInvalid)
+			})
+
+			t.Run("revoked returns ErrRevoked", func(t *testing.T) {
+				b := bootstrapOpaque(t, cache)
+				s, _, err := b.MakeAccessToken(ctx, Params{Subject: uuid.New()})
+				require.NoError(t, err)
+				// Verify first, so the claims are cached when there is a cache.
+				_, err = b.Verify(ctx, s, TypeAccess)
+				require.NoError(t, err)
+
+				require.NoError(t, b.Revoke(ctx, s))
+				_, err = b.Verify(ctx, s, TypeAccess)
+				require.ErrorIs(t, err, ErrRevoked)
+			})
+
+			t.Run("rolled back transaction returns ErrInvalid", func(t *testing.T) {
+				b := bootstrapOpaque(t, cache)
+				tx, err := b.db.BeginTx(ctx, nil)
+				require.NoError(t, err)
+				s, _, err := b.MakeAccessToken(ctx, Params{Subject: uuid.New(), DB: tx})
+				require.NoError(t, err)
+				require.NoError(t, tx.Rollback())
+
+				_, err = b.Verify(ctx, s, TypeAccess)
+				require.ErrorIs(t, err, ErrInvalid)
+			})
+		})
+	}
+}
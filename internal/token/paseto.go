@@ -1,65 +1,185 @@
 package token
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
 )
 
 var _ Maker = (*PasetoMaker)(nil)
 
-// PasetoMaker makes PASETO tokens.
+// footer is the unencrypted footer of every PASETO token.
+type footer struct {
+	// KeyID is the ID of the [Key] the token was made with.
+	KeyID string `json:"kid"`
+}
+
+// PasetoMaker makes v4.local PASETO tokens.
+// Tokens are encrypted with the active key of its [KeyRing],
+// the ID of that key is stored in the footer to find it again when verifying.
 type PasetoMaker struct {
-	accessDur    time.Duration
-	refreshDur   time.Duration
-	symmetricKey paseto.V4SymmetricKey
-	parser       *paseto.Parser
+	opts   MakerOpts
+	ring   *KeyRing
+	parser *paseto.Parser
+}
+
+// BootstrapPasetoMaker returns a new [PasetoMaker].
+// Reloading ring rotates the keys of the maker.
+func BootstrapPasetoMaker(opts MakerOpts, ring *KeyRing) (*PasetoMaker, error) {
+	if ring == nil {
+		return nil, errors.New("token: key ring is nil")
+	}
+
+	return &PasetoMaker{
+		opts:   opts,
+		ring:   ring,
+		parser: pasetoParser(),
+	}, nil
+}
+
+func (x *PasetoMaker) MakeAccessToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(TypeAccess, x.opts.AccessDuration, params)
+}
+
+func (x *PasetoMaker) MakeRefreshToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(TypeRefresh, x.opts.RefreshDuration, params)
+}
+
+func (x *PasetoMaker) make(typ Type, dur time.Duration, params Params) (SafeString, Claims, error) {
+	claims, err := newClaims(typ, dur, params, x.opts)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	key := x.ring.Active()
+	symmetricKey, err := paseto.V4SymmetricKeyFromBytes(key.Secret)
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("token: creating symmetric key, %w", err)
+	}
+	token, err := newPasetoToken(claims, key.ID)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	return fromString(token.V4Encrypt(symmetricKey, nil)), claims, nil
 }
 
-func BootstrapPasetoMaker(
-	accessDur, refreshDur time.Duration,
-	symmetricKey []byte,
-) (*PasetoMaker, error) {
-	k, err := paseto.V4SymmetricKeyFromBytes(symmetricKey)
+func (x *PasetoMaker) Verify(ctx context.Context, t SafeString, expected Type) (Claims, error) {
+	key, err := pasetoKey(x.parser, x.ring, paseto.V4Local, t)
+	if err != nil {
+		return Claims{}, err
+	}
+	symmetricKey, err := paseto.V4SymmetricKeyFromBytes(key.Secret)
+	if err != nil {
+		return Claims{}, fmt.Errorf("token: creating symmetric key, %w", err)
+	}
+	token, err := x.parser.ParseV4Local(symmetricKey, string(t), nil)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+
+	claims, err := pasetoClaims(token)
 	if err != nil {
-		return nil, fmt.Errorf("token: creating symmetric key, %w", err)
+		return Claims{}, err
+	}
+	if err = verifyClaims(ctx, x.opts, claims, expected); err != nil {
+		return Claims{}, err
 	}
 
+	return claims, nil
+}
+
+// pasetoParser returns a parser for every PASETO maker.
+func pasetoParser() *paseto.Parser {
+	// Rules are checked against the time of parsing.
 	p := paseto.MakeParser([]paseto.Rule{
 		paseto.NotExpired(),
-		paseto.ValidAt(time.Now()),
+		paseto.NotBeforeNbf(),
 	},
 	)
-
-	return &PasetoMaker{
-		accessDur:    accessDur,
-		refreshDur:   refreshDur,
-		symmetricKey: k,
-		parser:       &p,
-	}, nil
+	return &p
 }
 
-func (x *PasetoMaker) MakeAccessToken() SafeString {
+// newPasetoToken returns a token with claims and the ID of the key it is made with in its footer.
+func newPasetoToken(claims Claims, keyID string) (paseto.Token, error) {
+	f, err := json.Marshal(footer{KeyID: keyID})
+	if err != nil {
+		return paseto.Token{}, fmt.Errorf("token: marshaling footer, %w", err)
+	}
+
 	token := paseto.NewToken()
-	token.SetIssuedAt(time.Now())
-	token.SetNotBefore(time.Now())
-	token.SetExpiration(time.Now().Add(x.accessDur))
-	return fromString(token.V4Encrypt(x.symmetricKey, nil))
+	for k, v := range claims.Custom {
+		token.SetString(k, v)
+	}
+	token.SetJti(claims.ID)
+	token.SetSubject(claims.Subject.String())
+	token.SetIssuer(claims.Issuer)
+	token.SetAudience(claims.Audience)
+	token.SetString(claimType, string(claims.Type))
+	token.SetIssuedAt(claims.IssuedAt)
+	token.SetNotBefore(claims.IssuedAt)
+	token.SetExpiration(claims.ExpiresAt)
+	token.SetFooter(f)
+
+	return token, nil
 }
 
-func (x *PasetoMaker) MakeRefreshToken() SafeString {
-	token := paseto.NewToken()
-	token.SetIssuedAt(time.Now())
-	token.SetNotBefore(time.Now())
-	token.SetExpiration(time.Now().Add(x.refreshDur))
-	return fromString(token.V4Encrypt(x.symmetricKey, nil))
+// pasetoKey returns the key from ring that t was made with.
+func pasetoKey(parser *paseto.Parser, ring *KeyRing, protocol paseto.Protocol, t SafeString) (Key, error) {
+	b, err := parser.UnsafeParseFooter(protocol, string(t))
+	if err != nil {
+		return Key{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+	var f footer
+	if err = json.Unmarshal(b, &f); err != nil {
+		return Key{}, fmt.Errorf("%w, unmarshaling footer, %w", ErrInvalid, err)
+	}
+	key, err := ring.Get(f.KeyID)
+	if err != nil {
+		return Key{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+	return key, nil
 }
 
-func (x *PasetoMaker) Verify(t SafeString) error {
-	_, err := x.parser.ParseV4Local(x.symmetricKey, string(t), nil)
+// pasetoClaims reads the [Claims] of a parsed token.
+func pasetoClaims(token *paseto.Token) (Claims, error) {
+	var (
+		claims Claims
+		err    error
+	)
+	if claims.ID, err = token.GetJti(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading jti, %w", ErrInvalid, err)
+	}
+	sub, err := token.GetSubject()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, reading sub, %w", ErrInvalid, err)
+	}
+	if claims.Subject, err = uuid.Parse(sub); err != nil {
+		return Claims{}, fmt.Errorf("%w, parsing sub, %w", ErrInvalid, err)
+	}
+	if claims.Issuer, err = token.GetIssuer(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading iss, %w", ErrInvalid, err)
+	}
+	if claims.Audience, err = token.GetAudience(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading aud, %w", ErrInvalid, err)
+	}
+	typ, err := token.GetString(claimType)
 	if err != nil {
-		return fmt.Errorf("token: verifying token, %w", err)
+		return Claims{}, fmt.Errorf("%w, reading typ, %w", ErrInvalid, err)
 	}
-	return nil
+	claims.Type = Type(typ)
+	if claims.IssuedAt, err = token.GetIssuedAt(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading iat, %w", ErrInvalid, err)
+	}
+	if claims.ExpiresAt, err = token.GetExpiration(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading exp, %w", ErrInvalid, err)
+	}
+	claims.Custom = customClaims(token.Claims())
+
+	return claims, nil
 }
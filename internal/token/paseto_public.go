@@ -0,0 +1,106 @@
+package token
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"aidanwoods.dev/go-paseto"
+)
+
+var (
+	_ Maker           = (*PasetoPublicMaker)(nil)
+	_ PublicKeySource = (*PasetoPublicMaker)(nil)
+)
+
+// PasetoPublicMaker makes v4.public PASETO tokens.
+// Tokens are signed with the Ed25519 key derived from the active key of its [KeyRing],
+// every key in the ring is used as a seed. Anyone with the [PublicKey]s can verify the tokens.
+type PasetoPublicMaker struct {
+	opts   MakerOpts
+	ring   *KeyRing
+	parser *paseto.Parser
+}
+
+// BootstrapPasetoPublicMaker returns a new [PasetoPublicMaker].
+// Reloading ring rotates the keys of the maker.
+func BootstrapPasetoPublicMaker(opts MakerOpts, ring *KeyRing) (*PasetoPublicMaker, error) {
+	if ring == nil {
+		return nil, errors.New("token: key ring is nil")
+	}
+
+	return &PasetoPublicMaker{
+		opts:   opts,
+		ring:   ring,
+		parser: pasetoParser(),
+	}, nil
+}
+
+func (x *PasetoPublicMaker) MakeAccessToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(TypeAccess, x.opts.AccessDuration, params)
+}
+
+func (x *PasetoPublicMaker) MakeRefreshToken(ctx context.Context, params Params) (SafeString, Claims, error) {
+	return x.make(TypeRefresh, x.opts.RefreshDuration, params)
+}
+
+func (x *PasetoPublicMaker) make(typ Type, dur time.Duration, params Params) (SafeString, Claims, error) {
+	claims, err := newClaims(typ, dur, params, x.opts)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	key := x.ring.Active()
+	secretKey, err := paseto.NewV4AsymmetricSecretKeyFromEd25519(ed25519.NewKeyFromSeed(key.Secret))
+	if err != nil {
+		return "", Claims{}, fmt.Errorf("token: creating secret key, %w", err)
+	}
+	token, err := newPasetoToken(claims, key.ID)
+	if err != nil {
+		return "", Claims{}, err
+	}
+
+	return fromString(token.V4Sign(secretKey, nil)), claims, nil
+}
+
+func (x *PasetoPublicMaker) Verify(ctx context.Context, t SafeString, expected Type) (Claims, error) {
+	key, err := pasetoKey(x.parser, x.ring, paseto.V4Public, t)
+	if err != nil {
+		return Claims{}, err
+	}
+	publicKey, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(publicKey(key))
+	if err != nil {
+		return Claims{}, fmt.Errorf("token: creating public key, %w", err)
+	}
+	token, err := x.parser.ParseV4Public(publicKey, string(t), nil)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+
+	claims, err := pasetoClaims(token)
+	if err != nil {
+		return Claims{}, err
+	}
+	if err = verifyClaims(ctx, x.opts, claims, expected); err != nil {
+		return Claims{}, err
+	}
+
+	return claims, nil
+}
+
+// PublicKeys returns the public key of every key in the ring,
+// including the verify-only ones so tokens made before a rotation stay verifiable.
+func (x *PasetoPublicMaker) PublicKeys() []PublicKey {
+	keys := x.ring.Keys()
+	pub := make([]PublicKey, 0, len(keys))
+	for _, k := range keys {
+		pub = append(pub, PublicKey{
+			ID:        k.ID,
+			Algorithm: AlgorithmEd25519,
+			Key:       publicKey(k),
+		})
+	}
+	return pub
+}
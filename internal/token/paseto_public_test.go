@@ -0,0 +1,117 @@
+package token
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
+)
+
+func bootstrapPublic(t *testing.T) *PasetoPublicMaker {
+	t.Helper()
+
+	t.Run("nil key ring", func(t *testing.T) {
+		if _, err := BootstrapPasetoPublicMaker(testOpts(), nil); err == nil {
+			t.Error("expected err with nil key ring")
+		}
+	})
+
+	ring, err := NewKeyRing(testKey("test", 's'))
+	if err != nil {
+		t.Fatalf("expected no err, got %s", err.Error())
+	}
+	maker, err := BootstrapPasetoPublicMaker(testOpts(), ring)
+	if err != nil {
+		t.Errorf("expected no err, got %s", err.Error())
+	}
+	return maker
+}
+
+func TestPublicVerify(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		b := bootstrapPublic(t)
+		params := Params{Subject: uuid.New(), Custom: map[string]string{"strategy": "Credentials"}}
+		s, made, err := b.MakeAccessToken(context.Background(), params)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		claims, err := b.Verify(context.Background(), s, TypeAccess)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if claims.ID != made.ID || claims.Subject != params.Subject {
+			t.Errorf("expected claims %v, got %v", made, claims)
+		}
+		if claims.Custom["strategy"] != "Credentials" {
+			t.Errorf("expected custom claims, got %v", claims.Custom)
+		}
+	})
+
+	t.Run("unexpected type returns ErrInvalid", func(t *testing.T) {
+		b := bootstrapPublic(t)
+		s, _, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("local token returns ErrInvalid", func(t *testing.T) {
+		s, _, err := bootstrap(t).MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = bootstrapPublic(t).Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		b := bootstrapPublic(t)
+		s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if err = b.ring.Reload(testKey("next", 'n'), testKey("test", 's')); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); err != nil {
+			t.Errorf("expected verify-only key to verify, got %s", err.Error())
+		}
+		if err = b.ring.Reload(testKey("next", 'n')); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid with removed key, got %v", err)
+		}
+	})
+}
+
+func TestPublicKeys(t *testing.T) {
+	b := bootstrapPublic(t)
+	if err := b.ring.Reload(testKey("next", 'n'), testKey("test", 's')); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	keys := b.PublicKeys()
+	if len(keys) != 2 || keys[0].ID != "next" || keys[1].ID != "test" {
+		t.Fatalf("expected keys next and test, got %v", keys)
+	}
+
+	// Verify offline, with nothing but the published key.
+	s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	pub, err := paseto.NewV4AsymmetricPublicKeyFromBytes(keys[0].Key)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if _, err = paseto.NewParser().ParseV4Public(pub, string(s), nil); err != nil {
+		t.Errorf("expected token to verify with the public key, got %s", err.Error())
+	}
+}
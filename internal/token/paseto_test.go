@@ -1,37 +1,39 @@
 package token
 
 import (
+	"context"
+	"errors"
 	"testing"
 	"time"
+
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/google/uuid"
 )
 
+func testOpts() MakerOpts {
+	return MakerOpts{
+		AccessDuration:  time.Second * 10,
+		RefreshDuration: time.Minute,
+		Issuer:          "identity-test",
+		Audience:        "test",
+		Denylist:        hotstorage.NewMemory(),
+	}
+}
+
 func bootstrap(t *testing.T) *PasetoMaker {
 	t.Helper()
 
-	t.Run("invalid symmetric key", func(t *testing.T) {
-		bb := make([]byte, 0, 31)
-		for range 31 {
-			bb = append(bb, byte('s'))
-		}
-		_, err := BootstrapPasetoMaker(
-			time.Second*10,
-			time.Minute,
-			bb,
-		)
-		if err == nil {
-			t.Error("expected err with invalid symmetric key")
+	t.Run("nil key ring", func(t *testing.T) {
+		if _, err := BootstrapPasetoMaker(testOpts(), nil); err == nil {
+			t.Error("expected err with nil key ring")
 		}
 	})
 
-	b := make([]byte, 0, 32)
-	for range 32 {
-		b = append(b, byte('s'))
+	ring, err := NewKeyRing(testKey("test", 's'))
+	if err != nil {
+		t.Fatalf("expected no err, got %s", err.Error())
 	}
-	maker, err := BootstrapPasetoMaker(
-		time.Second*10,
-		time.Minute,
-		b,
-	)
+	maker, err := BootstrapPasetoMaker(testOpts(), ring)
 	if err != nil {
 		t.Errorf("expected no err, got %s", err.Error())
 	}
@@ -40,33 +42,206 @@ func bootstrap(t *testing.T) *PasetoMaker {
 
 func TestMakeAccessToken(t *testing.T) {
 	b := bootstrap(t)
-	s := b.MakeAccessToken()
+	s, claims, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
 	if s == "" {
 		t.Error("token is empty")
 	}
+	if claims.Type != TypeAccess {
+		t.Errorf("expected type %s, got %s", TypeAccess, claims.Type)
+	}
+
+	t.Run("subject is required", func(t *testing.T) {
+		if _, _, err := b.MakeAccessToken(context.Background(), Params{}); err == nil {
+			t.Error("expected error without subject")
+		}
+	})
+
+	t.Run("registered custom claim", func(t *testing.T) {
+		_, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New(), Custom: map[string]string{"sub": "ass"}})
+		if err == nil {
+			t.Error("expected error with registered custom claim")
+		}
+	})
 }
 
 func TestMakeRefreshToken(t *testing.T) {
 	b := bootstrap(t)
-	s := b.MakeRefreshToken()
+	s, claims, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New()})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
 	if s == "" {
 		t.Error("token is empty")
 	}
+	if claims.Type != TypeRefresh {
+		t.Errorf("expected type %s, got %s", TypeRefresh, claims.Type)
+	}
+
+	t.Run("duration", func(t *testing.T) {
+		_, claims, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New(), Duration: time.Hour})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if got := claims.ExpiresAt.Sub(claims.IssuedAt); got != time.Hour {
+			t.Errorf("expected duration %s, got %s", time.Hour, got)
+		}
+	})
+
+	t.Run("not after", func(t *testing.T) {
+		notAfter := time.Now().Add(30 * time.Second)
+		_, claims, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New(), NotAfter: notAfter})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if !claims.ExpiresAt.Equal(notAfter.Truncate(time.Second)) {
+			t.Errorf("expected expiry %s, got %s", notAfter.Truncate(time.Second), claims.ExpiresAt)
+		}
+
+		if _, _, err = b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New(), NotAfter: time.Now().Add(-time.Second)}); err == nil {
+			t.Error("expected error when the token would already be expired")
+		}
+	})
 }
 
 func TestVerify(t *testing.T) {
 	t.Run("OK", func(t *testing.T) {
 		b := bootstrap(t)
-		s := b.MakeAccessToken()
-		if err := b.Verify((s)); err != nil {
-			t.Errorf("expected no error, got %s", err.Error())
+		params := Params{Subject: uuid.New(), Custom: map[string]string{"strategy": "Credentials"}}
+		s, made, err := b.MakeAccessToken(context.Background(), params)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		claims, err := b.Verify(context.Background(), s, TypeAccess)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if claims.ID == "" || claims.ID != made.ID {
+			t.Errorf("expected token ID %s, got %s", made.ID, claims.ID)
+		}
+		if claims.Subject != params.Subject {
+			t.Errorf("expected subject %s, got %s", params.Subject, claims.Subject)
+		}
+		if claims.Issuer != b.opts.Issuer || claims.Audience != b.opts.Audience {
+			t.Errorf("expected issuer and audience, got %s and %s", claims.Issuer, claims.Audience)
+		}
+		if !claims.ExpiresAt.Equal(made.ExpiresAt) || !claims.ExpiresAt.After(time.Now()) {
+			t.Error("expected expiry in the future")
+		}
+		if claims.Custom["strategy"] != "Credentials" || len(claims.Custom) != 1 {
+			t.Errorf("expected custom claims, got %v", claims.Custom)
+		}
+	})
+
+	t.Run("unique IDs", func(t *testing.T) {
+		b := bootstrap(t)
+		_, a, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		_, c, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if a.ID == c.ID {
+			t.Error("expected unique token IDs")
 		}
 	})
 
 	t.Run("invalid returns error", func(t *testing.T) {
 		b := bootstrap(t)
-		if err := b.Verify(fromString("ass")); err == nil {
-			t.Error("expected error")
+		if _, err := b.Verify(context.Background(), fromString("ass"), TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("unexpected type returns ErrInvalid", func(t *testing.T) {
+		b := bootstrap(t)
+		s, _, err := b.MakeRefreshToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("unexpected audience returns ErrInvalid", func(t *testing.T) {
+		b := bootstrap(t)
+		s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		other := *b
+		other.opts.Audience = "other"
+		if _, err = other.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("denied returns ErrRevoked", func(t *testing.T) {
+		b := bootstrap(t)
+		s, claims, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if err = b.opts.Denylist.Deny(context.Background(), claims.ID, claims.ExpiresAt); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrRevoked) {
+			t.Errorf("expected ErrRevoked, got %v", err)
+		}
+	})
+
+	t.Run("denied session returns ErrRevoked", func(t *testing.T) {
+		b := bootstrap(t)
+		sid := uuid.New()
+		s, claims, err := b.MakeAccessToken(context.Background(), Params{
+			Subject: uuid.New(),
+			Custom:  map[string]string{ClaimSession: sid.String()},
+		})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if claims.SessionID() != sid {
+			t.Errorf("expected session %s, got %s", sid, claims.SessionID())
+		}
+		if err = b.opts.Denylist.Deny(context.Background(), sid.String(), claims.ExpiresAt); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrRevoked) {
+			t.Errorf("expected ErrRevoked, got %v", err)
+		}
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		b := bootstrap(t)
+		s, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+
+		if err = b.ring.Reload(testKey("next", 'n'), testKey("test", 's')); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); err != nil {
+			t.Errorf("expected verify-only key to verify, got %s", err.Error())
+		}
+		next, _, err := b.MakeAccessToken(context.Background(), Params{Subject: uuid.New()})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), next, TypeAccess); err != nil {
+			t.Errorf("expected active key to verify, got %s", err.Error())
+		}
+
+		if err = b.ring.Reload(testKey("next", 'n')); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err = b.Verify(context.Background(), s, TypeAccess); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid with removed key, got %v", err)
 		}
 	})
 }
@@ -0,0 +1,85 @@
+package token
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+)
+
+// Algorithms of a [PublicKey].
+const (
+	AlgorithmEd25519 = "Ed25519"
+	AlgorithmP256    = "P-256"
+)
+
+// PublicKey is published so other services can verify tokens offline.
+type PublicKey struct {
+	// ID is the ID of the [Key] the public key belongs to, it is in the footer
+	// or kid header of every token it verifies.
+	ID        string
+	Algorithm string
+	// Key is the raw Ed25519 public key or the uncompressed P-256 point.
+	Key []byte
+}
+
+// PublicKeySource is implemented by every [Maker] that signs tokens with asymmetric keys.
+type PublicKeySource interface {
+	PublicKeys() []PublicKey
+}
+
+// publicKey returns the Ed25519 public key of the private key seeded by k.
+func publicKey(k Key) ed25519.PublicKey {
+	return ed25519.NewKeyFromSeed(k.Secret).Public().(ed25519.PublicKey)
+}
+
+// jwk is a public key in the JSON Web Key format, see RFC 7517 and RFC 8037.
+type jwk struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	X       string `json:"x"`
+	Y       string `json:"y,omitempty"`
+}
+
+// newJWK returns k in the JSON Web Key format.
+func newJWK(k PublicKey) jwk {
+	key := jwk{
+		KeyType: "OKP",
+		Curve:   k.Algorithm,
+		KeyID:   k.ID,
+		Use:     "sig",
+		X:       base64.RawURLEncoding.EncodeToString(k.Key),
+	}
+	// An uncompressed P-256 point is 0x04 followed by both coordinates.
+	if k.Algorithm == AlgorithmP256 && len(k.Key) == 1+2*KeySize {
+		key.KeyType = "EC"
+		key.X = base64.RawURLEncoding.EncodeToString(k.Key[1 : 1+KeySize])
+		key.Y = base64.RawURLEncoding.EncodeToString(k.Key[1+KeySize:])
+	}
+	return key
+}
+
+// PublicKeysHandler serves the public keys of source as a JSON Web Key Set.
+func PublicKeysHandler(source PublicKeySource) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		keys := source.PublicKeys()
+		set := struct {
+			Keys []jwk `json:"keys"`
+		}{Keys: make([]jwk, 0, len(keys))}
+		for _, k := range keys {
+			set.Keys = append(set.Keys, newJWK(k))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "max-age=300")
+		_ = json.NewEncoder(w).Encode(set)
+	})
+}
@@ -0,0 +1,65 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicKeysHandler(t *testing.T) {
+	b := bootstrapPublic(t)
+	h := PublicKeysHandler(b)
+
+	t.Run("OK", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if len(set.Keys) != 1 {
+			t.Fatalf("expected 1 key, got %d", len(set.Keys))
+		}
+		k := set.Keys[0]
+		if k.KeyID != "test" || k.KeyType != "OKP" || k.Curve != AlgorithmEd25519 {
+			t.Errorf("unexpected key %v", k)
+		}
+		if k.X != base64.RawURLEncoding.EncodeToString(b.PublicKeys()[0].Key) {
+			t.Error("expected x to be the public key")
+		}
+	})
+
+	t.Run("method not allowed", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil))
+		if rec.Code != http.StatusMethodNotAllowed {
+			t.Errorf("expected status 405, got %d", rec.Code)
+		}
+	})
+	t.Run("P-256", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		PublicKeysHandler(bootstrapJWT(t, JWTAlgorithmES256)).
+			ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+		var set struct {
+			Keys []jwk `json:"keys"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if len(set.Keys) != 1 {
+			t.Fatalf("expected 1 key, got %d", len(set.Keys))
+		}
+		if k := set.Keys[0]; k.KeyType != "EC" || k.Curve != AlgorithmP256 || k.X == "" || k.Y == "" {
+			t.Errorf("unexpected key %v", k)
+		}
+	})
+}
@@ -1,6 +1,23 @@
 package token
 
-import "log/slog"
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/hotstorage"
+)
+
+var (
+	// ErrInvalid is returned by [Maker.Verify] when a token is malformed, expired or tampered with.
+	ErrInvalid = errors.New("token: invalid")
+	// ErrRevoked is returned by [Maker.Verify] when a token is on the denylist.
+	ErrRevoked = errors.New("token: revoked")
+)
 
 // SafeString has it's common stringers masked.
 // Should be created internally.
@@ -20,9 +37,64 @@ func fromString(s string) SafeString {
 	return SafeString(s)
 }
 
+// Hash returns the hex encoded SHA-256 hash of a token.
+// Tokens are long and random, so a fast hash is enough to store them safely.
+func Hash(t SafeString) string {
+	sum := sha256.Sum256([]byte(t))
+	return hex.EncodeToString(sum[:])
+}
+
+// MakerOpts configure a [Maker].
+type MakerOpts struct {
+	AccessDuration  time.Duration
+	RefreshDuration time.Duration
+	// Issuer is set as the iss claim of every token and is required when verifying.
+	Issuer string
+	// Audience is set as the aud claim of every token and is required when verifying.
+	Audience string
+	// Denylist is checked when verifying, it can be nil to skip the check.
+	Denylist hotstorage.Denylist
+}
+
 // Maker is an abstract interface for making and verifying access and refresh tokens.
 type Maker interface {
-	MakeAccessToken() SafeString
-	MakeRefreshToken() SafeString
-	Verify(t SafeString) error
+	// MakeAccessToken makes an access token for the subject of params.
+	MakeAccessToken(ctx context.Context, params Params) (SafeString, Claims, error)
+	// MakeRefreshToken makes a refresh token for the subject of params.
+	MakeRefreshToken(ctx context.Context, params Params) (SafeString, Claims, error)
+	// Verify returns the [Claims] of a valid token of the expected [Type].
+	// Returns [ErrInvalid] if the token is not valid or [ErrRevoked] if it is on the denylist.
+	Verify(ctx context.Context, t SafeString, expected Type) (Claims, error)
+}
+
+// Revoker is implemented by every [Maker] that can revoke its tokens where they are stored,
+// on top of the denylist.
+type Revoker interface {
+	// Revoke revokes t. Revoking an unknown or already revoked token is not an error.
+	Revoke(ctx context.Context, t SafeString) error
+}
+
+// verifyClaims returns [ErrInvalid] if claims are not of the expected [Type]
+// or not issued by and for opts, and [ErrRevoked] if they are on the denylist of opts.
+func verifyClaims(ctx context.Context, opts MakerOpts, claims Claims, expected Type) error {
+	if err := claims.check(expected, opts); err != nil {
+		return err
+	}
+	if opts.Denylist == nil {
+		return nil
+	}
+	ids := []string{claims.ID}
+	if sid, ok := claims.Custom[ClaimSession]; ok {
+		ids = append(ids, sid)
+	}
+	for _, id := range ids {
+		denied, err := opts.Denylist.IsDenied(ctx, id)
+		if err != nil {
+			return fmt.Errorf("token: checking denylist, %w", err)
+		}
+		if denied {
+			return ErrRevoked
+		}
+	}
+	return nil
 }
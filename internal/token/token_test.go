@@ -9,3 +9,16 @@ func TestSafeString(t *testing.T) {
 		}
 	})
 }
+
+func TestHash(t *testing.T) {
+	a := Hash(fromString("ass"))
+	if len(a) != 64 {
+		t.Errorf("expected len 64, got %d", len(a))
+	}
+	if a != Hash(fromString("ass")) {
+		t.Error("expected hash to be deterministic")
+	}
+	if a == Hash(fromString("asz")) {
+		t.Error("expected different hashes")
+	}
+}
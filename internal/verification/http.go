@@ -0,0 +1,92 @@
+package verification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var _ Provider = (*HTTPProvider)(nil)
+
+// httpTimeout bounds a single request to the provider.
+const httpTimeout = 10 * time.Second
+
+// HTTPProvider verifies personal numbers with an electronic ID service with a JSON HTTP API.
+// Every verification is a POST of {"personalNumber"} to the URL of the service,
+// authenticated with a bearer token. The service responds 200 with {"verified"}.
+type HTTPProvider struct {
+	client *http.Client
+	url    string
+	token  string
+}
+
+// NewHTTPProvider returns a [HTTPProvider] that posts to url.
+// client defaults to an [http.Client] with a timeout.
+func NewHTTPProvider(client *http.Client, url, token string) (*HTTPProvider, error) {
+	if url == "" {
+		return nil, errors.New("verification: http provider url is empty")
+	}
+	if client == nil {
+		client = &http.Client{Timeout: httpTimeout}
+	}
+	return &HTTPProvider{client: client, url: url, token: token}, nil
+}
+
+// VerifyPersonalNumber asks the service to verify the person behind number.
+// Returns [ErrNotVerified] if the service could not verify the person.
+func (x *HTTPProvider) VerifyPersonalNumber(ctx context.Context, number uint64) error {
+	ctx, span := tracer.Start(ctx, "VerifyPersonalNumber", trace.WithAttributes(
+		attribute.String("number", Mask(number)),
+	))
+	defer span.End()
+
+	body, err := json.Marshal(struct {
+		PersonalNumber string `json:"personalNumber"`
+	}{PersonalNumber: strconv.FormatUint(number, 10)})
+	if err != nil {
+		return fmt.Errorf("verification: encoding request, %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, x.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("verification: creating request, %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if x.token != "" {
+		req.Header.Set("Authorization", "Bearer "+x.token)
+	}
+
+	res, err := x.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("verification: sending request, %w", err)
+	}
+	defer res.Body.Close()
+	span.SetAttributes(attribute.Int("status", res.StatusCode))
+
+	if res.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(res.Body, 512))
+		return fmt.Errorf("verification: provider responded %s, %s", res.Status, bytes.TrimSpace(msg))
+	}
+
+	var result struct {
+		Verified bool `json:"verified"`
+	}
+	if err = json.NewDecoder(io.LimitReader(res.Body, 4096)).Decode(&result); err != nil {
+		return fmt.Errorf("verification: decoding response, %w", err)
+	}
+	// Drain the body so the connection can be reused.
+	_, _ = io.Copy(io.Discard, res.Body)
+	if !result.Verified {
+		return ErrNotVerified
+	}
+	return nil
+}
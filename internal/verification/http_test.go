@@ -0,0 +1,70 @@
+package verification
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPProvider(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var got map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]bool{"verified": got["personalNumber"] == "199001011239"})
+	}))
+	t.Cleanup(srv.Close)
+
+	provider, err := NewHTTPProvider(srv.Client(), srv.URL, "token")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err)
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		if err := provider.VerifyPersonalNumber(context.Background(), 199001011239); err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+	})
+
+	t.Run("not verified", func(t *testing.T) {
+		err := provider.VerifyPersonalNumber(context.Background(), 199001011247)
+		if !errors.Is(err, ErrNotVerified) {
+			t.Errorf("expected ErrNotVerified, got %v", err)
+		}
+	})
+
+	t.Run("unauthorized", func(t *testing.T) {
+		p, err := NewHTTPProvider(srv.Client(), srv.URL, "wrong")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err)
+		}
+		err = p.VerifyPersonalNumber(context.Background(), 199001011239)
+		if err == nil || errors.Is(err, ErrNotVerified) {
+			t.Errorf("expected a provider error, got %v", err)
+		}
+	})
+
+	t.Run("missing url", func(t *testing.T) {
+		if _, err := NewHTTPProvider(nil, "", "token"); err == nil {
+			t.Error("expected error")
+		}
+	})
+}
+
+func TestMask(t *testing.T) {
+	if got := Mask(199001011239); got != "********1239" {
+		t.Errorf("expected ********1239, got %s", got)
+	}
+	if got := Mask(123); got != "***" {
+		t.Errorf("expected ***, got %s", got)
+	}
+}
@@ -0,0 +1,68 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("verification")
+
+// ErrNotVerified is returned by a [Provider] when the person behind
+// a personal number could not be verified.
+var ErrNotVerified = errors.New("verification: personal number could not be verified")
+
+// Provider verifies that the person behind a personal number is the one making the request,
+// usually with an external electronic ID service.
+type Provider interface {
+	// VerifyPersonalNumber returns [ErrNotVerified] if the person could not be verified.
+	VerifyPersonalNumber(ctx context.Context, number uint64) error
+}
+
+// FakeProvider is a local implementation of the [Provider] interface.
+// It verifies every personal number except the rejected ones
+// and is meant for development and tests.
+type FakeProvider struct {
+	rejected map[uint64]struct{}
+}
+
+// NewFakeProvider returns a [FakeProvider] that rejects the given numbers.
+func NewFakeProvider(rejected ...uint64) *FakeProvider {
+	m := make(map[uint64]struct{}, len(rejected))
+	for _, n := range rejected {
+		m[n] = struct{}{}
+	}
+	return &FakeProvider{rejected: m}
+}
+
+// VerifyPersonalNumber logs the verification to the console.
+func (x *FakeProvider) VerifyPersonalNumber(ctx context.Context, number uint64) error {
+	ctx, span := tracer.Start(ctx, "VerifyPersonalNumber", trace.WithAttributes(
+		attribute.String("number", Mask(number)),
+	))
+	defer span.End()
+
+	if _, ok := x.rejected[number]; ok {
+		slog.InfoContext(ctx, "fake provider: rejecting personal number", "number", Mask(number))
+		return ErrNotVerified
+	}
+
+	slog.InfoContext(ctx, "fake provider: verifying personal number", "number", Mask(number))
+	return nil
+}
+
+// Mask returns number with every digit but the last 4 replaced by asterisks,
+// a personal number identifies a person and must not end up in logs or traces.
+func Mask(number uint64) string {
+	digits := strconv.FormatUint(number, 10)
+	if len(digits) <= 4 {
+		return strings.Repeat("*", len(digits))
+	}
+	return strings.Repeat("*", len(digits)-4) + digits[len(digits)-4:]
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net"
 	"net/http"
@@ -12,6 +14,7 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/Salam4nder/identity/internal/auth"
 	"github.com/Salam4nder/identity/internal/auth/strategy"
 	"github.com/Salam4nder/identity/internal/config"
 	"github.com/Salam4nder/identity/internal/database"
@@ -19,15 +22,20 @@ import (
 	"github.com/Salam4nder/identity/internal/event"
 	"github.com/Salam4nder/identity/internal/grpc/interceptors"
 	"github.com/Salam4nder/identity/internal/grpc/server"
+	"github.com/Salam4nder/identity/internal/hotstorage"
 	"github.com/Salam4nder/identity/internal/observability/metrics"
 	"github.com/Salam4nder/identity/internal/observability/otel"
+	"github.com/Salam4nder/identity/internal/sms"
 	"github.com/Salam4nder/identity/internal/token"
+	"github.com/Salam4nder/identity/internal/verification"
 	"github.com/Salam4nder/identity/pkg/logger"
 	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/google/uuid"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/recovery"
 	"github.com/nats-io/nats.go"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"github.com/stimtech/go-migration/v2"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
@@ -43,11 +51,8 @@ const (
 	// serviceVersion is the version of the service.
 	serviceVersion string = "1.0.0"
 
-	// TODO(kg):  Move these to config.
-	accessTokenDuration  = 15 * time.Minute
-	refreshTokenDuration = 7 * 24 * time.Hour
-	migrationFolder      = "db/migrations"
-	natsTimeout          = 5 * time.Second
+	migrationFolder = "db/migrations"
+	natsTimeout     = 5 * time.Second
 )
 
 // serviceID is the unique identifier of the service.
@@ -100,19 +105,88 @@ func main() {
 	natsChan := make(chan *nats.Msg, 64)
 	userSub, err := natsClient.ChanSubscribe(email.IngestedEvent, natsChan)
 	exitOnError(ctx, err)
+	smsSub, err := natsClient.ChanSubscribe(sms.IngestedEvent, natsChan)
+	exitOnError(ctx, err)
 
 	// Worker.
-	go event.NewWorker(email.NewNoOpSender()).Work(ctx, natsChan)
+	var smsSender sms.Sender
+	switch cfg.SMS.Sender {
+	case "", "noop":
+		smsSender = sms.NewNoOpSender()
+	case "http":
+		smsSender, err = sms.NewHTTPSender(nil, cfg.SMS.URL, cfg.SMS.Token, cfg.SMS.From)
+		exitOnError(ctx, err)
+	default:
+		exitOnError(ctx, fmt.Errorf("main: unknown sms sender %q", cfg.SMS.Sender))
+	}
+	go event.NewWorker(email.NewNoOpSender(), smsSender).Work(ctx, natsChan)
+
+	// Hot storage.
+	var (
+		denylist    hotstorage.Denylist
+		cache       hotstorage.Cache
+		redisClient *redis.Client
+	)
+	switch cfg.HotStorage.Driver {
+	case "", "memory":
+		memory := hotstorage.NewMemory()
+		denylist, cache = memory, memory
+	case "redis":
+		redisClient = redis.NewClient(&redis.Options{
+			Addr:     cfg.HotStorage.Redis.Addr(),
+			Password: cfg.HotStorage.Redis.Password,
+			DB:       cfg.HotStorage.Redis.DB,
+		})
+		r := hotstorage.NewRedis(redisClient)
+		denylist, cache = r, r
+	default:
+		exitOnError(ctx, fmt.Errorf("main: unknown hot storage driver %q", cfg.HotStorage.Driver))
+	}
 
 	// Token maker.
-	tokenMaker, err := token.BootstrapPasetoMaker(
-		accessTokenDuration,
-		refreshTokenDuration,
-		[]byte(cfg.SymmetricKey))
-	if err != nil {
-		exitOnError(ctx, err)
+	activeKey, verifyOnlyKeys, err := tokenKeys(cfg.Token)
+	exitOnError(ctx, err)
+	keyRing, err := token.NewKeyRing(activeKey, verifyOnlyKeys...)
+	exitOnError(ctx, err)
+	go reloadKeysOnHangup(ctx, keyRing)
+	makerOpts := token.MakerOpts{
+		AccessDuration:  cmp.Or(cfg.Sessions.AccessTokenDuration, config.DefaultAccessTokenDuration),
+		RefreshDuration: cmp.Or(cfg.Sessions.Default.IdleTimeout, config.DefaultIdleTimeout),
+		Issuer:          cfg.Token.Issuer,
+		Audience:        cfg.Token.Audience,
+		Denylist:        denylist,
+	}
+	var tokenMaker token.Maker
+	switch cfg.Token.Maker {
+	case "", "paseto":
+		tokenMaker, err = token.BootstrapPasetoMaker(makerOpts, keyRing)
+	case "pasetoPublic":
+		tokenMaker, err = token.BootstrapPasetoPublicMaker(makerOpts, keyRing)
+	case "jwt":
+		algorithm := cfg.Token.JWTAlgorithm
+		if algorithm == "" {
+			algorithm = token.JWTAlgorithmEdDSA
+		}
+		tokenMaker, err = token.BootstrapJWTMaker(makerOpts, keyRing, algorithm)
+	case "opaque":
+		if !cfg.Token.OpaqueCache {
+			cache = nil
+		}
+		tokenMaker, err = token.BootstrapOpaqueMaker(makerOpts, psqlDB, cache)
+	default:
+		err = fmt.Errorf("main: unknown token maker %q", cfg.Token.Maker)
 	}
+	exitOnError(ctx, err)
 
+	authenticator := interceptors.NewAuthenticator(
+		tokenMaker,
+		append(
+			server.PublicMethods,
+			"/grpc.health.v1.Health/*",
+			"/grpc.reflection.v1.ServerReflection/*",
+			"/grpc.reflection.v1alpha.ServerReflection/*",
+		)...,
+	)
 	grpcListener, err := net.Listen("tcp", cfg.Server.GRPCAddr())
 	exitOnError(ctx, err)
 	grpcServer := grpc.NewServer(
@@ -120,16 +194,97 @@ func main() {
 		grpc.ChainUnaryInterceptor(
 			recovery.UnaryServerInterceptor(),
 			interceptors.UnaryLoggerInterceptor,
+			authenticator.Unary(),
+		),
+		grpc.ChainStreamInterceptor(
+			recovery.StreamServerInterceptor(),
+			authenticator.Stream(),
 		),
 	)
 	healthServer := health.NewServer()
 	healthgen.RegisterHealthServer(grpcServer, healthServer)
+	configuredStrategies, err := cfg.ConfiguredStrategies()
+	exitOnError(ctx, err)
+	var relyingParty *webauthn.WebAuthn
+	if cfg.WebAuthn.RPID != "" {
+		relyingParty, err = webauthn.New(&webauthn.Config{
+			RPID:          cfg.WebAuthn.RPID,
+			RPDisplayName: cfg.WebAuthn.RPDisplayName,
+			RPOrigins:     cfg.WebAuthn.RPOrigins,
+		})
+		exitOnError(ctx, err)
+	}
+	var verifier verification.Provider
+	switch cfg.PersonalNumber.Provider {
+	case "":
+		if cfg.Environment == "dev" {
+			verifier = verification.NewFakeProvider()
+		}
+	case "fake":
+		if cfg.Environment != "dev" {
+			exitOnError(ctx, errors.New("main: the fake personal number provider only runs in the dev environment"))
+		}
+		verifier = verification.NewFakeProvider()
+	case "http":
+		verifier, err = verification.NewHTTPProvider(nil, cfg.PersonalNumber.URL, cfg.PersonalNumber.Token)
+		exitOnError(ctx, err)
+	default:
+		exitOnError(ctx, fmt.Errorf("main: unknown personal number provider %q", cfg.PersonalNumber.Provider))
+	}
+	emailVerification, err := auth.NewEmailVerification(
+		psqlDB,
+		natsClient,
+		cfg.EmailVerification.LinkURL,
+		cfg.EmailVerification.Required,
+	)
+	exitOnError(ctx, err)
+	strategies, err := strategy.NewRegistry(
+		strategy.Dependencies{
+			DB:                psqlDB,
+			Publisher:         natsClient,
+			EmailVerification: emailVerification,
+			Verifier:          verifier,
+			RelyingParty:      relyingParty,
+			OneTimeKey:        []byte(cfg.Passwordless.OneTimeKey),
+			LinkURL:           cfg.Passwordless.LinkURL,
+		},
+		configuredStrategies...,
+	)
+	exitOnError(ctx, err)
+	clientHashes := make(map[string]string, len(cfg.ServiceClients))
+	for _, c := range cfg.ServiceClients {
+		clientHashes[c.ID] = c.SecretHash
+	}
+	serviceClients, err := auth.NewServiceClients(clientHashes)
+	exitOnError(ctx, err)
+	mfa, err := auth.NewMFA(psqlDB, cfg.MFA.Issuer, []byte(cfg.MFA.EncryptionKey))
+	exitOnError(ctx, err)
+	tokens := auth.NewTokens(psqlDB, tokenMaker, denylist, auth.SessionPolicies{
+		Default:    auth.SessionPolicy(cfg.Sessions.Default),
+		RememberMe: auth.SessionPolicy(cfg.Sessions.RememberMe),
+	})
+	passwords, err := auth.NewPasswords(psqlDB, natsClient, tokens, cfg.PasswordReset.LinkURL)
+	exitOnError(ctx, err)
+	emailChanges, err := auth.NewEmailChanges(
+		psqlDB,
+		natsClient,
+		tokens,
+		passwords,
+		cfg.EmailChange.ConfirmLinkURL,
+		cfg.EmailChange.RevertLinkURL,
+	)
+	exitOnError(ctx, err)
 	userServer, err := server.NewUserServer(
 		psqlDB,
 		healthServer,
 		natsClient,
-		strategy.NewCredentials(psqlDB, natsClient),
-		tokenMaker,
+		strategies,
+		tokens,
+		serviceClients,
+		mfa,
+		emailVerification,
+		passwords,
+		emailChanges,
 	)
 	exitOnError(ctx, err)
 	gen.RegisterIdentityServer(grpcServer, userServer)
@@ -147,6 +302,9 @@ func main() {
 		exitOnError(ctx, err)
 	}
 	http.Handle("/metrics", promhttp.Handler())
+	if source, ok := tokenMaker.(token.PublicKeySource); ok {
+		http.Handle("/.well-known/jwks.json", token.PublicKeysHandler(source))
+	}
 	promSrv := http.Server{
 		Addr:        "0.0.0.0:8090",
 		ReadTimeout: time.Second * 10,
@@ -165,13 +323,67 @@ func main() {
 	grpcServer.GracefulStop()
 	err = errors.Join(err, psqlDB.Close())
 	err = errors.Join(err, userSub.Unsubscribe())
+	err = errors.Join(err, smsSub.Unsubscribe())
 	natsClient.Close()
+	if redisClient != nil {
+		err = errors.Join(err, redisClient.Close())
+	}
 
 	if err != nil {
 		slog.ErrorContext(ctx, "main: error upon exit", "error", err)
 	}
 }
 
+// tokenKeys splits the configured keys into the active key and the verify-only keys.
+func tokenKeys(cfg config.Token) (token.Key, []token.Key, error) {
+	var (
+		active     token.Key
+		verifyOnly []token.Key
+	)
+	for _, k := range cfg.Keys {
+		key := token.Key{ID: k.ID, Secret: []byte(k.Secret)}
+		if k.ID == cfg.ActiveKey {
+			active = key
+			continue
+		}
+		verifyOnly = append(verifyOnly, key)
+	}
+	if active.ID == "" {
+		return token.Key{}, nil, fmt.Errorf("main: active key %q is not configured", cfg.ActiveKey)
+	}
+	return active, verifyOnly, nil
+}
+
+// reloadKeysOnHangup reloads the keys of ring from the config file on every SIGHUP.
+// A config that fails to load leaves the ring untouched.
+func reloadKeysOnHangup(ctx context.Context, ring *token.KeyRing) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			cfg, err := config.New()
+			if err != nil {
+				slog.ErrorContext(ctx, "main: reloading token keys", "error", err)
+				continue
+			}
+			active, verifyOnly, err := tokenKeys(cfg.Token)
+			if err == nil {
+				err = ring.Reload(active, verifyOnly...)
+			}
+			if err != nil {
+				slog.ErrorContext(ctx, "main: reloading token keys", "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "main: reloaded token keys", "active", active.ID, "keys", ring.IDs())
+		}
+	}
+}
+
 func exitOnError(ctx context.Context, err error) {
 	if err != nil {
 		slog.ErrorContext(ctx, "main: exit on error", "error", err)
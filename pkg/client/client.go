@@ -0,0 +1,373 @@
+// Package client is a Go SDK for the identity service.
+// [Client] wraps the token RPCs with typed methods
+// and [Credentials] authenticates calls to protected RPCs with auto renewed access tokens.
+package client
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+// Client calls the identity service. It is safe for concurrent use.
+type Client struct {
+	rpc gen.IdentityClient
+}
+
+// New returns a new [Client] that calls the identity service over conn.
+func New(conn grpc.ClientConnInterface) *Client {
+	return &Client{rpc: gen.NewIdentityClient(conn)}
+}
+
+// Input is the input of an authentication strategy,
+// created with [CredentialsInput], [PersonalNumberInput], [WebAuthnInput],
+// [EmailCodeInput], [EmailLinkInput] or [SMSCodeInput].
+type Input struct {
+	in *gen.Input
+}
+
+// CredentialsInput returns the [Input] of the credentials strategy.
+func CredentialsInput(email, password string) Input {
+	return Input{in: &gen.Input{
+		Strategy: gen.Strategy_Credentials,
+		Data: &gen.Input_Credentials{
+			Credentials: &gen.CredentialsInput{Email: email, Password: password},
+		},
+	}}
+}
+
+// PersonalNumberInput returns the [Input] of the personal number strategy.
+func PersonalNumberInput(numbers uint64) Input {
+	return Input{in: &gen.Input{
+		Strategy: gen.Strategy_PersonalNumber,
+		Data: &gen.Input_Numbers{
+			Numbers: &gen.PersonalNumberInput{Numbers: numbers},
+		},
+	}}
+}
+
+// WebAuthnInput returns the [Input] of the WebAuthn strategy, answering the challenge
+// of [Client.BeginWebAuthnRegistration] or [Client.BeginWebAuthnAuthentication]
+// with the JSON encoded PublicKeyCredential of the authenticator.
+func WebAuthnInput(challengeID string, credential []byte) Input {
+	return Input{in: &gen.Input{
+		Strategy: gen.Strategy_WebAuthn,
+		Data: &gen.Input_WebAuthn{
+			WebAuthn: &gen.WebAuthnInput{ChallengeId: challengeID, Credential: credential},
+		},
+	}}
+}
+
+// EmailCodeInput returns the [Input] of the email strategy, answering the challenge
+// of [Client.StartPasswordless] with the code sent to the email.
+func EmailCodeInput(challengeID, code string) Input {
+	return Input{in: &gen.Input{
+		Strategy: gen.Strategy_Email,
+		Data: &gen.Input_OneTime{
+			OneTime: &gen.OneTimeInput{ChallengeId: challengeID, Code: code},
+		},
+	}}
+}
+
+// EmailLinkInput returns the [Input] of the email strategy, answering the challenge
+// of [Client.StartPasswordless] with the token of the link sent to the email.
+func EmailLinkInput(token string) Input {
+	return Input{in: &gen.Input{
+		Strategy: gen.Strategy_Email,
+		Data: &gen.Input_OneTime{
+			OneTime: &gen.OneTimeInput{Token: token},
+		},
+	}}
+}
+
+// SMSCodeInput returns the [Input] of the SMS strategy, answering the challenge
+// of [Client.StartPasswordless] with the code sent to the phone number.
+func SMSCodeInput(challengeID, code string) Input {
+	return Input{in: &gen.Input{
+		Strategy: gen.Strategy_SMS,
+		Data: &gen.Input_OneTime{
+			OneTime: &gen.OneTimeInput{ChallengeId: challengeID, Code: code},
+		},
+	}}
+}
+
+// WithRememberMe returns a copy of x that asks [Client.Authenticate] for a remember me session,
+// which lasts longer if the service is configured so.
+func (x Input) WithRememberMe() Input {
+	in := proto.Clone(x.in).(*gen.Input)
+	in.RememberMe = true
+	return Input{in: in}
+}
+
+// Tokens are the tokens returned by [Client.Authenticate] and [Client.Renew].
+type Tokens struct {
+	UserID       uuid.UUID
+	AccessToken  string
+	RefreshToken string
+	IssuedAt     time.Time
+	// AccessExpiresAt and RefreshExpiresAt are zero if the service did not send them.
+	AccessExpiresAt  time.Time
+	RefreshExpiresAt time.Time
+	// MFAChallenge is set instead of the tokens when the user has to verify a second factor
+	// with [Client.VerifyMFA].
+	MFAChallenge string
+}
+
+// Delivery is where and how [Client.StartPasswordless] delivers a one time code or link,
+// created with [EmailCode], [EmailLink] or [SMSCode].
+type Delivery struct {
+	req *gen.StartPasswordlessRequest
+}
+
+// EmailCode returns the [Delivery] of a one time code to email.
+func EmailCode(email string) Delivery {
+	return Delivery{req: &gen.StartPasswordlessRequest{
+		Strategy: gen.Strategy_Email,
+		Address:  email,
+		Method:   gen.DeliveryMethod_Code,
+	}}
+}
+
+// EmailLink returns the [Delivery] of a sign in link to email.
+func EmailLink(email string) Delivery {
+	return Delivery{req: &gen.StartPasswordlessRequest{
+		Strategy: gen.Strategy_Email,
+		Address:  email,
+		Method:   gen.DeliveryMethod_Link,
+	}}
+}
+
+// SMSCode returns the [Delivery] of a one time code to phone, a phone number in E.164 format.
+func SMSCode(phone string) Delivery {
+	return Delivery{req: &gen.StartPasswordlessRequest{
+		Strategy: gen.Strategy_SMS,
+		Address:  phone,
+		Method:   gen.DeliveryMethod_Code,
+	}}
+}
+
+// PasswordlessChallenge is answered by the code or link of a [Delivery] before ExpiresAt.
+type PasswordlessChallenge struct {
+	ID        string
+	ExpiresAt time.Time
+}
+
+// WebAuthnChallenge begins a WebAuthn ceremony, answer it with a [WebAuthnInput] before ExpiresAt.
+type WebAuthnChallenge struct {
+	ID string
+	// Options are the JSON encoded options for navigator.credentials.create or navigator.credentials.get.
+	Options   []byte
+	ExpiresAt time.Time
+}
+
+// Register registers a new user with the strategy of in.
+func (x *Client) Register(ctx context.Context, in Input) error {
+	if _, err := x.rpc.Register(ctx, in.in); err != nil {
+		return fmt.Errorf("client: registering, %w", err)
+	}
+	return nil
+}
+
+// Authenticate authenticates a user with the strategy of in.
+// Users with a second factor get [Tokens] with only an MFA challenge, see [Client.VerifyMFA].
+func (x *Client) Authenticate(ctx context.Context, in Input) (Tokens, error) {
+	res, err := x.rpc.Authenticate(ctx, in.in)
+	if err != nil {
+		return Tokens{}, fmt.Errorf("client: authenticating, %w", err)
+	}
+	return newTokens(res)
+}
+
+// Renew trades a refresh token for new [Tokens]. A refresh token can only be renewed once.
+func (x *Client) Renew(ctx context.Context, refreshToken string) (Tokens, error) {
+	res, err := x.rpc.Renew(ctx, &gen.RenewRequest{RefreshToken: refreshToken})
+	if err != nil {
+		return Tokens{}, fmt.Errorf("client: renewing, %w", err)
+	}
+	return newTokens(res)
+}
+
+// VerifyMFA answers the MFA challenge returned by [Client.Authenticate] with a TOTP or backup code.
+func (x *Client) VerifyMFA(ctx context.Context, challenge, code string) (Tokens, error) {
+	res, err := x.rpc.VerifyMFA(ctx, &gen.VerifyMFARequest{MfaChallenge: challenge, Code: code})
+	if err != nil {
+		return Tokens{}, fmt.Errorf("client: verifying mfa, %w", err)
+	}
+	return newTokens(res)
+}
+
+// StartPasswordless delivers a one time code or link, answer it with [Client.VerifyPasswordless].
+func (x *Client) StartPasswordless(ctx context.Context, d Delivery) (PasswordlessChallenge, error) {
+	res, err := x.rpc.StartPasswordless(ctx, d.req)
+	if err != nil {
+		return PasswordlessChallenge{}, fmt.Errorf("client: starting passwordless, %w", err)
+	}
+	challenge := PasswordlessChallenge{ID: res.GetChallengeId()}
+	if res.GetExpiresAt() != nil {
+		challenge.ExpiresAt = res.GetExpiresAt().AsTime()
+	}
+	return challenge, nil
+}
+
+// VerifyPasswordless answers the challenge of [Client.StartPasswordless] with in,
+// created with [EmailCodeInput] or [EmailLinkInput]. An email signs up on its first sign in.
+func (x *Client) VerifyPasswordless(ctx context.Context, in Input) (Tokens, error) {
+	res, err := x.rpc.VerifyPasswordless(ctx, &gen.VerifyPasswordlessRequest{
+		Strategy:   in.in.GetStrategy(),
+		Input:      in.in.GetOneTime(),
+		RememberMe: in.in.GetRememberMe(),
+	})
+	if err != nil {
+		return Tokens{}, fmt.Errorf("client: verifying passwordless, %w", err)
+	}
+	return newTokens(res)
+}
+
+// VerifyEmail verifies the email of a credentials user with the token emailed on registration.
+func (x *Client) VerifyEmail(ctx context.Context, token string) error {
+	if _, err := x.rpc.VerifyEmail(ctx, &gen.VerifyEmailRequest{Token: token}); err != nil {
+		return fmt.Errorf("client: verifying email, %w", err)
+	}
+	return nil
+}
+
+// ResendVerificationEmail emails a new verification token to the credentials user registered with email,
+// if its email is not verified yet.
+func (x *Client) ResendVerificationEmail(ctx context.Context, email string) error {
+	if _, err := x.rpc.ResendVerificationEmail(ctx, &gen.ResendVerificationEmailRequest{Email: email}); err != nil {
+		return fmt.Errorf("client: resending verification email, %w", err)
+	}
+	return nil
+}
+
+// RequestPasswordReset emails a password reset token to the credentials user registered with email,
+// use it with [Client.ResetPassword].
+func (x *Client) RequestPasswordReset(ctx context.Context, email string) error {
+	if _, err := x.rpc.RequestPasswordReset(ctx, &gen.RequestPasswordResetRequest{Email: email}); err != nil {
+		return fmt.Errorf("client: requesting password reset, %w", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password with the token of [Client.RequestPasswordReset].
+// Every session of the user ends, it has to authenticate again.
+func (x *Client) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if _, err := x.rpc.ResetPassword(ctx, &gen.ResetPasswordRequest{
+		Token:       token,
+		NewPassword: newPassword,
+	}); err != nil {
+		return fmt.Errorf("client: resetting password, %w", err)
+	}
+	return nil
+}
+
+// ConfirmEmailChange changes the email of a credentials user with the token emailed to its new email.
+func (x *Client) ConfirmEmailChange(ctx context.Context, token string) error {
+	if _, err := x.rpc.ConfirmEmailChange(ctx, &gen.ConfirmEmailChangeRequest{Token: token}); err != nil {
+		return fmt.Errorf("client: confirming email change, %w", err)
+	}
+	return nil
+}
+
+// RevertEmailChange changes the email of a credentials user back with the token emailed to its previous email.
+// Every session of the user ends and its password has to be reset with the token emailed to the restored email.
+func (x *Client) RevertEmailChange(ctx context.Context, token string) error {
+	if _, err := x.rpc.RevertEmailChange(ctx, &gen.RevertEmailChangeRequest{Token: token}); err != nil {
+		return fmt.Errorf("client: reverting email change, %w", err)
+	}
+	return nil
+}
+
+// BeginWebAuthnRegistration begins the registration of a passkey for a new user under name,
+// finish it with [Client.FinishWebAuthnRegistration].
+func (x *Client) BeginWebAuthnRegistration(ctx context.Context, name, displayName string) (WebAuthnChallenge, error) {
+	res, err := x.rpc.BeginWebAuthnRegistration(ctx, &gen.BeginWebAuthnRegistrationRequest{
+		Name:        name,
+		DisplayName: displayName,
+	})
+	if err != nil {
+		return WebAuthnChallenge{}, fmt.Errorf("client: beginning webauthn registration, %w", err)
+	}
+	return newWebAuthnChallenge(res), nil
+}
+
+// BeginWebAuthnAuthentication begins an authentication with a passkey of the user registered under name,
+// an empty name lets the authenticator choose a passkey. Finish it with [Client.FinishWebAuthnAuthentication].
+func (x *Client) BeginWebAuthnAuthentication(ctx context.Context, name string) (WebAuthnChallenge, error) {
+	res, err := x.rpc.BeginWebAuthnAuthentication(ctx, &gen.BeginWebAuthnAuthenticationRequest{Name: name})
+	if err != nil {
+		return WebAuthnChallenge{}, fmt.Errorf("client: beginning webauthn authentication, %w", err)
+	}
+	return newWebAuthnChallenge(res), nil
+}
+
+// FinishWebAuthnRegistration answers the challenge of [Client.BeginWebAuthnRegistration] with in,
+// created with [WebAuthnInput], and registers the user.
+func (x *Client) FinishWebAuthnRegistration(ctx context.Context, in Input) error {
+	if _, err := x.rpc.FinishWebAuthnRegistration(ctx, &gen.FinishWebAuthnRegistrationRequest{
+		Input: in.in.GetWebAuthn(),
+	}); err != nil {
+		return fmt.Errorf("client: finishing webauthn registration, %w", err)
+	}
+	return nil
+}
+
+// FinishWebAuthnAuthentication answers the challenge of [Client.BeginWebAuthnAuthentication] with in,
+// created with [WebAuthnInput].
+func (x *Client) FinishWebAuthnAuthentication(ctx context.Context, in Input) (Tokens, error) {
+	res, err := x.rpc.FinishWebAuthnAuthentication(ctx, &gen.FinishWebAuthnAuthenticationRequest{
+		Input:      in.in.GetWebAuthn(),
+		RememberMe: in.in.GetRememberMe(),
+	})
+	if err != nil {
+		return Tokens{}, fmt.Errorf("client: finishing webauthn authentication, %w", err)
+	}
+	return newTokens(res)
+}
+
+// Revoke revokes the given tokens, either can be empty.
+func (x *Client) Revoke(ctx context.Context, accessToken, refreshToken string) error {
+	if _, err := x.rpc.Revoke(ctx, &gen.RevokeRequest{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	}); err != nil {
+		return fmt.Errorf("client: revoking, %w", err)
+	}
+	return nil
+}
+
+func newTokens(res *gen.AuthenticateResponse) (Tokens, error) {
+	id, err := uuid.Parse(res.GetId())
+	if err != nil {
+		return Tokens{}, fmt.Errorf("client: parsing user ID, %w", err)
+	}
+	tokens := Tokens{
+		UserID:       id,
+		AccessToken:  res.GetAccessToken(),
+		RefreshToken: res.GetRefreshToken(),
+		MFAChallenge: res.GetMfaChallenge(),
+	}
+	if res.GetCreatedAt() != nil {
+		tokens.IssuedAt = res.GetCreatedAt().AsTime()
+	}
+	if res.GetAccessTokenExpiresAt() != nil {
+		tokens.AccessExpiresAt = res.GetAccessTokenExpiresAt().AsTime()
+	}
+	if res.GetRefreshTokenExpiresAt() != nil {
+		tokens.RefreshExpiresAt = res.GetRefreshTokenExpiresAt().AsTime()
+	}
+	return tokens, nil
+}
+
+func newWebAuthnChallenge(res *gen.WebAuthnChallenge) WebAuthnChallenge {
+	challenge := WebAuthnChallenge{ID: res.GetChallengeId(), Options: res.GetOptions()}
+	if res.GetExpiresAt() != nil {
+		challenge.ExpiresAt = res.GetExpiresAt().AsTime()
+	}
+	return challenge
+}
@@ -0,0 +1,440 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// fakeIdentity records the requests it gets and answers them with pair until it is set to fail.
+type fakeIdentity struct {
+	gen.IdentityClient
+	userID   uuid.UUID
+	renewals int
+	fail     bool
+	mfa      bool
+	inputs   []*gen.Input
+	revoked  *gen.RevokeRequest
+	verified *gen.VerifyMFARequest
+	names    []string
+	started  *gen.StartPasswordlessRequest
+	answered *gen.VerifyPasswordlessRequest
+	emails   []string
+	reset    *gen.ResetPasswordRequest
+	tokens   []string
+}
+
+func (x *fakeIdentity) pair(ctx context.Context) (*gen.AuthenticateResponse, error) {
+	if x.fail {
+		return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+	}
+	now := time.Now()
+	return &gen.AuthenticateResponse{
+		Id:                    x.userID.String(),
+		AccessToken:           "access-" + uuid.NewString(),
+		RefreshToken:          "refresh-" + uuid.NewString(),
+		CreatedAt:             timestamppb.New(now),
+		AccessTokenExpiresAt:  timestamppb.New(now.Add(time.Minute)),
+		RefreshTokenExpiresAt: timestamppb.New(now.Add(time.Hour)),
+	}, nil
+}
+
+func (x *fakeIdentity) Register(_ context.Context, in *gen.Input, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	x.inputs = append(x.inputs, in)
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) Authenticate(ctx context.Context, in *gen.Input, _ ...grpc.CallOption) (*gen.AuthenticateResponse, error) {
+	x.inputs = append(x.inputs, in)
+	if x.mfa {
+		return &gen.AuthenticateResponse{Id: x.userID.String(), MfaChallenge: "challenge"}, nil
+	}
+	return x.pair(ctx)
+}
+
+func (x *fakeIdentity) VerifyMFA(ctx context.Context, req *gen.VerifyMFARequest, _ ...grpc.CallOption) (*gen.AuthenticateResponse, error) {
+	x.verified = req
+	return x.pair(ctx)
+}
+
+func (x *fakeIdentity) BeginWebAuthnRegistration(
+	_ context.Context,
+	req *gen.BeginWebAuthnRegistrationRequest,
+	_ ...grpc.CallOption,
+) (*gen.WebAuthnChallenge, error) {
+	x.names = append(x.names, req.GetName())
+	return x.challenge(), nil
+}
+
+func (x *fakeIdentity) BeginWebAuthnAuthentication(
+	_ context.Context,
+	req *gen.BeginWebAuthnAuthenticationRequest,
+	_ ...grpc.CallOption,
+) (*gen.WebAuthnChallenge, error) {
+	x.names = append(x.names, req.GetName())
+	return x.challenge(), nil
+}
+
+func (x *fakeIdentity) FinishWebAuthnRegistration(
+	_ context.Context,
+	req *gen.FinishWebAuthnRegistrationRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	x.inputs = append(x.inputs, &gen.Input{
+		Strategy: gen.Strategy_WebAuthn,
+		Data:     &gen.Input_WebAuthn{WebAuthn: req.GetInput()},
+	})
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) FinishWebAuthnAuthentication(
+	ctx context.Context,
+	req *gen.FinishWebAuthnAuthenticationRequest,
+	_ ...grpc.CallOption,
+) (*gen.AuthenticateResponse, error) {
+	x.inputs = append(x.inputs, &gen.Input{
+		Strategy:   gen.Strategy_WebAuthn,
+		Data:       &gen.Input_WebAuthn{WebAuthn: req.GetInput()},
+		RememberMe: req.GetRememberMe(),
+	})
+	return x.pair(ctx)
+}
+
+func (x *fakeIdentity) StartPasswordless(
+	_ context.Context,
+	req *gen.StartPasswordlessRequest,
+	_ ...grpc.CallOption,
+) (*gen.PasswordlessChallenge, error) {
+	x.started = req
+	return &gen.PasswordlessChallenge{
+		ChallengeId: uuid.NewString(),
+		ExpiresAt:   timestamppb.New(time.Now().Add(time.Minute)),
+	}, nil
+}
+
+func (x *fakeIdentity) VerifyPasswordless(
+	ctx context.Context,
+	req *gen.VerifyPasswordlessRequest,
+	_ ...grpc.CallOption,
+) (*gen.AuthenticateResponse, error) {
+	x.answered = req
+	return x.pair(ctx)
+}
+
+func (x *fakeIdentity) VerifyEmail(
+	_ context.Context,
+	_ *gen.VerifyEmailRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	if x.fail {
+		return nil, status.Error(codes.Unauthenticated, "invalid verification token")
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) ResendVerificationEmail(
+	_ context.Context,
+	req *gen.ResendVerificationEmailRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	x.emails = append(x.emails, req.GetEmail())
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) RequestPasswordReset(
+	_ context.Context,
+	req *gen.RequestPasswordResetRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	x.emails = append(x.emails, req.GetEmail())
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) ResetPassword(
+	_ context.Context,
+	req *gen.ResetPasswordRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	x.reset = req
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) ConfirmEmailChange(
+	_ context.Context,
+	req *gen.ConfirmEmailChangeRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	if x.fail {
+		return nil, status.Error(codes.AlreadyExists, "email is taken")
+	}
+	x.tokens = append(x.tokens, req.GetToken())
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) RevertEmailChange(
+	_ context.Context,
+	req *gen.RevertEmailChangeRequest,
+	_ ...grpc.CallOption,
+) (*emptypb.Empty, error) {
+	x.tokens = append(x.tokens, req.GetToken())
+	return &emptypb.Empty{}, nil
+}
+
+func (x *fakeIdentity) challenge() *gen.WebAuthnChallenge {
+	return &gen.WebAuthnChallenge{
+		ChallengeId: uuid.NewString(),
+		Options:     []byte(`{"publicKey":{}}`),
+		ExpiresAt:   timestamppb.New(time.Now().Add(time.Minute)),
+	}
+}
+
+func (x *fakeIdentity) Renew(ctx context.Context, _ *gen.RenewRequest, _ ...grpc.CallOption) (*gen.AuthenticateResponse, error) {
+	x.renewals++
+	return x.pair(ctx)
+}
+
+func (x *fakeIdentity) Revoke(_ context.Context, req *gen.RevokeRequest, _ ...grpc.CallOption) (*emptypb.Empty, error) {
+	x.revoked = req
+	return &emptypb.Empty{}, nil
+}
+
+func TestClient(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	if err := c.Register(context.Background(), CredentialsInput("email@email.com", "securePassword400")); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	tokens, err := c.Authenticate(context.Background(), PersonalNumberInput(198112189876))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if tokens.UserID != fake.userID || tokens.AccessToken == "" || tokens.AccessExpiresAt.IsZero() {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	if got := fake.inputs[0].GetCredentials().GetEmail(); got != "email@email.com" {
+		t.Errorf("expected email email@email.com, got %s", got)
+	}
+	if got := fake.inputs[1].GetNumbers().GetNumbers(); got != 198112189876 {
+		t.Errorf("expected numbers 198112189876, got %d", got)
+	}
+
+	in := CredentialsInput("email@email.com", "securePassword400")
+	if _, err = c.Authenticate(context.Background(), in.WithRememberMe()); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if !fake.inputs[2].GetRememberMe() || in.in.GetRememberMe() {
+		t.Error("expected only the copy to remember me")
+	}
+
+	renewed, err := c.Renew(context.Background(), tokens.RefreshToken)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if renewed.RefreshToken == tokens.RefreshToken {
+		t.Error("expected a new refresh token")
+	}
+
+	if err = c.Revoke(context.Background(), renewed.AccessToken, renewed.RefreshToken); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if fake.revoked.GetRefreshToken() != renewed.RefreshToken {
+		t.Errorf("expected %s to be revoked, got %s", renewed.RefreshToken, fake.revoked.GetRefreshToken())
+	}
+
+	fake.fail = true
+	if _, err = c.Renew(context.Background(), renewed.RefreshToken); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected code %s, got %v", codes.Unauthenticated, err)
+	}
+	if !errors.As(err, new(interface{ GRPCStatus() *status.Status })) {
+		t.Error("expected the status to be wrapped")
+	}
+}
+
+func TestClientMFA(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New(), mfa: true}
+	c := &Client{rpc: fake}
+
+	challenged, err := c.Authenticate(context.Background(), CredentialsInput("email@email.com", "securePassword400"))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if challenged.MFAChallenge != "challenge" || challenged.AccessToken != "" || !challenged.IssuedAt.IsZero() {
+		t.Errorf("expected only an mfa challenge, got %+v", challenged)
+	}
+
+	tokens, err := c.VerifyMFA(context.Background(), challenged.MFAChallenge, "123456")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if tokens.AccessToken == "" || tokens.MFAChallenge != "" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	if fake.verified.GetMfaChallenge() != "challenge" || fake.verified.GetCode() != "123456" {
+		t.Errorf("unexpected verify request %+v", fake.verified)
+	}
+}
+
+func TestClientWebAuthn(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	registration, err := c.BeginWebAuthnRegistration(context.Background(), "name", "")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if registration.ID == "" || len(registration.Options) == 0 || registration.ExpiresAt.IsZero() {
+		t.Errorf("unexpected challenge %+v", registration)
+	}
+	if err = c.FinishWebAuthnRegistration(context.Background(), WebAuthnInput(registration.ID, []byte("{}"))); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	authentication, err := c.BeginWebAuthnAuthentication(context.Background(), "")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	_, err = c.FinishWebAuthnAuthentication(context.Background(), WebAuthnInput(authentication.ID, []byte("{}")).WithRememberMe())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	if len(fake.names) != 2 || fake.names[0] != "name" || fake.names[1] != "" {
+		t.Errorf("unexpected names %v", fake.names)
+	}
+	if len(fake.inputs) != 2 {
+		t.Fatalf("expected 2 inputs, got %d", len(fake.inputs))
+	}
+	if fake.inputs[0].GetWebAuthn().GetChallengeId() != registration.ID {
+		t.Errorf("unexpected input %+v", fake.inputs[0])
+	}
+	if fake.inputs[1].GetStrategy() != gen.Strategy_WebAuthn ||
+		fake.inputs[1].GetWebAuthn().GetChallengeId() != authentication.ID ||
+		!fake.inputs[1].GetRememberMe() {
+		t.Errorf("unexpected input %+v", fake.inputs[1])
+	}
+}
+
+func TestClientPasswordless(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	challenge, err := c.StartPasswordless(context.Background(), EmailCode("email@email.com"))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if challenge.ID == "" || challenge.ExpiresAt.IsZero() {
+		t.Errorf("unexpected challenge %+v", challenge)
+	}
+	if fake.started.GetAddress() != "email@email.com" || fake.started.GetMethod() != gen.DeliveryMethod_Code {
+		t.Errorf("unexpected start request %+v", fake.started)
+	}
+
+	tokens, err := c.VerifyPasswordless(context.Background(), EmailCodeInput(challenge.ID, "123456").WithRememberMe())
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if tokens.UserID != fake.userID || tokens.AccessToken == "" {
+		t.Errorf("unexpected tokens %+v", tokens)
+	}
+	if fake.answered.GetStrategy() != gen.Strategy_Email ||
+		fake.answered.GetInput().GetChallengeId() != challenge.ID ||
+		fake.answered.GetInput().GetCode() != "123456" ||
+		!fake.answered.GetRememberMe() {
+		t.Errorf("unexpected verify request %+v", fake.answered)
+	}
+
+	if _, err = c.StartPasswordless(context.Background(), EmailLink("email@email.com")); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if fake.started.GetMethod() != gen.DeliveryMethod_Link {
+		t.Errorf("expected a link, got %s", fake.started.GetMethod())
+	}
+	if _, err = c.VerifyPasswordless(context.Background(), EmailLinkInput("token")); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if fake.answered.GetInput().GetToken() != "token" {
+		t.Errorf("unexpected verify request %+v", fake.answered)
+	}
+
+	if _, err = c.StartPasswordless(context.Background(), SMSCode("+46701234567")); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if fake.started.GetStrategy() != gen.Strategy_SMS || fake.started.GetAddress() != "+46701234567" {
+		t.Errorf("unexpected start request %+v", fake.started)
+	}
+	if _, err = c.VerifyPasswordless(context.Background(), SMSCodeInput(challenge.ID, "654321")); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if fake.answered.GetStrategy() != gen.Strategy_SMS || fake.answered.GetInput().GetCode() != "654321" {
+		t.Errorf("unexpected verify request %+v", fake.answered)
+	}
+}
+
+func TestClientEmailVerification(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	if err := c.ResendVerificationEmail(context.Background(), "email@email.com"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if len(fake.emails) != 1 || fake.emails[0] != "email@email.com" {
+		t.Errorf("unexpected resend requests %v", fake.emails)
+	}
+
+	if err := c.VerifyEmail(context.Background(), "token"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	fake.fail = true
+	if err := c.VerifyEmail(context.Background(), "token"); status.Code(errors.Unwrap(err)) != codes.Unauthenticated {
+		t.Errorf("expected an unauthenticated error, got %v", err)
+	}
+}
+
+func TestClientPasswordReset(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	if err := c.RequestPasswordReset(context.Background(), "email@email.com"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if len(fake.emails) != 1 || fake.emails[0] != "email@email.com" {
+		t.Errorf("unexpected password reset requests %v", fake.emails)
+	}
+
+	if err := c.ResetPassword(context.Background(), "token", "N3wPassw0rd!"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if fake.reset.GetToken() != "token" || fake.reset.GetNewPassword() != "N3wPassw0rd!" {
+		t.Errorf("unexpected reset request %+v", fake.reset)
+	}
+}
+
+func TestClientEmailChange(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	if err := c.ConfirmEmailChange(context.Background(), "confirm"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if err := c.RevertEmailChange(context.Background(), "revert"); err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	if len(fake.tokens) != 2 || fake.tokens[0] != "confirm" || fake.tokens[1] != "revert" {
+		t.Errorf("unexpected email change tokens %v", fake.tokens)
+	}
+
+	fake.fail = true
+	if err := c.ConfirmEmailChange(context.Background(), "confirm"); status.Code(errors.Unwrap(err)) != codes.AlreadyExists {
+		t.Errorf("expected an already exists error, got %v", err)
+	}
+}
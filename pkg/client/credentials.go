@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Salam4nder/identity/proto/gen"
+	"google.golang.org/grpc/credentials"
+)
+
+var _ credentials.PerRPCCredentials = (*Credentials)(nil)
+
+// DefaultLeeway is how long before it expires an access token is renewed by default.
+const DefaultLeeway = 30 * time.Second
+
+// publicMethods of the identity service are called without an access token.
+// Introspect authenticates with its own basic authorization.
+var publicMethods = func() map[string]struct{} {
+	methods := gen.PublicMethods()
+	m := make(map[string]struct{}, len(methods))
+	for _, method := range methods {
+		m[method] = struct{}{}
+	}
+	return m
+}()
+
+// CredentialsOpts configure [Credentials].
+type CredentialsOpts struct {
+	// Leeway is how long before it expires the access token is renewed, [DefaultLeeway] if zero.
+	Leeway time.Duration
+	// Insecure allows sending the access token without transport security.
+	Insecure bool
+	// OnRenew is called with the renewed [Tokens], for example to persist them.
+	// It is called while holding the lock of the credentials, so it must not call them.
+	OnRenew func(Tokens)
+}
+
+// Credentials implements [credentials.PerRPCCredentials].
+// It attaches the access token as bearer authorization metadata to every call,
+// and renews the tokens with the refresh token when the access token is about to expire.
+// Calls to the public methods of the identity service are left as is,
+// so the same connection can be used for the [Client] that renews.
+// It is safe for concurrent use, a single call renews while the others wait.
+type Credentials struct {
+	client *Client
+	opts   CredentialsOpts
+
+	mu     sync.Mutex
+	tokens Tokens
+}
+
+// NewCredentials returns new [Credentials] starting with tokens, usually from [Client.Authenticate].
+// Pass them to the connection with grpc.WithPerRPCCredentials or to a call with grpc.PerRPCCredentials.
+func NewCredentials(client *Client, tokens Tokens, opts CredentialsOpts) *Credentials {
+	if opts.Leeway <= 0 {
+		opts.Leeway = DefaultLeeway
+	}
+	return &Credentials{client: client, opts: opts, tokens: tokens}
+}
+
+// Tokens returns the current tokens.
+func (x *Credentials) Tokens() Tokens {
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	return x.tokens
+}
+
+// GetRequestMetadata returns the bearer authorization metadata, renewing the tokens first if needed.
+func (x *Credentials) GetRequestMetadata(ctx context.Context, _ ...string) (map[string]string, error) {
+	if info, ok := credentials.RequestInfoFromContext(ctx); ok {
+		if _, public := publicMethods[info.Method]; public {
+			return nil, nil
+		}
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+
+	// Without an expiry the token is never renewed up front.
+	if !x.tokens.AccessExpiresAt.IsZero() && time.Until(x.tokens.AccessExpiresAt) < x.opts.Leeway {
+		tokens, err := x.client.Renew(ctx, x.tokens.RefreshToken)
+		if err != nil {
+			return nil, err
+		}
+		x.tokens = tokens
+		if x.opts.OnRenew != nil {
+			x.opts.OnRenew(tokens)
+		}
+	}
+
+	return map[string]string{"authorization": "Bearer " + x.tokens.AccessToken}, nil
+}
+
+// RequireTransportSecurity reports whether the access token may only be sent over TLS.
+func (x *Credentials) RequireTransportSecurity() bool {
+	return !x.opts.Insecure
+}
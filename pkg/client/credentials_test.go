@@ -0,0 +1,198 @@
+package client
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/grpc/server"
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthgen "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCredentials(t *testing.T) {
+	fake := &fakeIdentity{userID: uuid.New()}
+	c := &Client{rpc: fake}
+
+	t.Run("fresh token", func(t *testing.T) {
+		tokens := Tokens{AccessToken: "access", RefreshToken: "refresh", AccessExpiresAt: time.Now().Add(time.Minute)}
+		creds := NewCredentials(c, tokens, CredentialsOpts{})
+
+		md, err := creds.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if md["authorization"] != "Bearer access" {
+			t.Errorf("expected authorization Bearer access, got %s", md["authorization"])
+		}
+		if fake.renewals != 0 {
+			t.Errorf("expected no renewals, got %d", fake.renewals)
+		}
+		if !creds.RequireTransportSecurity() {
+			t.Error("expected transport security to be required")
+		}
+	})
+
+	t.Run("renews near expiry", func(t *testing.T) {
+		fake.renewals = 0
+		var renewed []Tokens
+		tokens := Tokens{AccessToken: "access", RefreshToken: "refresh", AccessExpiresAt: time.Now().Add(time.Second)}
+		creds := NewCredentials(c, tokens, CredentialsOpts{OnRenew: func(t Tokens) { renewed = append(renewed, t) }})
+
+		var wg sync.WaitGroup
+		for range 10 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if _, err := creds.GetRequestMetadata(context.Background()); err != nil {
+					t.Errorf("expected no error, got %s", err.Error())
+				}
+			}()
+		}
+		wg.Wait()
+
+		if fake.renewals != 1 || len(renewed) != 1 {
+			t.Fatalf("expected a single renewal, got %d", fake.renewals)
+		}
+		md, err := creds.GetRequestMetadata(context.Background())
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if md["authorization"] != "Bearer "+renewed[0].AccessToken {
+			t.Errorf("expected the renewed access token, got %s", md["authorization"])
+		}
+		if creds.Tokens().RefreshToken != renewed[0].RefreshToken {
+			t.Error("expected the renewed refresh token")
+		}
+	})
+
+	t.Run("renewal fails", func(t *testing.T) {
+		fake.fail = true
+		defer func() { fake.fail = false }()
+
+		tokens := Tokens{AccessToken: "access", RefreshToken: "refresh", AccessExpiresAt: time.Now()}
+		creds := NewCredentials(c, tokens, CredentialsOpts{})
+		if _, err := creds.GetRequestMetadata(context.Background()); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("expected code %s, got %v", codes.Unauthenticated, err)
+		}
+	})
+
+	t.Run("public method", func(t *testing.T) {
+		creds := NewCredentials(c, Tokens{AccessToken: "access"}, CredentialsOpts{Insecure: true})
+		if creds.RequireTransportSecurity() {
+			t.Error("expected transport security not to be required")
+		}
+
+		srv := &recordingServer{}
+		conn := serve(t, srv, creds)
+		client := New(conn)
+
+		if _, err := client.Renew(context.Background(), "refresh"); status.Code(err) != codes.Unauthenticated {
+			t.Fatalf("expected code %s, got %v", codes.Unauthenticated, err)
+		}
+		if len(srv.authorization) != 0 {
+			t.Errorf("expected no authorization for Renew, got %v", srv.authorization)
+		}
+
+		if _, err := healthgen.NewHealthClient(conn).Check(context.Background(), &healthgen.HealthCheckRequest{}); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if len(srv.authorization) != 1 || srv.authorization[0] != "Bearer access" {
+			t.Errorf("expected authorization Bearer access, got %v", srv.authorization)
+		}
+	})
+}
+
+// recordingServer records the authorization metadata of the last call.
+type recordingServer struct {
+	gen.UnimplementedIdentityServer
+	authorization []string
+}
+
+func (x *recordingServer) intercept(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	x.authorization = md.Get("authorization")
+	return handler(ctx, req)
+}
+
+func (x *recordingServer) Renew(context.Context, *gen.RenewRequest) (*gen.AuthenticateResponse, error) {
+	return nil, status.Error(codes.Unauthenticated, "invalid refresh token")
+}
+
+// serve serves srv and the health service on a local port
+// and returns a connection to it that uses creds.
+func serve(t *testing.T, srv *recordingServer, creds *Credentials) *grpc.ClientConn {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	s := grpc.NewServer(grpc.UnaryInterceptor(srv.intercept))
+	gen.RegisterIdentityServer(s, srv)
+	healthgen.RegisterHealthServer(s, health.NewServer())
+	go func() { _ = s.Serve(lis) }()
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(
+		lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(creds),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func TestPublicMethods(t *testing.T) {
+	want := []string{
+		gen.Identity_Register_FullMethodName,
+		gen.Identity_Authenticate_FullMethodName,
+		gen.Identity_Renew_FullMethodName,
+		gen.Identity_Revoke_FullMethodName,
+		gen.Identity_PublicKeys_FullMethodName,
+		gen.Identity_Introspect_FullMethodName,
+		gen.Identity_VerifyMFA_FullMethodName,
+		gen.Identity_BeginWebAuthnRegistration_FullMethodName,
+		gen.Identity_FinishWebAuthnRegistration_FullMethodName,
+		gen.Identity_BeginWebAuthnAuthentication_FullMethodName,
+		gen.Identity_FinishWebAuthnAuthentication_FullMethodName,
+		gen.Identity_StartPasswordless_FullMethodName,
+		gen.Identity_VerifyPasswordless_FullMethodName,
+		gen.Identity_VerifyEmail_FullMethodName,
+		gen.Identity_ResendVerificationEmail_FullMethodName,
+		gen.Identity_RequestPasswordReset_FullMethodName,
+		gen.Identity_ResetPassword_FullMethodName,
+		gen.Identity_ConfirmEmailChange_FullMethodName,
+		gen.Identity_RevertEmailChange_FullMethodName,
+	}
+
+	if len(publicMethods) != len(server.PublicMethods) {
+		t.Fatalf("expected %d public methods, the server has %d", len(publicMethods), len(server.PublicMethods))
+	}
+	for _, method := range server.PublicMethods {
+		if _, ok := publicMethods[method]; !ok {
+			t.Errorf("server method %s is not public for the client", method)
+		}
+	}
+
+	if len(publicMethods) != len(want) {
+		t.Errorf("expected %d public methods, got %d", len(want), len(publicMethods))
+	}
+	for _, method := range want {
+		if _, ok := publicMethods[method]; !ok {
+			t.Errorf("expected %s to be public", method)
+		}
+	}
+}
@@ -34,6 +34,13 @@ func (x TooShortError) Error() string {
 	return fmt.Sprintf("password: must be at least %d characters long", MinChars)
 }
 
+// MismatchError is returned when a password does not match a stored hash.
+type MismatchError struct{}
+
+func (x MismatchError) Error() string {
+	return "password: does not match"
+}
+
 type TooLongError struct {
 	displayedForUser bool
 }
@@ -113,3 +120,16 @@ func (x SafeString) Value() (driver.Value, error) {
 
 	return bcrypt.GenerateFromPassword([]byte(x), bcrypt.DefaultCost)
 }
+
+// Compare checks the underlying string against a BCRYPT hash produced by [Value()].
+// Returns [MismatchError] if they do not match.
+func (x SafeString) Compare(hash string) error {
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(x))
+	if err != nil {
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return MismatchError{}
+		}
+		return fmt.Errorf("password: comparing hash, %w", err)
+	}
+	return nil
+}
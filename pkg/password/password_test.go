@@ -55,3 +55,34 @@ func TestString(t *testing.T) {
 		t.Errorf("expected REDACTED, got %s", pw.String())
 	}
 }
+
+func TestCompare(t *testing.T) {
+	pw, err := FromString("myC00lp4zzW0rd")
+	if err != nil {
+		t.Error("expected no error")
+	}
+	hash, err := pw.Value()
+	if err != nil {
+		t.Errorf("expected no error, got %s", err.Error())
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		if err := pw.Compare(string(hash.([]byte))); err != nil {
+			t.Errorf("expected no error, got %s", err.Error())
+		}
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		other := SafeString("n0tMyP4zzW0rd")
+		if err := other.Compare(string(hash.([]byte))); !errors.As(err, &MismatchError{}) {
+			t.Errorf("expected MismatchError, got %T", err)
+		}
+	})
+
+	t.Run("Invalid hash", func(t *testing.T) {
+		err := pw.Compare("not a hash")
+		if err == nil || errors.As(err, &MismatchError{}) {
+			t.Errorf("expected non-mismatch error, got %v", err)
+		}
+	})
+}
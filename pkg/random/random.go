@@ -1,6 +1,7 @@
 package random
 
 import (
+	"fmt"
 	"math/rand"
 	"strings"
 	"time"
@@ -34,6 +35,11 @@ func Email() string {
 	return String(10) + "@" + String(5) + ".com"
 }
 
+// Phone returns a random phone number in E.164 format.
+func Phone() string {
+	return fmt.Sprintf("+4670%07d", Int(0, 9_999_999))
+}
+
 // FullName returns a random full name.
 func FullName() string {
 	return String(10) + " " + String(10)
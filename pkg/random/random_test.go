@@ -40,6 +40,15 @@ func TestEmail(t *testing.T) {
 	}
 }
 
+func TestPhone(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		res := Phone()
+
+		assert.True(t, strings.HasPrefix(res, "+"))
+		assert.Len(t, res, 12)
+	}
+}
+
 func TestFullName(t *testing.T) {
 	for i := 0; i < 20; i++ {
 		res := FullName()
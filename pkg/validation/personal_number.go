@@ -0,0 +1,45 @@
+package validation
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// PersonalNumberLen is the amount of digits in a personal number, YYYYMMDDNNNC.
+const PersonalNumberLen = 12
+
+// PersonalNumber checks if the given personal number is valid.
+// It must be [PersonalNumberLen] digits long and its last digit must be
+// the Luhn checksum of the 9 digits after the century, like a Swedish personal identity number.
+func PersonalNumber(value uint64) error {
+	digits := strconv.FormatUint(value, 10)
+	if len(digits) != PersonalNumberLen {
+		return InputError{
+			text: fmt.Sprintf("validation: personal number must be %d digits long", PersonalNumberLen),
+		}
+	}
+
+	if !luhn(digits[2:]) {
+		return InputError{text: "validation: personal number has an invalid checksum"}
+	}
+
+	return nil
+}
+
+// luhn reports whether the last digit of digits is the Luhn checksum of the rest.
+func luhn(digits string) bool {
+	var sum int
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
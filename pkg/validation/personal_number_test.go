@@ -0,0 +1,35 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPersonalNumber(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		for _, n := range []uint64{198112189876, 199001011239, 201212121212} {
+			if err := PersonalNumber(n); err != nil {
+				t.Errorf("expected no error for %d, got %s", n, err)
+			}
+		}
+	})
+
+	t.Run("Too short", func(t *testing.T) {
+		if err := PersonalNumber(8112189876); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("Too long", func(t *testing.T) {
+		if err := PersonalNumber(1981121898761); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	t.Run("Invalid checksum", func(t *testing.T) {
+		err := PersonalNumber(198112189875)
+		if !errors.As(err, &InputError{}) {
+			t.Error("expected InputError", err)
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package validation
+
+import "regexp"
+
+// isE164 matches a phone number in E.164 format, a plus followed by at most 15 digits
+// of which the first is the country code and not zero.
+var isE164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`).MatchString
+
+// Phone checks if the given phone number is valid.
+// It must be in E.164 format, like +46701234567, without spaces or dashes.
+func Phone(value string) error {
+	if !isE164(value) {
+		return InputError{text: "validation: phone number must be in E.164 format, like +46701234567"}
+	}
+
+	return nil
+}
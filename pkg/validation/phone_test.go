@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPhone(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		for _, p := range []string{"+46701234567", "+14155552671", "+442071838750"} {
+			if err := Phone(p); err != nil {
+				t.Errorf("expected no error for %s, got %s", p, err)
+			}
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, p := range []string{
+			"",
+			"46701234567",
+			"+046701234567",
+			"+46 70 123 45 67",
+			"+46-70-1234567",
+			"+1234",
+			"+1234567890123456",
+			"+4670123456a",
+		} {
+			if err := Phone(p); err == nil {
+				t.Errorf("expected error for %q", p)
+			}
+		}
+	})
+
+	t.Run("InputError", func(t *testing.T) {
+		err := Phone("phone")
+		if !errors.As(err, &InputError{}) {
+			t.Error("expected InputError", err)
+		}
+	})
+}
@@ -0,0 +1,95 @@
+package verifier
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/proto/gen"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// introspectClient answers Introspect with res and records the authorization metadata.
+type introspectClient struct {
+	gen.IdentityClient
+	res           *gen.IntrospectResponse
+	authorization string
+}
+
+func (x *introspectClient) Introspect(ctx context.Context, _ *gen.IntrospectRequest, _ ...grpc.CallOption) (*gen.IntrospectResponse, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if v := md.Get("authorization"); len(v) > 0 {
+		x.authorization = v[0]
+	}
+	return x.res, nil
+}
+
+func TestIntrospection(t *testing.T) {
+	sub := uuid.New()
+	now := time.Now().Truncate(time.Second)
+	active := &gen.IntrospectResponse{
+		Active:    true,
+		Scope:     "read",
+		TokenType: "access_token",
+		Exp:       timestamppb.New(now.Add(time.Minute)),
+		Iat:       timestamppb.New(now),
+		Sub:       sub.String(),
+		Aud:       "test",
+		Iss:       "identity-test",
+		Jti:       "jti",
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		client := &introspectClient{res: active}
+		v, err := NewIntrospection(testOpts(), client, "id", "secret")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+
+		claims, err := v.Verify(context.Background(), "token")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if claims.Subject != sub || claims.ID != "jti" || !claims.HasScope("read") || !claims.ExpiresAt.Equal(now.Add(time.Minute)) {
+			t.Errorf("unexpected claims %+v", claims)
+		}
+		if want := "Basic " + base64.StdEncoding.EncodeToString([]byte("id:secret")); client.authorization != want {
+			t.Errorf("expected authorization %s, got %s", want, client.authorization)
+		}
+	})
+
+	t.Run("inactive", func(t *testing.T) {
+		v, err := NewIntrospection(testOpts(), &introspectClient{res: &gen.IntrospectResponse{}}, "id", "secret")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err := v.Verify(context.Background(), "token"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		res := proto.Clone(active).(*gen.IntrospectResponse)
+		res.TokenType = "refresh_token"
+		v, err := NewIntrospection(testOpts(), &introspectClient{res: res}, "id", "secret")
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err := v.Verify(context.Background(), "token"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("missing options", func(t *testing.T) {
+		client := &introspectClient{res: active}
+		if _, err := NewIntrospection(Options{Issuer: "identity-test"}, client, "id", "secret"); err == nil {
+			t.Error("expected error without audience")
+		}
+	})
+}
@@ -0,0 +1,148 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var _ KeySource = (*JWKS)(nil)
+
+// minRefresh is the least time between two fetches caused by an unknown key ID,
+// so tokens with made up key IDs can not flood the identity service.
+const minRefresh = 10 * time.Second
+
+// fetchTimeout bounds a single fetch of the set, which outlives the call that started it.
+const fetchTimeout = 10 * time.Second
+
+// JWKS is a [KeySource] that fetches the JSON Web Key Set published by the identity service,
+// like "http://identity:8090/.well-known/jwks.json", and caches it.
+// The set is fetched again when it is older than its TTL or a token was made with an unknown key,
+// which happens when the identity service rotates its keys. It is safe for concurrent use,
+// cached keys are served while the set is fetched and concurrent calls share a single fetch.
+type JWKS struct {
+	url    string
+	client *http.Client
+	ttl    time.Duration
+	group  singleflight.Group
+
+	mu        sync.RWMutex
+	keys      map[string]ed25519.PublicKey
+	fetchedAt time.Time
+}
+
+// NewJWKS returns a new [JWKS] that caches the set at url for ttl.
+// http.DefaultClient is used if client is nil.
+func NewJWKS(url string, client *http.Client, ttl time.Duration) *JWKS {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &JWKS{url: url, client: client, ttl: ttl}
+}
+
+// Key returns the Ed25519 public key with the given ID.
+// Returns [ErrInvalid] if the set does not contain it.
+func (x *JWKS) Key(ctx context.Context, id string) (ed25519.PublicKey, error) {
+	x.mu.RLock()
+	key, ok := x.keys[id]
+	age := time.Since(x.fetchedAt)
+	x.mu.RUnlock()
+
+	if ok && age < x.ttl {
+		return key, nil
+	}
+	if ok || age >= minRefresh {
+		keys, err := x.refresh(ctx)
+		if err != nil {
+			// Serve the stale key rather than failing every call while the identity service is down.
+			if ok {
+				return key, nil
+			}
+			return nil, err
+		}
+		if key, ok = keys[id]; ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w, unknown key %q", ErrInvalid, id)
+}
+
+// refresh fetches the set without holding the lock and swaps it in, concurrent calls share one fetch.
+// A fetch is not canceled with the ctx of the call that started it, the others may still wait on it,
+// but every call stops waiting once its own ctx is done.
+func (x *JWKS) refresh(ctx context.Context) (map[string]ed25519.PublicKey, error) {
+	ch := x.group.DoChan(x.url, func() (any, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), fetchTimeout)
+		defer cancel()
+
+		keys, err := x.fetch(fetchCtx)
+		if err != nil {
+			return nil, err
+		}
+		x.mu.Lock()
+		x.keys, x.fetchedAt = keys, time.Now()
+		x.mu.Unlock()
+		return keys, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, fmt.Errorf("verifier: fetching keys, %w", ctx.Err())
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(map[string]ed25519.PublicKey), nil
+	}
+}
+
+// jwk is a public key in the JSON Web Key format, see RFC 7517 and RFC 8037.
+type jwk struct {
+	KeyType string `json:"kty"`
+	Curve   string `json:"crv"`
+	KeyID   string `json:"kid"`
+	X       string `json:"x"`
+}
+
+// fetch returns the Ed25519 keys of the set by ID, other keys are skipped.
+func (x *JWKS) fetch(ctx context.Context) (map[string]ed25519.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, x.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("verifier: creating request, %w", err)
+	}
+	res, err := x.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("verifier: fetching keys, %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("verifier: fetching keys, unexpected status %s", res.Status)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err = json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("verifier: decoding keys, %w", err)
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.KeyType != "OKP" || k.Curve != "Ed25519" {
+			continue
+		}
+		b, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(b) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("verifier: malformed key %q", k.KeyID)
+		}
+		keys[k.KeyID] = ed25519.PublicKey(b)
+	}
+	return keys, nil
+}
@@ -0,0 +1,82 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestJWKSConcurrent(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	var fetches atomic.Int32
+	started, release := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if fetches.Add(1) == 1 {
+			close(started)
+		}
+		<-release
+		fmt.Fprintf(
+			w,
+			`{"keys":[{"kty":"OKP","crv":"Ed25519","kid":"new","x":%q}]}`,
+			base64.RawURLEncoding.EncodeToString(pub),
+		)
+	}))
+	defer srv.Close()
+
+	jwks := NewJWKS(srv.URL, srv.Client(), time.Hour)
+	// The cached key is fresh, but the set is old enough to be fetched for an unknown key.
+	jwks.keys = map[string]ed25519.PublicKey{"cached": pub}
+	jwks.fetchedAt = time.Now().Add(-time.Minute)
+
+	const n = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := jwks.Key(context.Background(), "new")
+			errs <- err
+		}()
+	}
+	<-started
+
+	t.Run("cached key is served during a fetch", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if _, err := jwks.Key(ctx, "cached"); err != nil {
+			t.Errorf("expected no error, got %s", err.Error())
+		}
+	})
+
+	t.Run("waiting stops with ctx", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		if _, err := jwks.Key(ctx, "unknown"); err == nil {
+			t.Error("expected error")
+		}
+	})
+
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("expected no error, got %s", err.Error())
+		}
+	}
+	if got := fetches.Load(); got != 1 {
+		t.Errorf("expected a single fetch, got %d", got)
+	}
+}
@@ -0,0 +1,119 @@
+package verifier
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx that carries claims.
+func NewContext(ctx context.Context, claims Claims) context.Context {
+	return context.WithValue(ctx, contextKey{}, claims)
+}
+
+// FromContext returns the [Claims] of the verified access token of the request, if any.
+func FromContext(ctx context.Context) (Claims, bool) {
+	claims, ok := ctx.Value(contextKey{}).(Claims)
+	return claims, ok
+}
+
+// UnaryServerInterceptor returns a unary server interceptor that verifies the bearer access token
+// in the authorization metadata of every call with v and puts its [Claims] into the context.
+// Calls without a valid token fail with codes.Unauthenticated.
+func UnaryServerInterceptor(v Verifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := verifyIncoming(ctx, v)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor is the stream counterpart of [UnaryServerInterceptor].
+func StreamServerInterceptor(v Verifier) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := verifyIncoming(ss.Context(), v)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &verifiedStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// Middleware returns net/http middleware that verifies the bearer access token
+// in the Authorization header of every request with v and puts its [Claims] into the context.
+// Requests without a valid token are answered with 401 Unauthorized.
+func Middleware(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t, ok := bearerToken(r.Header.Get("Authorization"))
+			if !ok {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				http.Error(w, "missing access token", http.StatusUnauthorized)
+				return
+			}
+			claims, err := v.Verify(r.Context(), t)
+			if err != nil {
+				if errors.Is(err, ErrInvalid) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					http.Error(w, "invalid access token", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), claims)))
+		})
+	}
+}
+
+// verifyIncoming returns ctx with the claims of its access token.
+// Returned errors are ready to be sent to the client.
+func verifyIncoming(ctx context.Context, v Verifier) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) != 1 {
+		return nil, status.Error(codes.Unauthenticated, "missing access token")
+	}
+	t, ok := bearerToken(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "missing access token")
+	}
+
+	claims, err := v.Verify(ctx, t)
+	if err != nil {
+		if errors.Is(err, ErrInvalid) {
+			return nil, status.Error(codes.Unauthenticated, "invalid access token")
+		}
+		return nil, status.Error(codes.Internal, "verifying access token")
+	}
+	return NewContext(ctx, claims), nil
+}
+
+// bearerToken returns the token of a bearer authorization value.
+func bearerToken(authorization string) (string, bool) {
+	const prefix = "bearer "
+	if len(authorization) <= len(prefix) || !strings.EqualFold(authorization[:len(prefix)], prefix) {
+		return "", false
+	}
+	return authorization[len(prefix):], true
+}
+
+// verifiedStream overrides the context of a stream with the verified one.
+type verifiedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (x *verifiedStream) Context() context.Context {
+	return x.ctx
+}
@@ -0,0 +1,104 @@
+package verifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// staticVerifier accepts a single token.
+type staticVerifier struct {
+	token  string
+	claims Claims
+}
+
+func (x staticVerifier) Verify(_ context.Context, t string) (Claims, error) {
+	if t != x.token {
+		return Claims{}, ErrInvalid
+	}
+	return x.claims, nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	v := staticVerifier{token: "valid", claims: Claims{Subject: uuid.New()}}
+	interceptor := UnaryServerInterceptor(v)
+	handler := func(ctx context.Context, _ any) (any, error) {
+		claims, ok := FromContext(ctx)
+		if !ok {
+			t.Error("expected claims in context")
+		}
+		return claims, nil
+	}
+
+	for _, tc := range []struct {
+		name          string
+		authorization string
+		code          codes.Code
+	}{
+		{name: "OK", authorization: "Bearer valid", code: codes.OK},
+		{name: "lowercase scheme", authorization: "bearer valid", code: codes.OK},
+		{name: "missing", code: codes.Unauthenticated},
+		{name: "basic", authorization: "Basic dmFsaWQ=", code: codes.Unauthenticated},
+		{name: "invalid", authorization: "Bearer invalid", code: codes.Unauthenticated},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.authorization != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", tc.authorization))
+			}
+			res, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, handler)
+			if code := status.Code(err); code != tc.code {
+				t.Fatalf("expected code %s, got %s", tc.code, code)
+			}
+			if tc.code == codes.OK && res.(Claims).Subject != v.claims.Subject {
+				t.Errorf("expected subject %s, got %v", v.claims.Subject, res)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	v := staticVerifier{token: "valid", claims: Claims{Subject: uuid.New()}}
+	h := Middleware(v)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := FromContext(r.Context())
+		if !ok {
+			t.Error("expected claims in context")
+		}
+		_, _ = w.Write([]byte(claims.Subject.String()))
+	}))
+
+	for _, tc := range []struct {
+		name          string
+		authorization string
+		code          int
+	}{
+		{name: "OK", authorization: "Bearer valid", code: http.StatusOK},
+		{name: "missing", code: http.StatusUnauthorized},
+		{name: "invalid", authorization: "Bearer invalid", code: http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tc.code {
+				t.Fatalf("expected status %d, got %d", tc.code, rec.Code)
+			}
+			if tc.code == http.StatusOK && rec.Body.String() != v.claims.Subject.String() {
+				t.Errorf("expected subject %s, got %s", v.claims.Subject, rec.Body.String())
+			}
+			if tc.code == http.StatusUnauthorized && rec.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header")
+			}
+		})
+	}
+}
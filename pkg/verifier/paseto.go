@@ -0,0 +1,168 @@
+package verifier
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"aidanwoods.dev/go-paseto"
+	"github.com/google/uuid"
+)
+
+var (
+	_ Verifier = (*Local)(nil)
+	_ Verifier = (*Public)(nil)
+)
+
+// footer is the unencrypted footer of every PASETO token of the identity service.
+type footer struct {
+	KeyID string `json:"kid"`
+}
+
+// Local verifies v4.local PASETO tokens with the symmetric keys shared with the identity service.
+type Local struct {
+	opts   Options
+	keys   map[string]paseto.V4SymmetricKey
+	parser paseto.Parser
+}
+
+// NewLocal returns a new [Local] verifier. keys are the 32 byte symmetric keys
+// of the identity service, keyed by their ID.
+// Returns an error if an option is missing.
+func NewLocal(opts Options, keys map[string][]byte) (*Local, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("verifier: no keys")
+	}
+	m := make(map[string]paseto.V4SymmetricKey, len(keys))
+	for id, k := range keys {
+		key, err := paseto.V4SymmetricKeyFromBytes(k)
+		if err != nil {
+			return nil, fmt.Errorf("verifier: key %q, %w", id, err)
+		}
+		m[id] = key
+	}
+	return &Local{opts: opts, keys: m, parser: parser()}, nil
+}
+
+func (x *Local) Verify(_ context.Context, t string) (Claims, error) {
+	id, err := keyID(x.parser, paseto.V4Local, t)
+	if err != nil {
+		return Claims{}, err
+	}
+	key, ok := x.keys[id]
+	if !ok {
+		return Claims{}, fmt.Errorf("%w, unknown key %q", ErrInvalid, id)
+	}
+	token, err := x.parser.ParseV4Local(key, t, nil)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+	return pasetoClaims(x.opts, token)
+}
+
+// KeySource returns the Ed25519 public key with the given ID.
+type KeySource interface {
+	Key(ctx context.Context, id string) (ed25519.PublicKey, error)
+}
+
+// Public verifies v4.public PASETO tokens with the public keys of the identity service.
+type Public struct {
+	opts   Options
+	source KeySource
+	parser paseto.Parser
+}
+
+// NewPublic returns a new [Public] verifier, usually with a [JWKS] source.
+// Returns an error if an option is missing.
+func NewPublic(opts Options, source KeySource) (*Public, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	return &Public{opts: opts, source: source, parser: parser()}, nil
+}
+
+func (x *Public) Verify(ctx context.Context, t string) (Claims, error) {
+	id, err := keyID(x.parser, paseto.V4Public, t)
+	if err != nil {
+		return Claims{}, err
+	}
+	pub, err := x.source.Key(ctx, id)
+	if err != nil {
+		return Claims{}, err
+	}
+	key, err := paseto.NewV4AsymmetricPublicKeyFromEd25519(pub)
+	if err != nil {
+		return Claims{}, fmt.Errorf("verifier: key %q, %w", id, err)
+	}
+	token, err := x.parser.ParseV4Public(key, t, nil)
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+	return pasetoClaims(x.opts, token)
+}
+
+func parser() paseto.Parser {
+	// Rules are checked against the time of parsing.
+	return paseto.MakeParser([]paseto.Rule{
+		paseto.NotExpired(),
+		paseto.NotBeforeNbf(),
+	})
+}
+
+// keyID returns the ID of the key t was made with, from its footer.
+func keyID(parser paseto.Parser, protocol paseto.Protocol, t string) (string, error) {
+	b, err := parser.UnsafeParseFooter(protocol, t)
+	if err != nil {
+		return "", fmt.Errorf("%w, %w", ErrInvalid, err)
+	}
+	var f footer
+	if err = json.Unmarshal(b, &f); err != nil {
+		return "", fmt.Errorf("%w, unmarshaling footer, %w", ErrInvalid, err)
+	}
+	return f.KeyID, nil
+}
+
+// pasetoClaims reads the [Claims] of a parsed access token and checks them against opts.
+func pasetoClaims(opts Options, token *paseto.Token) (Claims, error) {
+	typ, err := token.GetString(claimType)
+	if err != nil || typ != typeAccess {
+		return Claims{}, fmt.Errorf("%w, not an access token", ErrInvalid)
+	}
+
+	var claims Claims
+	if claims.ID, err = token.GetJti(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading jti, %w", ErrInvalid, err)
+	}
+	sub, err := token.GetSubject()
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w, reading sub, %w", ErrInvalid, err)
+	}
+	if claims.Subject, err = uuid.Parse(sub); err != nil {
+		return Claims{}, fmt.Errorf("%w, parsing sub, %w", ErrInvalid, err)
+	}
+	if claims.Issuer, err = token.GetIssuer(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading iss, %w", ErrInvalid, err)
+	}
+	if claims.Audience, err = token.GetAudience(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading aud, %w", ErrInvalid, err)
+	}
+	if claims.IssuedAt, err = token.GetIssuedAt(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading iat, %w", ErrInvalid, err)
+	}
+	if claims.ExpiresAt, err = token.GetExpiration(); err != nil {
+		return Claims{}, fmt.Errorf("%w, reading exp, %w", ErrInvalid, err)
+	}
+	claims.Custom = customClaims(token.Claims())
+	claims.Scopes = strings.Fields(claims.Custom[claimScope])
+
+	if err = opts.check(claims); err != nil {
+		return Claims{}, err
+	}
+	return claims, nil
+}
@@ -0,0 +1,229 @@
+package verifier
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Salam4nder/identity/internal/hotstorage"
+	"github.com/Salam4nder/identity/internal/token"
+	"github.com/google/uuid"
+)
+
+func testMakerOpts() token.MakerOpts {
+	return token.MakerOpts{
+		AccessDuration:  time.Second * 10,
+		RefreshDuration: time.Minute,
+		Issuer:          "identity-test",
+		Audience:        "test",
+		Denylist:        hotstorage.NewMemory(),
+	}
+}
+
+func testOpts() Options {
+	return Options{Issuer: "identity-test", Audience: "test"}
+}
+
+func testKey(id string, b byte) token.Key {
+	return token.Key{ID: id, Secret: bytes.Repeat([]byte{b}, token.KeySize)}
+}
+
+func makeTokens(t *testing.T, maker token.Maker) (access, refresh string, claims token.Claims) {
+	t.Helper()
+
+	params := token.Params{Subject: uuid.New(), Custom: map[string]string{token.ClaimScope: "read write", "strategy": "Credentials"}}
+	a, claims, err := maker.MakeAccessToken(context.Background(), params)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	r, _, err := maker.MakeRefreshToken(context.Background(), params)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	return string(a), string(r), claims
+}
+
+func checkClaims(t *testing.T, got Claims, want token.Claims) {
+	t.Helper()
+
+	if got.ID != want.ID {
+		t.Errorf("expected ID %s, got %s", want.ID, got.ID)
+	}
+	if got.Subject != want.Subject {
+		t.Errorf("expected subject %s, got %s", want.Subject, got.Subject)
+	}
+	if got.Issuer != want.Issuer || got.Audience != want.Audience {
+		t.Errorf("expected issuer %s and audience %s, got %s and %s", want.Issuer, want.Audience, got.Issuer, got.Audience)
+	}
+	if !got.ExpiresAt.Equal(want.ExpiresAt) || !got.IssuedAt.Equal(want.IssuedAt) {
+		t.Errorf("expected times %s and %s, got %s and %s", want.IssuedAt, want.ExpiresAt, got.IssuedAt, got.ExpiresAt)
+	}
+	if !got.HasScope("read") || !got.HasScope("write") || got.HasScope("admin") {
+		t.Errorf("expected scopes [read write], got %v", got.Scopes)
+	}
+}
+
+func TestLocal(t *testing.T) {
+	ring, err := token.NewKeyRing(testKey("new", 'n'))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	maker, err := token.BootstrapPasetoMaker(testMakerOpts(), ring)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	access, refresh, want := makeTokens(t, maker)
+
+	v, err := NewLocal(testOpts(), map[string][]byte{"new": testKey("new", 'n').Secret})
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		claims, err := v.Verify(context.Background(), access)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		checkClaims(t, claims, want)
+		if claims.Custom["strategy"] != "Credentials" {
+			t.Errorf("expected strategy claim Credentials, got %v", claims.Custom)
+		}
+	})
+
+	t.Run("refresh token", func(t *testing.T) {
+		if _, err := v.Verify(context.Background(), refresh); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		v, err := NewLocal(testOpts(), map[string][]byte{"old": testKey("old", 'o').Secret})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err := v.Verify(context.Background(), access); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("unexpected audience", func(t *testing.T) {
+		opts := testOpts()
+		opts.Audience = "other"
+		v, err := NewLocal(opts, map[string][]byte{"new": testKey("new", 'n').Secret})
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err := v.Verify(context.Background(), access); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("malformed", func(t *testing.T) {
+		if _, err := v.Verify(context.Background(), "v4.local.garbage"); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+
+	t.Run("invalid key", func(t *testing.T) {
+		if _, err := NewLocal(testOpts(), map[string][]byte{"new": []byte("short")}); err == nil {
+			t.Error("expected error with invalid key")
+		}
+		if _, err := NewLocal(testOpts(), nil); err == nil {
+			t.Error("expected error without keys")
+		}
+	})
+
+	t.Run("missing options", func(t *testing.T) {
+		keys := map[string][]byte{"new": testKey("new", 'n').Secret}
+		if _, err := NewLocal(Options{Audience: "test"}, keys); err == nil {
+			t.Error("expected error without issuer")
+		}
+		if _, err := NewLocal(Options{Issuer: "identity-test"}, keys); err == nil {
+			t.Error("expected error without audience")
+		}
+		if _, err := NewPublic(Options{}, NewJWKS("http://localhost", nil, time.Hour)); err == nil {
+			t.Error("expected error without options")
+		}
+	})
+
+	t.Run("zero options match no token", func(t *testing.T) {
+		v := &Local{keys: v.keys, parser: parser()}
+		if _, err := v.Verify(context.Background(), access); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+}
+
+func TestPublic(t *testing.T) {
+	ring, err := token.NewKeyRing(testKey("new", 'n'))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+	maker, err := token.BootstrapPasetoPublicMaker(testMakerOpts(), ring)
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	var fetches atomic.Int32
+	handler := token.PublicKeysHandler(maker)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		handler.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	v, err := NewPublic(testOpts(), NewJWKS(srv.URL, srv.Client(), time.Hour))
+	if err != nil {
+		t.Fatalf("expected no error, got %s", err.Error())
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		access, _, want := makeTokens(t, maker)
+		for range 3 {
+			claims, err := v.Verify(context.Background(), access)
+			if err != nil {
+				t.Fatalf("expected no error, got %s", err.Error())
+			}
+			checkClaims(t, claims, want)
+		}
+		if n := fetches.Load(); n != 1 {
+			t.Errorf("expected keys to be fetched once, got %d", n)
+		}
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		if err := ring.Reload(testKey("newer", 'm'), testKey("new", 'n')); err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		access, _, _ := makeTokens(t, maker)
+
+		// The set was just fetched, so the unknown key is not fetched right away.
+		if _, err := v.Verify(context.Background(), access); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+
+		v, err := NewPublic(testOpts(), NewJWKS(srv.URL, srv.Client(), time.Hour))
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		if _, err := v.Verify(context.Background(), access); err != nil {
+			t.Errorf("expected no error, got %s", err.Error())
+		}
+	})
+
+	t.Run("local token", func(t *testing.T) {
+		local, err := token.BootstrapPasetoMaker(testMakerOpts(), ring)
+		if err != nil {
+			t.Fatalf("expected no error, got %s", err.Error())
+		}
+		access, _, _ := makeTokens(t, local)
+		if _, err := v.Verify(context.Background(), access); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid, got %v", err)
+		}
+	})
+}
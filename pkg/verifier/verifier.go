@@ -0,0 +1,114 @@
+// Package verifier verifies the access tokens of the identity service for downstream services.
+// Tokens are verified locally, with a shared v4.local key or the published v4.public keys,
+// or remotely with the Introspect RPC. Verified [Claims] are put into the context
+// by the gRPC interceptors and the HTTP middleware of this package.
+package verifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalid is returned when a token is malformed, expired, revoked or not an access token.
+var ErrInvalid = errors.New("verifier: invalid token")
+
+// Verifier verifies access tokens.
+type Verifier interface {
+	// Verify returns the [Claims] of a valid access token.
+	// Returns [ErrInvalid] if the token is not valid.
+	Verify(ctx context.Context, token string) (Claims, error)
+}
+
+// Options are checked against the claims of every verified token, every option is required.
+type Options struct {
+	// Issuer is required as the iss claim.
+	Issuer string
+	// Audience is required as the aud claim, it keeps a service from accepting the tokens of another.
+	Audience string
+}
+
+// Claims are the claims of a verified access token.
+type Claims struct {
+	ID        string
+	Subject   uuid.UUID
+	Issuer    string
+	Audience  string
+	Scopes    []string
+	IssuedAt  time.Time
+	ExpiresAt time.Time
+	// Custom claims, like the strategy the user authenticated with.
+	Custom map[string]string
+}
+
+// HasScope reports whether the token was granted scope.
+func (x Claims) HasScope(scope string) bool {
+	for _, s := range x.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Claim names set by the identity service.
+const (
+	claimID        = "jti"
+	claimSubject   = "sub"
+	claimIssuer    = "iss"
+	claimAudience  = "aud"
+	claimType      = "typ"
+	claimIssuedAt  = "iat"
+	claimNotBefore = "nbf"
+	claimExpires   = "exp"
+	claimScope     = "scope"
+
+	typeAccess = "access"
+)
+
+// validate returns an error if an option is missing.
+func (x Options) validate() error {
+	if x.Issuer == "" {
+		return errors.New("verifier: issuer is required")
+	}
+	if x.Audience == "" {
+		return errors.New("verifier: audience is required")
+	}
+	return nil
+}
+
+// check returns [ErrInvalid] if claims do not match opts. Missing options match no claims.
+func (x Options) check(claims Claims) error {
+	if x.Issuer == "" || claims.Issuer != x.Issuer {
+		return fmt.Errorf("%w, unexpected issuer %q", ErrInvalid, claims.Issuer)
+	}
+	if x.Audience == "" || claims.Audience != x.Audience {
+		return fmt.Errorf("%w, unexpected audience %q", ErrInvalid, claims.Audience)
+	}
+	if !time.Now().Before(claims.ExpiresAt) {
+		return fmt.Errorf("%w, expired", ErrInvalid)
+	}
+	return nil
+}
+
+// customClaims returns every claim in m that is not registered and has a string value.
+func customClaims(m map[string]any) map[string]string {
+	custom := make(map[string]string)
+	for k, v := range m {
+		switch k {
+		case claimID, claimSubject, claimIssuer, claimAudience, claimType,
+			claimIssuedAt, claimNotBefore, claimExpires:
+			continue
+		}
+		if s, ok := v.(string); ok {
+			custom[k] = s
+		}
+	}
+	if len(custom) == 0 {
+		return nil
+	}
+	return custom
+}
@@ -0,0 +1,22 @@
+package gen
+
+import (
+	"google.golang.org/protobuf/proto"
+)
+
+// PublicMethods returns the full method names of the [Identity_ServiceDesc] RPCs marked with the public option
+// in proto/service.proto. They are called without an access token, Introspect is authenticated
+// with service client credentials instead. It is the single list the server and its clients share.
+func PublicMethods() []string {
+	service := File_service_proto.Services().ByName("Identity")
+	methods := service.Methods()
+
+	var public []string
+	for i := range methods.Len() {
+		method := methods.Get(i)
+		if v, ok := proto.GetExtension(method.Options(), E_Public).(bool); ok && v {
+			public = append(public, "/"+string(service.FullName())+"/"+string(method.Name()))
+		}
+	}
+	return public
+}
@@ -20,8 +20,33 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Identity_Register_FullMethodName     = "/gen.Identity/Register"
-	Identity_Authenticate_FullMethodName = "/gen.Identity/Authenticate"
+	Identity_Register_FullMethodName                     = "/gen.Identity/Register"
+	Identity_Authenticate_FullMethodName                 = "/gen.Identity/Authenticate"
+	Identity_Renew_FullMethodName                        = "/gen.Identity/Renew"
+	Identity_Revoke_FullMethodName                       = "/gen.Identity/Revoke"
+	Identity_PublicKeys_FullMethodName                   = "/gen.Identity/PublicKeys"
+	Identity_Introspect_FullMethodName                   = "/gen.Identity/Introspect"
+	Identity_Sessions_FullMethodName                     = "/gen.Identity/Sessions"
+	Identity_RevokeSession_FullMethodName                = "/gen.Identity/RevokeSession"
+	Identity_RevokeSessions_FullMethodName               = "/gen.Identity/RevokeSessions"
+	Identity_BeginWebAuthnRegistration_FullMethodName    = "/gen.Identity/BeginWebAuthnRegistration"
+	Identity_FinishWebAuthnRegistration_FullMethodName   = "/gen.Identity/FinishWebAuthnRegistration"
+	Identity_BeginWebAuthnAuthentication_FullMethodName  = "/gen.Identity/BeginWebAuthnAuthentication"
+	Identity_FinishWebAuthnAuthentication_FullMethodName = "/gen.Identity/FinishWebAuthnAuthentication"
+	Identity_StartPasswordless_FullMethodName            = "/gen.Identity/StartPasswordless"
+	Identity_VerifyPasswordless_FullMethodName           = "/gen.Identity/VerifyPasswordless"
+	Identity_VerifyEmail_FullMethodName                  = "/gen.Identity/VerifyEmail"
+	Identity_ResendVerificationEmail_FullMethodName      = "/gen.Identity/ResendVerificationEmail"
+	Identity_RequestPasswordReset_FullMethodName         = "/gen.Identity/RequestPasswordReset"
+	Identity_ResetPassword_FullMethodName                = "/gen.Identity/ResetPassword"
+	Identity_ChangePassword_FullMethodName               = "/gen.Identity/ChangePassword"
+	Identity_ChangeEmail_FullMethodName                  = "/gen.Identity/ChangeEmail"
+	Identity_ConfirmEmailChange_FullMethodName           = "/gen.Identity/ConfirmEmailChange"
+	Identity_RevertEmailChange_FullMethodName            = "/gen.Identity/RevertEmailChange"
+	Identity_VerifyMFA_FullMethodName                    = "/gen.Identity/VerifyMFA"
+	Identity_EnrollTOTP_FullMethodName                   = "/gen.Identity/EnrollTOTP"
+	Identity_ConfirmTOTP_FullMethodName                  = "/gen.Identity/ConfirmTOTP"
+	Identity_DisableTOTP_FullMethodName                  = "/gen.Identity/DisableTOTP"
 )
 
 // IdentityClient is the client API for Identity service.
@@ -30,6 +55,80 @@ const (
 type IdentityClient interface {
 	Register(ctx context.Context, in *Input, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Authenticate(ctx context.Context, in *Input, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	// Renew trades a refresh token for a new access and refresh token pair.
+	// A refresh token can only be renewed once.
+	Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	// Revoke revokes the given tokens until they expire.
+	// Revoking a refresh token also revokes every token renewed from the same authentication.
+	Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// PublicKeys returns the public keys tokens can be verified with offline.
+	// The key ID of a token is in its footer.
+	PublicKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PublicKeysResponse, error)
+	// Introspect reports whether a token is active and returns its claims, see RFC 7662.
+	// Only service clients can call it, with their credentials as basic authorization metadata.
+	Introspect(ctx context.Context, in *IntrospectRequest, opts ...grpc.CallOption) (*IntrospectResponse, error)
+	// Sessions lists the active sessions of the authenticated user, most recently seen first.
+	Sessions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SessionsResponse, error)
+	// RevokeSession ends a session of the authenticated user, its tokens are revoked.
+	RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RevokeSessions ends every session of the authenticated user, logging it out everywhere.
+	RevokeSessions(ctx context.Context, in *RevokeSessionsRequest, opts ...grpc.CallOption) (*RevokeSessionsResponse, error)
+	// BeginWebAuthnRegistration begins the registration of a passkey for a new user.
+	// Finish it with FinishWebAuthnRegistration.
+	BeginWebAuthnRegistration(ctx context.Context, in *BeginWebAuthnRegistrationRequest, opts ...grpc.CallOption) (*WebAuthnChallenge, error)
+	// FinishWebAuthnRegistration answers the challenge of BeginWebAuthnRegistration with the new passkey
+	// and registers the user. It is the same as Register with the WebAuthn strategy.
+	FinishWebAuthnRegistration(ctx context.Context, in *FinishWebAuthnRegistrationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// BeginWebAuthnAuthentication begins an authentication with a passkey.
+	// Finish it with FinishWebAuthnAuthentication.
+	BeginWebAuthnAuthentication(ctx context.Context, in *BeginWebAuthnAuthenticationRequest, opts ...grpc.CallOption) (*WebAuthnChallenge, error)
+	// FinishWebAuthnAuthentication answers the challenge of BeginWebAuthnAuthentication with a passkey
+	// and returns the tokens. It is the same as Authenticate with the WebAuthn strategy.
+	FinishWebAuthnAuthentication(ctx context.Context, in *FinishWebAuthnAuthenticationRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	// StartPasswordless delivers a single use code or link to the address of a user of a passwordless strategy.
+	// The response is the same whether or not the address belongs to a user.
+	// An address is only delivered to a few times in a row, it is RESOURCE_EXHAUSTED after that.
+	StartPasswordless(ctx context.Context, in *StartPasswordlessRequest, opts ...grpc.CallOption) (*PasswordlessChallenge, error)
+	// VerifyPasswordless answers the challenge of StartPasswordless and returns the tokens,
+	// a new address is registered on its first sign in. A challenge expires after 15 minutes or 5 wrong codes.
+	VerifyPasswordless(ctx context.Context, in *VerifyPasswordlessRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	// VerifyEmail verifies the email of a credentials user with the token emailed on registration.
+	// A token can be used once and expires after 24 hours.
+	VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ResendVerificationEmail emails a new verification token to a credentials user with an unverified email.
+	// A user is sent at most 3 tokens an hour. The response is the same whether or not the email belongs
+	// to a user and whether or not a token was sent.
+	ResendVerificationEmail(ctx context.Context, in *ResendVerificationEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RequestPasswordReset emails a password reset token to a credentials user.
+	// The response is the same whether or not the email belongs to a user.
+	RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ResetPassword sets a new password with the token of RequestPasswordReset and ends every session of the user.
+	// A token can be used once and expires after 30 minutes, other reset tokens of the user expire on reset.
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ChangePassword replaces the password of the authenticated credentials user, the current password is required.
+	// The user is emailed that its password was changed.
+	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ChangeEmail emails a confirmation token to the new email of the authenticated credentials user,
+	// the current password is required. The email stays the same until the change is confirmed.
+	// A user can request 3 changes an hour, it is RESOURCE_EXHAUSTED after that.
+	ChangeEmail(ctx context.Context, in *ChangeEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ConfirmEmailChange changes the email with the token of ChangeEmail, it is ALREADY_EXISTS
+	// if the new email was registered in the meantime. The previous email is sent a token that reverts it,
+	// password reset tokens sent to it expire. A token can be used once and expires after an hour.
+	ConfirmEmailChange(ctx context.Context, in *ConfirmEmailChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// RevertEmailChange changes the email back with the token sent to the previous email
+	// and ends every session of the user. The password is replaced with an unknown one and a password reset
+	// token is sent to the restored email. A token can be used once and expires after 7 days.
+	RevertEmailChange(ctx context.Context, in *RevertEmailChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// VerifyMFA answers the MFA challenge returned by Authenticate and returns the tokens.
+	// A challenge expires after 5 minutes or 5 wrong codes.
+	VerifyMFA(ctx context.Context, in *VerifyMFARequest, opts ...grpc.CallOption) (*AuthenticateResponse, error)
+	// EnrollTOTP generates a TOTP secret for the authenticated user, it is enabled once confirmed.
+	EnrollTOTP(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*EnrollTOTPResponse, error)
+	// ConfirmTOTP enables TOTP with a code of the enrolled secret and returns the backup codes.
+	ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...grpc.CallOption) (*ConfirmTOTPResponse, error)
+	// DisableTOTP turns TOTP off for the authenticated user.
+	DisableTOTP(ctx context.Context, in *DisableTOTPRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 }
 
 type identityClient struct {
@@ -58,12 +157,311 @@ func (c *identityClient) Authenticate(ctx context.Context, in *Input, opts ...gr
 	return out, nil
 }
 
+func (c *identityClient) Renew(ctx context.Context, in *RenewRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error) {
+	out := new(AuthenticateResponse)
+	err := c.cc.Invoke(ctx, Identity_Renew_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) Revoke(ctx context.Context, in *RevokeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_Revoke_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) PublicKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*PublicKeysResponse, error) {
+	out := new(PublicKeysResponse)
+	err := c.cc.Invoke(ctx, Identity_PublicKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) Introspect(ctx context.Context, in *IntrospectRequest, opts ...grpc.CallOption) (*IntrospectResponse, error) {
+	out := new(IntrospectResponse)
+	err := c.cc.Invoke(ctx, Identity_Introspect_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) Sessions(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SessionsResponse, error) {
+	out := new(SessionsResponse)
+	err := c.cc.Invoke(ctx, Identity_Sessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) RevokeSession(ctx context.Context, in *RevokeSessionRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_RevokeSession_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) RevokeSessions(ctx context.Context, in *RevokeSessionsRequest, opts ...grpc.CallOption) (*RevokeSessionsResponse, error) {
+	out := new(RevokeSessionsResponse)
+	err := c.cc.Invoke(ctx, Identity_RevokeSessions_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) BeginWebAuthnRegistration(ctx context.Context, in *BeginWebAuthnRegistrationRequest, opts ...grpc.CallOption) (*WebAuthnChallenge, error) {
+	out := new(WebAuthnChallenge)
+	err := c.cc.Invoke(ctx, Identity_BeginWebAuthnRegistration_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) FinishWebAuthnRegistration(ctx context.Context, in *FinishWebAuthnRegistrationRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_FinishWebAuthnRegistration_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) BeginWebAuthnAuthentication(ctx context.Context, in *BeginWebAuthnAuthenticationRequest, opts ...grpc.CallOption) (*WebAuthnChallenge, error) {
+	out := new(WebAuthnChallenge)
+	err := c.cc.Invoke(ctx, Identity_BeginWebAuthnAuthentication_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) FinishWebAuthnAuthentication(ctx context.Context, in *FinishWebAuthnAuthenticationRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error) {
+	out := new(AuthenticateResponse)
+	err := c.cc.Invoke(ctx, Identity_FinishWebAuthnAuthentication_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) StartPasswordless(ctx context.Context, in *StartPasswordlessRequest, opts ...grpc.CallOption) (*PasswordlessChallenge, error) {
+	out := new(PasswordlessChallenge)
+	err := c.cc.Invoke(ctx, Identity_StartPasswordless_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) VerifyPasswordless(ctx context.Context, in *VerifyPasswordlessRequest, opts ...grpc.CallOption) (*AuthenticateResponse, error) {
+	out := new(AuthenticateResponse)
+	err := c.cc.Invoke(ctx, Identity_VerifyPasswordless_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_VerifyEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) ResendVerificationEmail(ctx context.Context, in *ResendVerificationEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_ResendVerificationEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_RequestPasswordReset_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_ResetPassword_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_ChangePassword_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) ChangeEmail(ctx context.Context, in *ChangeEmailRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_ChangeEmail_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) ConfirmEmailChange(ctx context.Context, in *ConfirmEmailChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_ConfirmEmailChange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) RevertEmailChange(ctx context.Context, in *RevertEmailChangeRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_RevertEmailChange_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) VerifyMFA(ctx context.Context, in *VerifyMFARequest, opts ...grpc.CallOption) (*AuthenticateResponse, error) {
+	out := new(AuthenticateResponse)
+	err := c.cc.Invoke(ctx, Identity_VerifyMFA_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) EnrollTOTP(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*EnrollTOTPResponse, error) {
+	out := new(EnrollTOTPResponse)
+	err := c.cc.Invoke(ctx, Identity_EnrollTOTP_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...grpc.CallOption) (*ConfirmTOTPResponse, error) {
+	out := new(ConfirmTOTPResponse)
+	err := c.cc.Invoke(ctx, Identity_ConfirmTOTP_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *identityClient) DisableTOTP(ctx context.Context, in *DisableTOTPRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Identity_DisableTOTP_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // IdentityServer is the server API for Identity service.
 // All implementations must embed UnimplementedIdentityServer
 // for forward compatibility
 type IdentityServer interface {
 	Register(context.Context, *Input) (*emptypb.Empty, error)
 	Authenticate(context.Context, *Input) (*AuthenticateResponse, error)
+	// Renew trades a refresh token for a new access and refresh token pair.
+	// A refresh token can only be renewed once.
+	Renew(context.Context, *RenewRequest) (*AuthenticateResponse, error)
+	// Revoke revokes the given tokens until they expire.
+	// Revoking a refresh token also revokes every token renewed from the same authentication.
+	Revoke(context.Context, *RevokeRequest) (*emptypb.Empty, error)
+	// PublicKeys returns the public keys tokens can be verified with offline.
+	// The key ID of a token is in its footer.
+	PublicKeys(context.Context, *emptypb.Empty) (*PublicKeysResponse, error)
+	// Introspect reports whether a token is active and returns its claims, see RFC 7662.
+	// Only service clients can call it, with their credentials as basic authorization metadata.
+	Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error)
+	// Sessions lists the active sessions of the authenticated user, most recently seen first.
+	Sessions(context.Context, *emptypb.Empty) (*SessionsResponse, error)
+	// RevokeSession ends a session of the authenticated user, its tokens are revoked.
+	RevokeSession(context.Context, *RevokeSessionRequest) (*emptypb.Empty, error)
+	// RevokeSessions ends every session of the authenticated user, logging it out everywhere.
+	RevokeSessions(context.Context, *RevokeSessionsRequest) (*RevokeSessionsResponse, error)
+	// BeginWebAuthnRegistration begins the registration of a passkey for a new user.
+	// Finish it with FinishWebAuthnRegistration.
+	BeginWebAuthnRegistration(context.Context, *BeginWebAuthnRegistrationRequest) (*WebAuthnChallenge, error)
+	// FinishWebAuthnRegistration answers the challenge of BeginWebAuthnRegistration with the new passkey
+	// and registers the user. It is the same as Register with the WebAuthn strategy.
+	FinishWebAuthnRegistration(context.Context, *FinishWebAuthnRegistrationRequest) (*emptypb.Empty, error)
+	// BeginWebAuthnAuthentication begins an authentication with a passkey.
+	// Finish it with FinishWebAuthnAuthentication.
+	BeginWebAuthnAuthentication(context.Context, *BeginWebAuthnAuthenticationRequest) (*WebAuthnChallenge, error)
+	// FinishWebAuthnAuthentication answers the challenge of BeginWebAuthnAuthentication with a passkey
+	// and returns the tokens. It is the same as Authenticate with the WebAuthn strategy.
+	FinishWebAuthnAuthentication(context.Context, *FinishWebAuthnAuthenticationRequest) (*AuthenticateResponse, error)
+	// StartPasswordless delivers a single use code or link to the address of a user of a passwordless strategy.
+	// The response is the same whether or not the address belongs to a user.
+	// An address is only delivered to a few times in a row, it is RESOURCE_EXHAUSTED after that.
+	StartPasswordless(context.Context, *StartPasswordlessRequest) (*PasswordlessChallenge, error)
+	// VerifyPasswordless answers the challenge of StartPasswordless and returns the tokens,
+	// a new address is registered on its first sign in. A challenge expires after 15 minutes or 5 wrong codes.
+	VerifyPasswordless(context.Context, *VerifyPasswordlessRequest) (*AuthenticateResponse, error)
+	// VerifyEmail verifies the email of a credentials user with the token emailed on registration.
+	// A token can be used once and expires after 24 hours.
+	VerifyEmail(context.Context, *VerifyEmailRequest) (*emptypb.Empty, error)
+	// ResendVerificationEmail emails a new verification token to a credentials user with an unverified email.
+	// A user is sent at most 3 tokens an hour. The response is the same whether or not the email belongs
+	// to a user and whether or not a token was sent.
+	ResendVerificationEmail(context.Context, *ResendVerificationEmailRequest) (*emptypb.Empty, error)
+	// RequestPasswordReset emails a password reset token to a credentials user.
+	// The response is the same whether or not the email belongs to a user.
+	RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*emptypb.Empty, error)
+	// ResetPassword sets a new password with the token of RequestPasswordReset and ends every session of the user.
+	// A token can be used once and expires after 30 minutes, other reset tokens of the user expire on reset.
+	ResetPassword(context.Context, *ResetPasswordRequest) (*emptypb.Empty, error)
+	// ChangePassword replaces the password of the authenticated credentials user, the current password is required.
+	// The user is emailed that its password was changed.
+	ChangePassword(context.Context, *ChangePasswordRequest) (*emptypb.Empty, error)
+	// ChangeEmail emails a confirmation token to the new email of the authenticated credentials user,
+	// the current password is required. The email stays the same until the change is confirmed.
+	// A user can request 3 changes an hour, it is RESOURCE_EXHAUSTED after that.
+	ChangeEmail(context.Context, *ChangeEmailRequest) (*emptypb.Empty, error)
+	// ConfirmEmailChange changes the email with the token of ChangeEmail, it is ALREADY_EXISTS
+	// if the new email was registered in the meantime. The previous email is sent a token that reverts it,
+	// password reset tokens sent to it expire. A token can be used once and expires after an hour.
+	ConfirmEmailChange(context.Context, *ConfirmEmailChangeRequest) (*emptypb.Empty, error)
+	// RevertEmailChange changes the email back with the token sent to the previous email
+	// and ends every session of the user. The password is replaced with an unknown one and a password reset
+	// token is sent to the restored email. A token can be used once and expires after 7 days.
+	RevertEmailChange(context.Context, *RevertEmailChangeRequest) (*emptypb.Empty, error)
+	// VerifyMFA answers the MFA challenge returned by Authenticate and returns the tokens.
+	// A challenge expires after 5 minutes or 5 wrong codes.
+	VerifyMFA(context.Context, *VerifyMFARequest) (*AuthenticateResponse, error)
+	// EnrollTOTP generates a TOTP secret for the authenticated user, it is enabled once confirmed.
+	EnrollTOTP(context.Context, *emptypb.Empty) (*EnrollTOTPResponse, error)
+	// ConfirmTOTP enables TOTP with a code of the enrolled secret and returns the backup codes.
+	ConfirmTOTP(context.Context, *ConfirmTOTPRequest) (*ConfirmTOTPResponse, error)
+	// DisableTOTP turns TOTP off for the authenticated user.
+	DisableTOTP(context.Context, *DisableTOTPRequest) (*emptypb.Empty, error)
 	mustEmbedUnimplementedIdentityServer()
 }
 
@@ -77,6 +475,81 @@ func (UnimplementedIdentityServer) Register(context.Context, *Input) (*emptypb.E
 func (UnimplementedIdentityServer) Authenticate(context.Context, *Input) (*AuthenticateResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Authenticate not implemented")
 }
+func (UnimplementedIdentityServer) Renew(context.Context, *RenewRequest) (*AuthenticateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Renew not implemented")
+}
+func (UnimplementedIdentityServer) Revoke(context.Context, *RevokeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Revoke not implemented")
+}
+func (UnimplementedIdentityServer) PublicKeys(context.Context, *emptypb.Empty) (*PublicKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PublicKeys not implemented")
+}
+func (UnimplementedIdentityServer) Introspect(context.Context, *IntrospectRequest) (*IntrospectResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Introspect not implemented")
+}
+func (UnimplementedIdentityServer) Sessions(context.Context, *emptypb.Empty) (*SessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Sessions not implemented")
+}
+func (UnimplementedIdentityServer) RevokeSession(context.Context, *RevokeSessionRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSession not implemented")
+}
+func (UnimplementedIdentityServer) RevokeSessions(context.Context, *RevokeSessionsRequest) (*RevokeSessionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSessions not implemented")
+}
+func (UnimplementedIdentityServer) BeginWebAuthnRegistration(context.Context, *BeginWebAuthnRegistrationRequest) (*WebAuthnChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginWebAuthnRegistration not implemented")
+}
+func (UnimplementedIdentityServer) FinishWebAuthnRegistration(context.Context, *FinishWebAuthnRegistrationRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishWebAuthnRegistration not implemented")
+}
+func (UnimplementedIdentityServer) BeginWebAuthnAuthentication(context.Context, *BeginWebAuthnAuthenticationRequest) (*WebAuthnChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginWebAuthnAuthentication not implemented")
+}
+func (UnimplementedIdentityServer) FinishWebAuthnAuthentication(context.Context, *FinishWebAuthnAuthenticationRequest) (*AuthenticateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishWebAuthnAuthentication not implemented")
+}
+func (UnimplementedIdentityServer) StartPasswordless(context.Context, *StartPasswordlessRequest) (*PasswordlessChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartPasswordless not implemented")
+}
+func (UnimplementedIdentityServer) VerifyPasswordless(context.Context, *VerifyPasswordlessRequest) (*AuthenticateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyPasswordless not implemented")
+}
+func (UnimplementedIdentityServer) VerifyEmail(context.Context, *VerifyEmailRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyEmail not implemented")
+}
+func (UnimplementedIdentityServer) ResendVerificationEmail(context.Context, *ResendVerificationEmailRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResendVerificationEmail not implemented")
+}
+func (UnimplementedIdentityServer) RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestPasswordReset not implemented")
+}
+func (UnimplementedIdentityServer) ResetPassword(context.Context, *ResetPasswordRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
+func (UnimplementedIdentityServer) ChangePassword(context.Context, *ChangePasswordRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
+}
+func (UnimplementedIdentityServer) ChangeEmail(context.Context, *ChangeEmailRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ChangeEmail not implemented")
+}
+func (UnimplementedIdentityServer) ConfirmEmailChange(context.Context, *ConfirmEmailChangeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmEmailChange not implemented")
+}
+func (UnimplementedIdentityServer) RevertEmailChange(context.Context, *RevertEmailChangeRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevertEmailChange not implemented")
+}
+func (UnimplementedIdentityServer) VerifyMFA(context.Context, *VerifyMFARequest) (*AuthenticateResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyMFA not implemented")
+}
+func (UnimplementedIdentityServer) EnrollTOTP(context.Context, *emptypb.Empty) (*EnrollTOTPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnrollTOTP not implemented")
+}
+func (UnimplementedIdentityServer) ConfirmTOTP(context.Context, *ConfirmTOTPRequest) (*ConfirmTOTPResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmTOTP not implemented")
+}
+func (UnimplementedIdentityServer) DisableTOTP(context.Context, *DisableTOTPRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableTOTP not implemented")
+}
 func (UnimplementedIdentityServer) mustEmbedUnimplementedIdentityServer() {}
 
 // UnsafeIdentityServer may be embedded to opt out of forward compatibility for this service.
@@ -126,20 +599,570 @@ func _Identity_Authenticate_Handler(srv interface{}, ctx context.Context, dec fu
 	return interceptor(ctx, in, info, handler)
 }
 
-// Identity_ServiceDesc is the grpc.ServiceDesc for Identity service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Identity_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "gen.Identity",
-	HandlerType: (*IdentityServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Register",
-			Handler:    _Identity_Register_Handler,
-		},
-		{
-			MethodName: "Authenticate",
-			Handler:    _Identity_Authenticate_Handler,
+func _Identity_Renew_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).Renew(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_Renew_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).Renew(ctx, req.(*RenewRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_Revoke_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).Revoke(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_Revoke_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).Revoke(ctx, req.(*RevokeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_PublicKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).PublicKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_PublicKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).PublicKeys(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_Introspect_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(IntrospectRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).Introspect(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_Introspect_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).Introspect(ctx, req.(*IntrospectRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_Sessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).Sessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_Sessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).Sessions(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_RevokeSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).RevokeSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_RevokeSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).RevokeSession(ctx, req.(*RevokeSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_RevokeSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).RevokeSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_RevokeSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).RevokeSessions(ctx, req.(*RevokeSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_BeginWebAuthnRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginWebAuthnRegistrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).BeginWebAuthnRegistration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_BeginWebAuthnRegistration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).BeginWebAuthnRegistration(ctx, req.(*BeginWebAuthnRegistrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_FinishWebAuthnRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishWebAuthnRegistrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).FinishWebAuthnRegistration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_FinishWebAuthnRegistration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).FinishWebAuthnRegistration(ctx, req.(*FinishWebAuthnRegistrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_BeginWebAuthnAuthentication_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginWebAuthnAuthenticationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).BeginWebAuthnAuthentication(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_BeginWebAuthnAuthentication_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).BeginWebAuthnAuthentication(ctx, req.(*BeginWebAuthnAuthenticationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_FinishWebAuthnAuthentication_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishWebAuthnAuthenticationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).FinishWebAuthnAuthentication(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_FinishWebAuthnAuthentication_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).FinishWebAuthnAuthentication(ctx, req.(*FinishWebAuthnAuthenticationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_StartPasswordless_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartPasswordlessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).StartPasswordless(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_StartPasswordless_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).StartPasswordless(ctx, req.(*StartPasswordlessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_VerifyPasswordless_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyPasswordlessRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).VerifyPasswordless(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_VerifyPasswordless_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).VerifyPasswordless(ctx, req.(*VerifyPasswordlessRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_VerifyEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).VerifyEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_VerifyEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).VerifyEmail(ctx, req.(*VerifyEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_ResendVerificationEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResendVerificationEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).ResendVerificationEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_ResendVerificationEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).ResendVerificationEmail(ctx, req.(*ResendVerificationEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_RequestPasswordReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestPasswordResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).RequestPasswordReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_RequestPasswordReset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).RequestPasswordReset(ctx, req.(*RequestPasswordResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_ResetPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangePasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).ChangePassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_ChangePassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).ChangePassword(ctx, req.(*ChangePasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_ChangeEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ChangeEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).ChangeEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_ChangeEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).ChangeEmail(ctx, req.(*ChangeEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_ConfirmEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmEmailChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).ConfirmEmailChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_ConfirmEmailChange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).ConfirmEmailChange(ctx, req.(*ConfirmEmailChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_RevertEmailChange_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevertEmailChangeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).RevertEmailChange(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_RevertEmailChange_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).RevertEmailChange(ctx, req.(*RevertEmailChangeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_VerifyMFA_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyMFARequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).VerifyMFA(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_VerifyMFA_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).VerifyMFA(ctx, req.(*VerifyMFARequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_EnrollTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).EnrollTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_EnrollTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).EnrollTOTP(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_ConfirmTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfirmTOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).ConfirmTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_ConfirmTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).ConfirmTOTP(ctx, req.(*ConfirmTOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Identity_DisableTOTP_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableTOTPRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(IdentityServer).DisableTOTP(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Identity_DisableTOTP_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(IdentityServer).DisableTOTP(ctx, req.(*DisableTOTPRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Identity_ServiceDesc is the grpc.ServiceDesc for Identity service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Identity_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gen.Identity",
+	HandlerType: (*IdentityServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Register",
+			Handler:    _Identity_Register_Handler,
+		},
+		{
+			MethodName: "Authenticate",
+			Handler:    _Identity_Authenticate_Handler,
+		},
+		{
+			MethodName: "Renew",
+			Handler:    _Identity_Renew_Handler,
+		},
+		{
+			MethodName: "Revoke",
+			Handler:    _Identity_Revoke_Handler,
+		},
+		{
+			MethodName: "PublicKeys",
+			Handler:    _Identity_PublicKeys_Handler,
+		},
+		{
+			MethodName: "Introspect",
+			Handler:    _Identity_Introspect_Handler,
+		},
+		{
+			MethodName: "Sessions",
+			Handler:    _Identity_Sessions_Handler,
+		},
+		{
+			MethodName: "RevokeSession",
+			Handler:    _Identity_RevokeSession_Handler,
+		},
+		{
+			MethodName: "RevokeSessions",
+			Handler:    _Identity_RevokeSessions_Handler,
+		},
+		{
+			MethodName: "BeginWebAuthnRegistration",
+			Handler:    _Identity_BeginWebAuthnRegistration_Handler,
+		},
+		{
+			MethodName: "FinishWebAuthnRegistration",
+			Handler:    _Identity_FinishWebAuthnRegistration_Handler,
+		},
+		{
+			MethodName: "BeginWebAuthnAuthentication",
+			Handler:    _Identity_BeginWebAuthnAuthentication_Handler,
+		},
+		{
+			MethodName: "FinishWebAuthnAuthentication",
+			Handler:    _Identity_FinishWebAuthnAuthentication_Handler,
+		},
+		{
+			MethodName: "StartPasswordless",
+			Handler:    _Identity_StartPasswordless_Handler,
+		},
+		{
+			MethodName: "VerifyPasswordless",
+			Handler:    _Identity_VerifyPasswordless_Handler,
+		},
+		{
+			MethodName: "VerifyEmail",
+			Handler:    _Identity_VerifyEmail_Handler,
+		},
+		{
+			MethodName: "ResendVerificationEmail",
+			Handler:    _Identity_ResendVerificationEmail_Handler,
+		},
+		{
+			MethodName: "RequestPasswordReset",
+			Handler:    _Identity_RequestPasswordReset_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _Identity_ResetPassword_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _Identity_ChangePassword_Handler,
+		},
+		{
+			MethodName: "ChangeEmail",
+			Handler:    _Identity_ChangeEmail_Handler,
+		},
+		{
+			MethodName: "ConfirmEmailChange",
+			Handler:    _Identity_ConfirmEmailChange_Handler,
+		},
+		{
+			MethodName: "RevertEmailChange",
+			Handler:    _Identity_RevertEmailChange_Handler,
+		},
+		{
+			MethodName: "VerifyMFA",
+			Handler:    _Identity_VerifyMFA_Handler,
+		},
+		{
+			MethodName: "EnrollTOTP",
+			Handler:    _Identity_EnrollTOTP_Handler,
+		},
+		{
+			MethodName: "ConfirmTOTP",
+			Handler:    _Identity_ConfirmTOTP_Handler,
+		},
+		{
+			MethodName: "DisableTOTP",
+			Handler:    _Identity_DisableTOTP_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},